@@ -0,0 +1,333 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"crproductos/internal/auth"
+	"crproductos/internal/models"
+	"crproductos/internal/service"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func ptr[T any](value T) *T {
+	return &value
+}
+
+// fakeServices serves a fixed catalog and counts the calls made to it
+type fakeServices struct {
+	mu         sync.Mutex
+	calls      map[string]int
+	products   []models.ProductResponse
+	patchStore []byte
+}
+
+func (f *fakeServices) count(method string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls[method]++
+}
+
+func newFakeServices() *fakeServices {
+	return &fakeServices{
+		calls: map[string]int{},
+		products: []models.ProductResponse{
+			{Id: 1, Name: ptr("Leche entera"), Quantity: ptr(1.0), Unit: ptr("litros"), BrandId: ptr(1),
+				Stores: &models.Stores{"automercado": 1150, "walmart": 990, "pali": 950}},
+			{Id: 2, Name: ptr("Leche entera"), Quantity: ptr(1.8), Unit: ptr("litros"), BrandId: ptr(1), ParentId: ptr(1),
+				Stores: &models.Stores{"walmart": 1650}, Currencies: &models.StoreCurrencies{}},
+			{Id: 3, Name: ptr("Cafe molido"), Quantity: ptr(500.0), Unit: ptr("g"), BrandId: ptr(2), ParentId: ptr(4),
+				Stores: &models.Stores{"masxmenos": 7.5}, Currencies: &models.StoreCurrencies{"masxmenos": "USD"}},
+			{Id: 4, Name: ptr("Cafe molido"), Quantity: ptr(1.0), Unit: ptr("kg")},
+		},
+	}
+}
+
+func (f *fakeServices) GetAllProducts(ctx context.Context, filter models.ProductFilter) ([]models.ProductResponse, error) {
+	f.count("GetAllProducts")
+	if len(filter.Ids) == 0 {
+		return f.products[:3], nil
+	}
+	var products []models.ProductResponse
+	for _, product := range f.products {
+		for _, id := range filter.Ids {
+			if product.Id == id {
+				products = append(products, product)
+			}
+		}
+	}
+	return products, nil
+}
+
+func (f *fakeServices) ScanProducts(ctx context.Context, filter models.ProductFilter, fn func(models.ProductResponse) error) error {
+	products, err := f.GetAllProducts(ctx, filter)
+	if err != nil {
+		return err
+	}
+	for _, product := range products {
+		if err := fn(product); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fakeServices) GetProductById(ctx context.Context, id string) (models.Product, error) {
+	return models.Product{}, nil
+}
+
+func (f *fakeServices) GetProductByBarcode(ctx context.Context, barcode string) (models.Product, error) {
+	return models.Product{}, nil
+}
+
+func (f *fakeServices) CreateProduct(ctx context.Context, product models.ProductResponse) (models.ProductResponse, error) {
+	f.count("CreateProduct")
+	product.Id = 5
+	return product, nil
+}
+
+func (f *fakeServices) DeleteProduct(ctx context.Context, id string) error {
+	f.count("DeleteProduct")
+	return nil
+}
+
+func (f *fakeServices) UpdateProduct(ctx context.Context, id string, product models.ProductResponse) (models.ProductResponse, error) {
+	return product, nil
+}
+
+func (f *fakeServices) PatchProduct(ctx context.Context, id string, product models.ProductResponse) (models.Product, error) {
+	return models.Product{}, nil
+}
+
+func (f *fakeServices) PatchStore(ctx context.Context, id string, jsonStore []byte) (models.Product, error) {
+	f.count("PatchStore")
+	f.patchStore = jsonStore
+	return models.Product{Id: 1}, nil
+}
+
+func (f *fakeServices) CompareProducts(ctx context.Context, filter models.ProductFilter) ([]models.VariantComparison, error) {
+	return nil, nil
+}
+
+func (f *fakeServices) GetAllBrands() ([]models.Brand, error) {
+	f.count("GetAllBrands")
+	return []models.Brand{{Id: 1, Name: "Dos Pinos"}, {Id: 2, Name: "Britt"}}, nil
+}
+
+func (f *fakeServices) GetBrandById(id string) (models.Brand, error) {
+	return models.Brand{}, nil
+}
+
+func (f *fakeServices) CreateBrand(brand models.Brand) (models.Brand, error) {
+	return brand, nil
+}
+
+func (f *fakeServices) UpdateBrand(id string, brand models.Brand) (models.Brand, error) {
+	return brand, nil
+}
+
+func (f *fakeServices) DeleteBrand(id string) error {
+	return nil
+}
+
+func (f *fakeServices) GetPriceHistory(filter models.PriceHistoryFilter) ([]models.PricePoint, error) {
+	f.count("GetPriceHistory")
+	day := time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC)
+	var points []models.PricePoint
+	for _, id := range filter.ProductIds {
+		if id == 1 {
+			points = append(points,
+				models.PricePoint{ProductId: 1, Store: "walmart", Price: ptr(1050.0), RecordedAt: day},
+				models.PricePoint{ProductId: 1, Store: "walmart", Price: ptr(990.0), RecordedAt: day.AddDate(0, 0, 7)},
+				models.PricePoint{ProductId: 1, Store: "pali", Price: ptr(950.0), RecordedAt: day})
+		}
+	}
+	return points, nil
+}
+
+type graphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func execute(t *testing.T, h http.Handler, req *http.Request) graphQLResponse {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rr.Code, rr.Body.String())
+	}
+	var response graphQLResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	return response
+}
+
+func post(query string, variables map[string]interface{}) *http.Request {
+	body, _ := json.Marshal(request{Query: query, Variables: variables})
+	return httptest.NewRequest("POST", "/graphql", bytes.NewReader(body))
+}
+
+func TestProductsQuery(t *testing.T) {
+	services := newFakeServices()
+	h := NewHandler(services, services, services, service.NewPricingService(nil, nil))
+
+	response := execute(t, h, post(`{
+		products {
+			id
+			brand { name }
+			parent { id name }
+			stores(cheapest: 2) { store price currency unitPrice baseUnit history { price } }
+			priceHistory(store: "walmart") { price }
+		}
+	}`, nil))
+	if len(response.Errors) > 0 {
+		t.Fatalf("unexpected errors %+v", response.Errors)
+	}
+	var data struct {
+		Products []struct {
+			Id     string
+			Brand  struct{ Name string }
+			Parent *struct{ Id string }
+			Stores []struct {
+				Store     string
+				Price     float64
+				Currency  string
+				UnitPrice *float64
+				BaseUnit  *string
+				History   []struct{ Price *float64 }
+			}
+			PriceHistory []struct{ Price *float64 }
+		}
+	}
+	if err := json.Unmarshal(response.Data, &data); err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Products) != 3 {
+		t.Fatalf("got %d products, want 3", len(data.Products))
+	}
+	milk, bigMilk, coffee := data.Products[0], data.Products[1], data.Products[2]
+	if len(milk.Stores) != 2 || milk.Stores[0].Store != "pali" || milk.Stores[1].Store != "walmart" {
+		t.Errorf("expected the two cheapest stores, got %+v", milk.Stores)
+	}
+	if milk.Brand.Name != "Dos Pinos" || milk.Parent != nil || bigMilk.Parent == nil || bigMilk.Parent.Id != "1" || coffee.Parent.Id != "4" {
+		t.Errorf("unexpected brand or parent %+v", data.Products)
+	}
+	if len(milk.PriceHistory) != 2 || *milk.PriceHistory[1].Price != 990 || len(milk.Stores[1].History) != 2 || len(milk.Stores[0].History) != 1 {
+		t.Errorf("unexpected price history %+v", milk)
+	}
+	if *coffee.Stores[0].UnitPrice != 15 || *coffee.Stores[0].BaseUnit != "kg" || coffee.Stores[0].Currency != "USD" || bigMilk.Stores[0].Currency != "CRC" {
+		t.Errorf("unexpected coffee price %+v", coffee.Stores[0])
+	}
+
+	// one query for the list, one for every parent, brand and history lookup
+	for method, want := range map[string]int{"GetAllProducts": 2, "GetAllBrands": 1, "GetPriceHistory": 1} {
+		if services.calls[method] != want {
+			t.Errorf("%s called %d times, want %d", method, services.calls[method], want)
+		}
+	}
+}
+
+func TestMutations(t *testing.T) {
+	services := newFakeServices()
+	h := NewHandler(services, services, services, service.NewPricingService(nil, nil))
+	create := `mutation($input: ProductInput!) { createProduct(input: $input) { id name stores { store price } } }`
+	variables := map[string]interface{}{"input": map[string]interface{}{"name": "Arroz", "stores": []map[string]interface{}{{"store": "pali", "price": 1200}}}}
+
+	response := execute(t, h, post(create, variables))
+	if len(response.Errors) != 1 || response.Errors[0].Message != "unauthorized" {
+		t.Errorf("expected unauthorized, got %+v", response.Errors)
+	}
+
+	withRole := func(req *http.Request, role models.Role) *http.Request {
+		return req.WithContext(auth.NewContext(req.Context(), auth.Principal{Subject: "test", Role: role}))
+	}
+	response = execute(t, h, withRole(post(create, variables), models.RolePriceCollector))
+	if len(response.Errors) != 1 || response.Errors[0].Message != "forbidden" {
+		t.Errorf("expected forbidden, got %+v", response.Errors)
+	}
+	response = execute(t, h, withRole(post(create, variables), models.RoleAdmin))
+	if len(response.Errors) > 0 || !strings.Contains(string(response.Data), `"id":"5","name":"Arroz","stores":[{"store":"pali","price":1200}]`) {
+		t.Errorf("unexpected createProduct response %s %+v", response.Data, response.Errors)
+	}
+
+	response = execute(t, h, withRole(post(`mutation { setStorePrices(id: "1", prices: [{store: "walmart", price: 980}]) { id } }`, nil), models.RolePriceCollector))
+	if len(response.Errors) > 0 || string(services.patchStore) != `{"walmart":980}` {
+		t.Errorf("unexpected setStorePrices %s %+v", services.patchStore, response.Errors)
+	}
+
+	get := httptest.NewRequest("GET", "/graphql?query="+url.QueryEscape(`mutation { deleteProduct(id: "1") }`), nil)
+	response = execute(t, h, withRole(get, models.RoleAdmin))
+	if len(response.Errors) != 1 || services.calls["DeleteProduct"] != 0 {
+		t.Errorf("mutations over GET should be refused, got %+v", response.Errors)
+	}
+}
+
+// promotionsAndRates runs 20% off at automercado and knows a USD to CRC rate
+type promotionsAndRates struct{}
+
+func (promotionsAndRates) GetPromotions(filter models.PromotionFilter) ([]models.Promotion, error) {
+	return []models.Promotion{{Id: 1, ProductId: 1, Store: "automercado", Type: models.PromotionPercentage, Value: 20}}, nil
+}
+func (promotionsAndRates) GetPromotionById(id string) (models.Promotion, error) {
+	return models.Promotion{}, nil
+}
+func (promotionsAndRates) CreatePromotion(promotion models.Promotion) (models.Promotion, error) {
+	return promotion, nil
+}
+func (promotionsAndRates) UpdatePromotion(id string, promotion models.Promotion) (models.Promotion, error) {
+	return promotion, nil
+}
+func (promotionsAndRates) DeletePromotion(id string) error                     { return nil }
+func (promotionsAndRates) GetAllExchangeRates() ([]models.ExchangeRate, error) { return nil, nil }
+func (promotionsAndRates) CreateExchangeRate(rate models.ExchangeRate) (models.ExchangeRate, error) {
+	return rate, nil
+}
+func (promotionsAndRates) DeleteExchangeRate(id string) error { return nil }
+func (promotionsAndRates) FindExchangeRate(base string, quote string, at time.Time) (models.ExchangeRate, error) {
+	if base == "USD" && quote == "CRC" {
+		return models.ExchangeRate{Id: 1, Base: base, Quote: quote, Rate: 520}, nil
+	}
+	return models.ExchangeRate{}, models.ErrExchangeRateNotFound
+}
+
+func TestStoresRankByEffectivePrice(t *testing.T) {
+	services := newFakeServices()
+	services.products[0].Stores = &models.Stores{"automercado": 1150, "walmart": 990, "amazon": 1.5}
+	services.products[0].Currencies = &models.StoreCurrencies{"amazon": "USD"}
+	pricing := service.NewPricingService(service.NewPromotionService(promotionsAndRates{}), service.NewExchangeRateService(promotionsAndRates{}))
+	h := NewHandler(services, services, services, pricing)
+
+	response := execute(t, h, post(`{ products(ids: ["1"]) { stores(cheapest: 2) { store price effectivePrice currency } } }`, nil))
+	if len(response.Errors) > 0 {
+		t.Fatalf("unexpected errors %+v", response.Errors)
+	}
+	var data struct {
+		Products []struct {
+			Stores []struct {
+				Store          string
+				Price          float64
+				EffectivePrice float64
+				Currency       string
+			}
+		}
+	}
+	if err := json.Unmarshal(response.Data, &data); err != nil {
+		t.Fatal(err)
+	}
+	stores := data.Products[0].Stores
+	// amazon's 1.5 dollars are 780 colones and automercado's promotion leaves 920
+	if len(stores) != 2 || stores[0].Store != "amazon" || stores[0].Currency != "CRC" || stores[0].Price != 780 ||
+		stores[1].Store != "automercado" || stores[1].Price != 1150 || stores[1].EffectivePrice != 920 {
+		t.Errorf("expected the cheapest effective prices in colones, got %+v", stores)
+	}
+}
@@ -0,0 +1,69 @@
+// Package graphql serves the products, their store prices and price history
+// over GraphQL
+package graphql
+
+import (
+	"context"
+	"crproductos/internal/service"
+	_ "embed"
+	"encoding/json"
+	"github.com/graph-gophers/graphql-go"
+	"net/http"
+)
+
+//go:embed schema.graphql
+var schemaSDL string
+
+type handler struct {
+	schema   *graphql.Schema
+	resolver *resolver
+}
+
+type request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// NewHandler serves queries sent as a JSON body with POST or in the query
+// string with GET, mutations are only accepted over POST. Store prices go
+// through pricing like the REST API's
+func NewHandler(products service.ProductService, brands service.BrandService, history service.PriceHistoryService, pricing service.PricingService) http.Handler {
+	r := &resolver{products: products, brands: brands, history: history, pricing: pricing}
+	return &handler{
+		schema:   graphql.MustParseSchema(schemaSDL, r, graphql.MaxDepth(10)),
+		resolver: r,
+	}
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req request
+	switch r.Method {
+	case http.MethodGet:
+		query := r.URL.Query()
+		req.Query, req.OperationName = query.Get("query"), query.Get("operationName")
+		if variables := query.Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				http.Error(w, "Bad Request", http.StatusBadRequest)
+				return
+			}
+		}
+	case http.MethodPost:
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if req.Query == "" {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	ctx := context.WithValue(r.Context(), loadersKey{}, h.resolver.newLoaders(r.Method == http.MethodGet))
+	response := h.schema.Exec(ctx, req.Query, req.OperationName, req.Variables)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
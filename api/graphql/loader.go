@@ -0,0 +1,60 @@
+package graphql
+
+import (
+	"context"
+	"sync"
+)
+
+// loader batches the lookups made while resolving a single request. The
+// first load of a key also fetches the batch keys given with it, the keys
+// its sibling objects in the same list will ask for, so a list of n products
+// costs one fetch instead of n. Every key is fetched at most once
+type loader[K comparable, V any] struct {
+	fetch func(ctx context.Context, keys []K) (map[K]V, error)
+
+	mu      sync.Mutex
+	entries map[K]*loaderEntry[V]
+}
+
+type loaderEntry[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+func newLoader[K comparable, V any](fetch func(ctx context.Context, keys []K) (map[K]V, error)) *loader[K, V] {
+	return &loader[K, V]{fetch: fetch, entries: map[K]*loaderEntry[V]{}}
+}
+
+// load returns the value of key, the zero value when fetch did not return it
+func (l *loader[K, V]) load(ctx context.Context, key K, batch []K) (V, error) {
+	l.mu.Lock()
+	entry, ok := l.entries[key]
+	if ok {
+		l.mu.Unlock()
+		select {
+		case <-entry.done:
+			return entry.value, entry.err
+		case <-ctx.Done():
+			var zero V
+			return zero, ctx.Err()
+		}
+	}
+	fetching := map[K]*loaderEntry[V]{}
+	keys := []K{}
+	for _, k := range append([]K{key}, batch...) {
+		if _, seen := l.entries[k]; !seen {
+			l.entries[k] = &loaderEntry[V]{done: make(chan struct{})}
+			fetching[k] = l.entries[k]
+			keys = append(keys, k)
+		}
+	}
+	l.mu.Unlock()
+
+	values, err := l.fetch(ctx, keys)
+	for k, entry := range fetching {
+		entry.value, entry.err = values[k], err
+		close(entry.done)
+	}
+	return fetching[key].value, err
+}
@@ -0,0 +1,641 @@
+package graphql
+
+import (
+	"context"
+	"crproductos/internal/auth"
+	"crproductos/internal/models"
+	"crproductos/internal/service"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/graph-gophers/graphql-go"
+	"sort"
+	"strconv"
+	"time"
+)
+
+var (
+	errUnauthorized = errors.New("unauthorized")
+	errForbidden    = errors.New("forbidden")
+	errReadOnly     = errors.New("mutations must be sent with POST")
+)
+
+// resolver is the root of the schema, it holds the services every request
+// shares. Per request state lives in the context, see requestLoaders
+type resolver struct {
+	products service.ProductService
+	brands   service.BrandService
+	history  service.PriceHistoryService
+	pricing  service.PricingService
+}
+
+// requestLoaders batch the nested lookups of a single request
+type requestLoaders struct {
+	products *loader[int, *models.ProductResponse]
+	brands   *loader[int, *models.Brand]
+	history  *loader[historyKey, []models.PricePoint]
+	prices   *loader[pricesKey, models.ProductResponse]
+	readOnly bool
+}
+
+type historyKey struct {
+	productId int
+	since     time.Time
+}
+
+// pricesKey identifies a product of a list by its place in the list, so the
+// products priced are exactly the ones being resolved
+type pricesKey struct {
+	product  *models.ProductResponse
+	currency string
+}
+
+type loadersKey struct{}
+
+func (r *resolver) newLoaders(readOnly bool) *requestLoaders {
+	// every price in the response is taken at the same moment
+	at := time.Now()
+	return &requestLoaders{
+		readOnly: readOnly,
+		products: newLoader(func(ctx context.Context, ids []int) (map[int]*models.ProductResponse, error) {
+			products, err := r.products.GetAllProducts(ctx, models.ProductFilter{Ids: ids})
+			found := map[int]*models.ProductResponse{}
+			for i := range products {
+				found[products[i].Id] = &products[i]
+			}
+			return found, err
+		}),
+		// brands are few, a single query fetches all of them
+		brands: newLoader(func(ctx context.Context, ids []int) (map[int]*models.Brand, error) {
+			brands, err := r.brands.GetAllBrands()
+			found := map[int]*models.Brand{}
+			for i := range brands {
+				found[brands[i].Id] = &brands[i]
+			}
+			return found, err
+		}),
+		history: newLoader(func(ctx context.Context, keys []historyKey) (map[historyKey][]models.PricePoint, error) {
+			bySince := map[time.Time][]int{}
+			for _, key := range keys {
+				bySince[key.since] = append(bySince[key.since], key.productId)
+			}
+			found := map[historyKey][]models.PricePoint{}
+			for since, ids := range bySince {
+				filter := models.PriceHistoryFilter{ProductIds: ids}
+				if !since.IsZero() {
+					filter.Since = &since
+				}
+				points, err := r.history.GetPriceHistory(filter)
+				if err != nil {
+					return found, err
+				}
+				for _, point := range points {
+					key := historyKey{productId: point.ProductId, since: since}
+					found[key] = append(found[key], point)
+				}
+			}
+			return found, nil
+		}),
+		prices: newLoader(func(ctx context.Context, keys []pricesKey) (map[pricesKey]models.ProductResponse, error) {
+			byCurrency := map[string][]pricesKey{}
+			for _, key := range keys {
+				byCurrency[key.currency] = append(byCurrency[key.currency], key)
+			}
+			found := map[pricesKey]models.ProductResponse{}
+			for currency, keys := range byCurrency {
+				products := make([]models.ProductResponse, len(keys))
+				for i, key := range keys {
+					products[i] = *key.product
+				}
+				priced, err := r.pricing.PriceProducts(products, currency, at)
+				if err != nil {
+					return found, err
+				}
+				for i, key := range keys {
+					found[key] = priced[i]
+				}
+			}
+			return found, nil
+		}),
+	}
+}
+
+func loadersFrom(ctx context.Context) *requestLoaders {
+	return ctx.Value(loadersKey{}).(*requestLoaders)
+}
+
+func parseId(id graphql.ID) (int, error) {
+	value, err := strconv.Atoi(string(id))
+	if err != nil {
+		return 0, fmt.Errorf("invalid id %q", id)
+	}
+	return value, nil
+}
+
+func optionalId(id *graphql.ID) (*int, error) {
+	if id == nil {
+		return nil, nil
+	}
+	value, err := parseId(*id)
+	return &value, err
+}
+
+func idOf(id int) graphql.ID {
+	return graphql.ID(strconv.Itoa(id))
+}
+
+// newProducts wraps a list of products, each product knows its siblings so
+// the loaders can batch their lookups
+func newProducts(products []models.ProductResponse) []*productResolver {
+	resolvers := make([]*productResolver, len(products))
+	for i := range products {
+		resolvers[i] = &productResolver{product: products[i], siblings: products, index: i}
+	}
+	return resolvers
+}
+
+func newProduct(product models.ProductResponse) *productResolver {
+	return newProducts([]models.ProductResponse{product})[0]
+}
+
+type productsArgs struct {
+	Ids      *[]graphql.ID
+	Category *graphql.ID
+	Tags     *[]string
+	Brand    *graphql.ID
+	Parent   *graphql.ID
+	MaxPrice *float64
+}
+
+func (r *resolver) Products(ctx context.Context, args productsArgs) ([]*productResolver, error) {
+	var filter models.ProductFilter
+	var err error
+	if args.Ids != nil {
+		for _, id := range *args.Ids {
+			value, err := parseId(id)
+			if err != nil {
+				return nil, err
+			}
+			filter.Ids = append(filter.Ids, value)
+		}
+		if len(filter.Ids) == 0 {
+			return []*productResolver{}, nil
+		}
+	}
+	if filter.CategoryId, err = optionalId(args.Category); err != nil {
+		return nil, err
+	}
+	if filter.BrandId, err = optionalId(args.Brand); err != nil {
+		return nil, err
+	}
+	if filter.ParentId, err = optionalId(args.Parent); err != nil {
+		return nil, err
+	}
+	if args.Tags != nil {
+		for _, tag := range *args.Tags {
+			name, err := models.NormalizeTag(tag)
+			if err != nil {
+				return nil, err
+			}
+			filter.Tags = append(filter.Tags, name)
+		}
+	}
+	filter.MaxPrice = args.MaxPrice
+	products, err := r.products.GetAllProducts(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return newProducts(products), nil
+}
+
+func (r *resolver) Product(ctx context.Context, args struct{ Id graphql.ID }) (*productResolver, error) {
+	id, err := parseId(args.Id)
+	if err != nil {
+		return nil, err
+	}
+	product, err := loadersFrom(ctx).products.load(ctx, id, nil)
+	if err != nil || product == nil {
+		return nil, err
+	}
+	return newProduct(*product), nil
+}
+
+func (r *resolver) ProductByBarcode(ctx context.Context, args struct{ Code string }) (*productResolver, error) {
+	product, err := r.products.GetProductByBarcode(ctx, args.Code)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newProduct(product.ToJSON()), nil
+}
+
+// authorize fails unless the caller holds role and the request may write
+func authorize(ctx context.Context, role models.Role) error {
+	if loadersFrom(ctx).readOnly {
+		return errReadOnly
+	}
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return errUnauthorized
+	}
+	if !principal.Role.Allows(role) {
+		return errForbidden
+	}
+	return nil
+}
+
+type productInput struct {
+	Name     *string
+	Quantity *float64
+	Unit     *string
+	BrandId  *graphql.ID
+	ParentId *graphql.ID
+	Barcodes *[]string
+	Stores   *[]storePriceInput
+}
+
+type storePriceInput struct {
+	Store string
+	Price float64
+}
+
+func storesOf(prices []storePriceInput) models.Stores {
+	stores := models.Stores{}
+	for _, price := range prices {
+		stores[price.Store] = price.Price
+	}
+	return stores
+}
+
+func (in productInput) product() (models.ProductResponse, error) {
+	product := models.ProductResponse{Name: in.Name, Quantity: in.Quantity, Unit: in.Unit}
+	var err error
+	if product.BrandId, err = optionalId(in.BrandId); err != nil {
+		return product, err
+	}
+	if product.ParentId, err = optionalId(in.ParentId); err != nil {
+		return product, err
+	}
+	if in.Barcodes != nil {
+		product.Barcodes = *in.Barcodes
+	}
+	if in.Stores != nil {
+		stores := storesOf(*in.Stores)
+		product.Stores = &stores
+	}
+	return product, nil
+}
+
+type productArgs struct {
+	Id    graphql.ID
+	Input productInput
+}
+
+func (r *resolver) CreateProduct(ctx context.Context, args struct{ Input productInput }) (*productResolver, error) {
+	if err := authorize(ctx, models.RoleAdmin); err != nil {
+		return nil, err
+	}
+	product, err := args.Input.product()
+	if err != nil {
+		return nil, err
+	}
+	created, err := r.products.CreateProduct(ctx, product)
+	if err != nil {
+		return nil, err
+	}
+	return newProduct(created), nil
+}
+
+func (r *resolver) UpdateProduct(ctx context.Context, args productArgs) (*productResolver, error) {
+	if err := authorize(ctx, models.RoleAdmin); err != nil {
+		return nil, err
+	}
+	product, err := args.Input.product()
+	if err != nil {
+		return nil, err
+	}
+	updated, err := r.products.UpdateProduct(ctx, string(args.Id), product)
+	if err != nil {
+		return nil, err
+	}
+	return newProduct(updated), nil
+}
+
+func (r *resolver) PatchProduct(ctx context.Context, args productArgs) (*productResolver, error) {
+	if err := authorize(ctx, models.RoleAdmin); err != nil {
+		return nil, err
+	}
+	product, err := args.Input.product()
+	if err != nil {
+		return nil, err
+	}
+	patched, err := r.products.PatchProduct(ctx, string(args.Id), product)
+	if err != nil {
+		return nil, err
+	}
+	return newProduct(patched.ToJSON()), nil
+}
+
+func (r *resolver) SetStorePrices(ctx context.Context, args struct {
+	Id     graphql.ID
+	Prices []storePriceInput
+}) (*productResolver, error) {
+	if err := authorize(ctx, models.RolePriceCollector); err != nil {
+		return nil, err
+	}
+	jsonStore, err := json.Marshal(storesOf(args.Prices))
+	if err != nil {
+		return nil, err
+	}
+	patched, err := r.products.PatchStore(ctx, string(args.Id), jsonStore)
+	if err != nil {
+		return nil, err
+	}
+	return newProduct(patched.ToJSON()), nil
+}
+
+func (r *resolver) DeleteProduct(ctx context.Context, args struct{ Id graphql.ID }) (bool, error) {
+	if err := authorize(ctx, models.RoleAdmin); err != nil {
+		return false, err
+	}
+	if err := r.products.DeleteProduct(ctx, string(args.Id)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+type productResolver struct {
+	product  models.ProductResponse
+	siblings []models.ProductResponse
+	index    int
+}
+
+func (p *productResolver) ID() graphql.ID {
+	return idOf(p.product.Id)
+}
+
+func (p *productResolver) Name() *string {
+	return p.product.Name
+}
+
+func (p *productResolver) Quantity() *float64 {
+	return p.product.Quantity
+}
+
+func (p *productResolver) Unit() *string {
+	return p.product.Unit
+}
+
+func (p *productResolver) Barcodes() []string {
+	if p.product.Barcodes == nil {
+		return []string{}
+	}
+	return p.product.Barcodes
+}
+
+func (p *productResolver) Tags() []string {
+	if p.product.Tags == nil {
+		return []string{}
+	}
+	return p.product.Tags
+}
+
+func (p *productResolver) Categories() []*categoryResolver {
+	categories := make([]*categoryResolver, len(p.product.Categories))
+	for i := range p.product.Categories {
+		categories[i] = &categoryResolver{p.product.Categories[i]}
+	}
+	return categories
+}
+
+func (p *productResolver) Brand(ctx context.Context) (*brandResolver, error) {
+	if p.product.BrandId == nil {
+		return nil, nil
+	}
+	var batch []int
+	for _, sibling := range p.siblings {
+		if sibling.BrandId != nil {
+			batch = append(batch, *sibling.BrandId)
+		}
+	}
+	brand, err := loadersFrom(ctx).brands.load(ctx, *p.product.BrandId, batch)
+	if err != nil || brand == nil {
+		return nil, err
+	}
+	return &brandResolver{*brand}, nil
+}
+
+func (p *productResolver) Parent(ctx context.Context) (*productResolver, error) {
+	if p.product.ParentId == nil {
+		return nil, nil
+	}
+	var batch []int
+	for _, sibling := range p.siblings {
+		if sibling.ParentId != nil {
+			batch = append(batch, *sibling.ParentId)
+		}
+	}
+	parent, err := loadersFrom(ctx).products.load(ctx, *p.product.ParentId, batch)
+	if err != nil || parent == nil {
+		return nil, err
+	}
+	return newProduct(*parent), nil
+}
+
+// pricingCurrency is the currency the stores of product are shown in, the
+// requested one or colones when the stores use different currencies, as
+// prices in different currencies can't be ranked
+func pricingCurrency(product models.ProductResponse, requested *string) string {
+	if requested != nil {
+		return *requested
+	}
+	seen := ""
+	for store := range *product.Stores {
+		if currency := product.Currencies.Of(store); seen == "" {
+			seen = currency
+		} else if currency != seen {
+			return models.DefaultCurrency
+		}
+	}
+	return ""
+}
+
+// Stores: Lists the store prices with the promotions and conversion the REST
+// API applies, ranked by effective price so cheapest keeps the stores that
+// actually charge the least
+func (p *productResolver) Stores(ctx context.Context, args struct {
+	Cheapest *int32
+	Currency *string
+}) ([]*storePriceResolver, error) {
+	stores := []*storePriceResolver{}
+	if p.product.Stores == nil {
+		return stores, nil
+	}
+	var batch []pricesKey
+	for i, sibling := range p.siblings {
+		if sibling.Stores != nil {
+			batch = append(batch, pricesKey{&p.siblings[i], pricingCurrency(sibling, args.Currency)})
+		}
+	}
+	key := pricesKey{&p.siblings[p.index], pricingCurrency(p.product, args.Currency)}
+	product, err := loadersFrom(ctx).prices.load(ctx, key, batch)
+	if err != nil {
+		return nil, err
+	}
+	var quantity float64
+	var baseUnit string
+	if product.Quantity != nil && *product.Quantity > 0 {
+		unit := ""
+		if product.Unit != nil {
+			unit = *product.Unit
+		}
+		quantity, baseUnit = models.BaseQuantity(*product.Quantity, unit)
+	}
+	for store, price := range *product.Stores {
+		resolver := &storePriceResolver{product: p, store: store, price: price, effectivePrice: price,
+			currency: product.Currencies.Of(store)}
+		if product.Conversion != nil {
+			resolver.currency = product.Conversion.Currency
+		}
+		if product.EffectivePrices != nil {
+			if effective, ok := (*product.EffectivePrices)[store]; ok {
+				resolver.effectivePrice = effective
+			}
+		}
+		if quantity > 0 {
+			unitPrice := resolver.effectivePrice / quantity
+			resolver.unitPrice, resolver.baseUnit = &unitPrice, &baseUnit
+		}
+		stores = append(stores, resolver)
+	}
+	sort.Slice(stores, func(i, j int) bool {
+		if stores[i].effectivePrice != stores[j].effectivePrice {
+			return stores[i].effectivePrice < stores[j].effectivePrice
+		}
+		return stores[i].store < stores[j].store
+	})
+	if args.Cheapest != nil && int(*args.Cheapest) >= 0 && int(*args.Cheapest) < len(stores) {
+		stores = stores[:*args.Cheapest]
+	}
+	return stores, nil
+}
+
+// history loads the price history of the product recorded since, batched with
+// the history of its siblings
+func (p *productResolver) history(ctx context.Context, store *string, since *graphql.Time) ([]*pricePointResolver, error) {
+	var from time.Time
+	if since != nil {
+		from = since.Time
+	}
+	batch := make([]historyKey, len(p.siblings))
+	for i, sibling := range p.siblings {
+		batch[i] = historyKey{productId: sibling.Id, since: from}
+	}
+	points, err := loadersFrom(ctx).history.load(ctx, historyKey{productId: p.product.Id, since: from}, batch)
+	if err != nil {
+		return nil, err
+	}
+	resolvers := []*pricePointResolver{}
+	for _, point := range points {
+		if store == nil || point.Store == *store {
+			resolvers = append(resolvers, &pricePointResolver{point})
+		}
+	}
+	return resolvers, nil
+}
+
+func (p *productResolver) PriceHistory(ctx context.Context, args struct {
+	Store *string
+	Since *graphql.Time
+}) ([]*pricePointResolver, error) {
+	return p.history(ctx, args.Store, args.Since)
+}
+
+type storePriceResolver struct {
+	product        *productResolver
+	store          string
+	price          float64
+	effectivePrice float64
+	currency       string
+	unitPrice      *float64
+	baseUnit       *string
+}
+
+func (s *storePriceResolver) Store() string {
+	return s.store
+}
+
+func (s *storePriceResolver) Price() float64 {
+	return s.price
+}
+
+func (s *storePriceResolver) EffectivePrice() float64 {
+	return s.effectivePrice
+}
+
+func (s *storePriceResolver) Currency() string {
+	return s.currency
+}
+
+func (s *storePriceResolver) UnitPrice() *float64 {
+	return s.unitPrice
+}
+
+func (s *storePriceResolver) BaseUnit() *string {
+	return s.baseUnit
+}
+
+func (s *storePriceResolver) History(ctx context.Context, args struct{ Since *graphql.Time }) ([]*pricePointResolver, error) {
+	return s.product.history(ctx, &s.store, args.Since)
+}
+
+type pricePointResolver struct {
+	point models.PricePoint
+}
+
+func (p *pricePointResolver) Store() string {
+	return p.point.Store
+}
+
+func (p *pricePointResolver) Price() *float64 {
+	return p.point.Price
+}
+
+func (p *pricePointResolver) RecordedAt() graphql.Time {
+	return graphql.Time{Time: p.point.RecordedAt}
+}
+
+type brandResolver struct {
+	brand models.Brand
+}
+
+func (b *brandResolver) ID() graphql.ID {
+	return idOf(b.brand.Id)
+}
+
+func (b *brandResolver) Name() string {
+	return b.brand.Name
+}
+
+type categoryResolver struct {
+	category models.Category
+}
+
+func (c *categoryResolver) ID() graphql.ID {
+	return idOf(c.category.Id)
+}
+
+func (c *categoryResolver) Name() string {
+	return c.category.Name
+}
+
+func (c *categoryResolver) ParentId() *graphql.ID {
+	if c.category.ParentId == nil {
+		return nil
+	}
+	id := idOf(*c.category.ParentId)
+	return &id
+}
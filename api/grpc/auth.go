@@ -0,0 +1,105 @@
+package grpc
+
+import (
+	"context"
+	"crproductos/internal/auth"
+	"crproductos/internal/models"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var errMissingProduct = status.Error(codes.InvalidArgument, "product is required")
+
+// statusOf maps the service errors to their gRPC status, the same way
+// errorStatus does for HTTP
+func statusOf(err error) error {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return status.Error(codes.NotFound, "not found")
+	case errors.Is(err, models.ErrInvalidBarcode), errors.Is(err, models.ErrInvalidTag),
+		errors.Is(err, models.ErrInvalidCategory), errors.Is(err, models.ErrCategoryNotFound),
+		errors.Is(err, models.ErrInvalidBrand), errors.Is(err, models.ErrBrandNotFound),
+		errors.Is(err, models.ErrInvalidVariant), errors.Is(err, models.ErrInvalidCurrency):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, models.ErrDuplicateBarcode):
+		return status.Error(codes.AlreadyExists, err.Error())
+	}
+	slog.Error("grpc request failed", "err", err)
+	return status.Error(codes.Internal, "internal error")
+}
+
+// authenticateContext: Runs the authorization and x-api-key metadata through
+// the authenticators like the HTTP middleware does with the headers. Calls
+// without credentials go through anonymous, bad credentials are refused
+func authenticateContext(ctx context.Context, authenticators []auth.Authenticator) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	r := &http.Request{Header: http.Header{}}
+	for _, key := range []string{"Authorization", "X-API-Key"} {
+		for _, value := range md.Get(key) {
+			r.Header.Add(key, value)
+		}
+	}
+	r = r.WithContext(ctx)
+	for _, authenticator := range authenticators {
+		principal, err := authenticator.Authenticate(r)
+		if errors.Is(err, auth.ErrNoCredentials) {
+			continue
+		}
+		if err != nil {
+			slog.Error("rejected credentials", "err", err)
+			return nil, status.Error(codes.Unauthenticated, "unauthorized")
+		}
+		return auth.NewContext(ctx, principal), nil
+	}
+	return ctx, nil
+}
+
+// UnaryAuthenticator authenticates unary calls, see authenticateContext
+func UnaryAuthenticator(authenticators ...auth.Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := authenticateContext(ctx, authenticators)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamAuthenticator authenticates streaming calls, see authenticateContext
+func StreamAuthenticator(authenticators ...auth.Authenticator) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticateContext(stream.Context(), authenticators)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authenticatedStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+// require only lets calls through whose principal holds role
+func require(ctx context.Context, role models.Role) error {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "unauthorized")
+	}
+	if !principal.Role.Allows(role) {
+		return status.Error(codes.PermissionDenied, "forbidden")
+	}
+	return nil
+}
@@ -0,0 +1,1052 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: products.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Category struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	ParentId      *int32                 `protobuf:"varint,3,opt,name=parent_id,json=parentId,proto3,oneof" json:"parent_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Category) Reset() {
+	*x = Category{}
+	mi := &file_products_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Category) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Category) ProtoMessage() {}
+
+func (x *Category) ProtoReflect() protoreflect.Message {
+	mi := &file_products_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Category.ProtoReflect.Descriptor instead.
+func (*Category) Descriptor() ([]byte, []int) {
+	return file_products_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Category) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Category) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Category) GetParentId() int32 {
+	if x != nil && x.ParentId != nil {
+		return *x.ParentId
+	}
+	return 0
+}
+
+type Product struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Id       int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name     *string                `protobuf:"bytes,2,opt,name=name,proto3,oneof" json:"name,omitempty"`
+	Quantity *float64               `protobuf:"fixed64,3,opt,name=quantity,proto3,oneof" json:"quantity,omitempty"`
+	Unit     *string                `protobuf:"bytes,4,opt,name=unit,proto3,oneof" json:"unit,omitempty"`
+	BrandId  *int32                 `protobuf:"varint,5,opt,name=brand_id,json=brandId,proto3,oneof" json:"brand_id,omitempty"`
+	// The product this one is a size variant of
+	ParentId *int32 `protobuf:"varint,6,opt,name=parent_id,json=parentId,proto3,oneof" json:"parent_id,omitempty"`
+	// Price by store name
+	Stores map[string]float64 `protobuf:"bytes,7,rep,name=stores,proto3" json:"stores,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"fixed64,2,opt,name=value"`
+	// Currency by store name, for the stores not priced in colones
+	Currencies map[string]string `protobuf:"bytes,8,rep,name=currencies,proto3" json:"currencies,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Barcodes   []string          `protobuf:"bytes,9,rep,name=barcodes,proto3" json:"barcodes,omitempty"`
+	// Categories and tags are read only
+	Categories    []*Category `protobuf:"bytes,10,rep,name=categories,proto3" json:"categories,omitempty"`
+	Tags          []string    `protobuf:"bytes,11,rep,name=tags,proto3" json:"tags,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Product) Reset() {
+	*x = Product{}
+	mi := &file_products_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Product) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Product) ProtoMessage() {}
+
+func (x *Product) ProtoReflect() protoreflect.Message {
+	mi := &file_products_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Product.ProtoReflect.Descriptor instead.
+func (*Product) Descriptor() ([]byte, []int) {
+	return file_products_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Product) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Product) GetName() string {
+	if x != nil && x.Name != nil {
+		return *x.Name
+	}
+	return ""
+}
+
+func (x *Product) GetQuantity() float64 {
+	if x != nil && x.Quantity != nil {
+		return *x.Quantity
+	}
+	return 0
+}
+
+func (x *Product) GetUnit() string {
+	if x != nil && x.Unit != nil {
+		return *x.Unit
+	}
+	return ""
+}
+
+func (x *Product) GetBrandId() int32 {
+	if x != nil && x.BrandId != nil {
+		return *x.BrandId
+	}
+	return 0
+}
+
+func (x *Product) GetParentId() int32 {
+	if x != nil && x.ParentId != nil {
+		return *x.ParentId
+	}
+	return 0
+}
+
+func (x *Product) GetStores() map[string]float64 {
+	if x != nil {
+		return x.Stores
+	}
+	return nil
+}
+
+func (x *Product) GetCurrencies() map[string]string {
+	if x != nil {
+		return x.Currencies
+	}
+	return nil
+}
+
+func (x *Product) GetBarcodes() []string {
+	if x != nil {
+		return x.Barcodes
+	}
+	return nil
+}
+
+func (x *Product) GetCategories() []*Category {
+	if x != nil {
+		return x.Categories
+	}
+	return nil
+}
+
+func (x *Product) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+// Unset fields don't filter
+type ListProductsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Also matches the subcategories
+	CategoryId *int32 `protobuf:"varint,1,opt,name=category_id,json=categoryId,proto3,oneof" json:"category_id,omitempty"`
+	// Every tag must be present on the product
+	Tags []string `protobuf:"bytes,2,rep,name=tags,proto3" json:"tags,omitempty"`
+	// Also matches the variants of the products of the brand
+	BrandId  *int32 `protobuf:"varint,3,opt,name=brand_id,json=brandId,proto3,oneof" json:"brand_id,omitempty"`
+	ParentId *int32 `protobuf:"varint,4,opt,name=parent_id,json=parentId,proto3,oneof" json:"parent_id,omitempty"`
+	// Sold at or below this price in colones in at least one store
+	MaxPrice      *float64 `protobuf:"fixed64,5,opt,name=max_price,json=maxPrice,proto3,oneof" json:"max_price,omitempty"`
+	Ids           []int32  `protobuf:"varint,6,rep,packed,name=ids,proto3" json:"ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListProductsRequest) Reset() {
+	*x = ListProductsRequest{}
+	mi := &file_products_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListProductsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListProductsRequest) ProtoMessage() {}
+
+func (x *ListProductsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_products_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListProductsRequest.ProtoReflect.Descriptor instead.
+func (*ListProductsRequest) Descriptor() ([]byte, []int) {
+	return file_products_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ListProductsRequest) GetCategoryId() int32 {
+	if x != nil && x.CategoryId != nil {
+		return *x.CategoryId
+	}
+	return 0
+}
+
+func (x *ListProductsRequest) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *ListProductsRequest) GetBrandId() int32 {
+	if x != nil && x.BrandId != nil {
+		return *x.BrandId
+	}
+	return 0
+}
+
+func (x *ListProductsRequest) GetParentId() int32 {
+	if x != nil && x.ParentId != nil {
+		return *x.ParentId
+	}
+	return 0
+}
+
+func (x *ListProductsRequest) GetMaxPrice() float64 {
+	if x != nil && x.MaxPrice != nil {
+		return *x.MaxPrice
+	}
+	return 0
+}
+
+func (x *ListProductsRequest) GetIds() []int32 {
+	if x != nil {
+		return x.Ids
+	}
+	return nil
+}
+
+type ListProductsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Products      []*Product             `protobuf:"bytes,1,rep,name=products,proto3" json:"products,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListProductsResponse) Reset() {
+	*x = ListProductsResponse{}
+	mi := &file_products_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListProductsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListProductsResponse) ProtoMessage() {}
+
+func (x *ListProductsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_products_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListProductsResponse.ProtoReflect.Descriptor instead.
+func (*ListProductsResponse) Descriptor() ([]byte, []int) {
+	return file_products_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ListProductsResponse) GetProducts() []*Product {
+	if x != nil {
+		return x.Products
+	}
+	return nil
+}
+
+type GetProductRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetProductRequest) Reset() {
+	*x = GetProductRequest{}
+	mi := &file_products_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetProductRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetProductRequest) ProtoMessage() {}
+
+func (x *GetProductRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_products_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetProductRequest.ProtoReflect.Descriptor instead.
+func (*GetProductRequest) Descriptor() ([]byte, []int) {
+	return file_products_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetProductRequest) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type GetProductByBarcodeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Barcode       string                 `protobuf:"bytes,1,opt,name=barcode,proto3" json:"barcode,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetProductByBarcodeRequest) Reset() {
+	*x = GetProductByBarcodeRequest{}
+	mi := &file_products_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetProductByBarcodeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetProductByBarcodeRequest) ProtoMessage() {}
+
+func (x *GetProductByBarcodeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_products_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetProductByBarcodeRequest.ProtoReflect.Descriptor instead.
+func (*GetProductByBarcodeRequest) Descriptor() ([]byte, []int) {
+	return file_products_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GetProductByBarcodeRequest) GetBarcode() string {
+	if x != nil {
+		return x.Barcode
+	}
+	return ""
+}
+
+type CreateProductRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Product       *Product               `protobuf:"bytes,1,opt,name=product,proto3" json:"product,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateProductRequest) Reset() {
+	*x = CreateProductRequest{}
+	mi := &file_products_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateProductRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateProductRequest) ProtoMessage() {}
+
+func (x *CreateProductRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_products_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateProductRequest.ProtoReflect.Descriptor instead.
+func (*CreateProductRequest) Descriptor() ([]byte, []int) {
+	return file_products_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *CreateProductRequest) GetProduct() *Product {
+	if x != nil {
+		return x.Product
+	}
+	return nil
+}
+
+type UpdateProductRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Product       *Product               `protobuf:"bytes,2,opt,name=product,proto3" json:"product,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateProductRequest) Reset() {
+	*x = UpdateProductRequest{}
+	mi := &file_products_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateProductRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateProductRequest) ProtoMessage() {}
+
+func (x *UpdateProductRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_products_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateProductRequest.ProtoReflect.Descriptor instead.
+func (*UpdateProductRequest) Descriptor() ([]byte, []int) {
+	return file_products_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *UpdateProductRequest) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *UpdateProductRequest) GetProduct() *Product {
+	if x != nil {
+		return x.Product
+	}
+	return nil
+}
+
+type PatchProductRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Product       *Product               `protobuf:"bytes,2,opt,name=product,proto3" json:"product,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PatchProductRequest) Reset() {
+	*x = PatchProductRequest{}
+	mi := &file_products_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PatchProductRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PatchProductRequest) ProtoMessage() {}
+
+func (x *PatchProductRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_products_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PatchProductRequest.ProtoReflect.Descriptor instead.
+func (*PatchProductRequest) Descriptor() ([]byte, []int) {
+	return file_products_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *PatchProductRequest) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *PatchProductRequest) GetProduct() *Product {
+	if x != nil {
+		return x.Product
+	}
+	return nil
+}
+
+type PatchStoreRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Prices        map[string]float64     `protobuf:"bytes,2,rep,name=prices,proto3" json:"prices,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"fixed64,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PatchStoreRequest) Reset() {
+	*x = PatchStoreRequest{}
+	mi := &file_products_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PatchStoreRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PatchStoreRequest) ProtoMessage() {}
+
+func (x *PatchStoreRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_products_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PatchStoreRequest.ProtoReflect.Descriptor instead.
+func (*PatchStoreRequest) Descriptor() ([]byte, []int) {
+	return file_products_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *PatchStoreRequest) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *PatchStoreRequest) GetPrices() map[string]float64 {
+	if x != nil {
+		return x.Prices
+	}
+	return nil
+}
+
+type DeleteProductRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteProductRequest) Reset() {
+	*x = DeleteProductRequest{}
+	mi := &file_products_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteProductRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteProductRequest) ProtoMessage() {}
+
+func (x *DeleteProductRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_products_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteProductRequest.ProtoReflect.Descriptor instead.
+func (*DeleteProductRequest) Descriptor() ([]byte, []int) {
+	return file_products_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *DeleteProductRequest) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type DeleteProductResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteProductResponse) Reset() {
+	*x = DeleteProductResponse{}
+	mi := &file_products_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteProductResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteProductResponse) ProtoMessage() {}
+
+func (x *DeleteProductResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_products_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteProductResponse.ProtoReflect.Descriptor instead.
+func (*DeleteProductResponse) Descriptor() ([]byte, []int) {
+	return file_products_proto_rawDescGZIP(), []int{11}
+}
+
+// Empty lists watch every product and store
+type WatchPricesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ProductIds    []int32                `protobuf:"varint,1,rep,packed,name=product_ids,json=productIds,proto3" json:"product_ids,omitempty"`
+	Stores        []string               `protobuf:"bytes,2,rep,name=stores,proto3" json:"stores,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchPricesRequest) Reset() {
+	*x = WatchPricesRequest{}
+	mi := &file_products_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchPricesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchPricesRequest) ProtoMessage() {}
+
+func (x *WatchPricesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_products_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchPricesRequest.ProtoReflect.Descriptor instead.
+func (*WatchPricesRequest) Descriptor() ([]byte, []int) {
+	return file_products_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *WatchPricesRequest) GetProductIds() []int32 {
+	if x != nil {
+		return x.ProductIds
+	}
+	return nil
+}
+
+func (x *WatchPricesRequest) GetStores() []string {
+	if x != nil {
+		return x.Stores
+	}
+	return nil
+}
+
+type PriceChange struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	EventId   string                 `protobuf:"bytes,1,opt,name=event_id,json=eventId,proto3" json:"event_id,omitempty"`
+	ProductId int32                  `protobuf:"varint,2,opt,name=product_id,json=productId,proto3" json:"product_id,omitempty"`
+	Store     string                 `protobuf:"bytes,3,opt,name=store,proto3" json:"store,omitempty"`
+	// Unset when the store did not list the product before
+	PreviousPrice *float64 `protobuf:"fixed64,4,opt,name=previous_price,json=previousPrice,proto3,oneof" json:"previous_price,omitempty"`
+	// Unset when the store stopped listing the product
+	Price         *float64               `protobuf:"fixed64,5,opt,name=price,proto3,oneof" json:"price,omitempty"`
+	OccurredAt    *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=occurred_at,json=occurredAt,proto3" json:"occurred_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PriceChange) Reset() {
+	*x = PriceChange{}
+	mi := &file_products_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PriceChange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PriceChange) ProtoMessage() {}
+
+func (x *PriceChange) ProtoReflect() protoreflect.Message {
+	mi := &file_products_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PriceChange.ProtoReflect.Descriptor instead.
+func (*PriceChange) Descriptor() ([]byte, []int) {
+	return file_products_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *PriceChange) GetEventId() string {
+	if x != nil {
+		return x.EventId
+	}
+	return ""
+}
+
+func (x *PriceChange) GetProductId() int32 {
+	if x != nil {
+		return x.ProductId
+	}
+	return 0
+}
+
+func (x *PriceChange) GetStore() string {
+	if x != nil {
+		return x.Store
+	}
+	return ""
+}
+
+func (x *PriceChange) GetPreviousPrice() float64 {
+	if x != nil && x.PreviousPrice != nil {
+		return *x.PreviousPrice
+	}
+	return 0
+}
+
+func (x *PriceChange) GetPrice() float64 {
+	if x != nil && x.Price != nil {
+		return *x.Price
+	}
+	return 0
+}
+
+func (x *PriceChange) GetOccurredAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.OccurredAt
+	}
+	return nil
+}
+
+var File_products_proto protoreflect.FileDescriptor
+
+const file_products_proto_rawDesc = "" +
+	"\n" +
+	"\x0eproducts.proto\x12\x0ecrproductos.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"^\n" +
+	"\bCategory\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12 \n" +
+	"\tparent_id\x18\x03 \x01(\x05H\x00R\bparentId\x88\x01\x01B\f\n" +
+	"\n" +
+	"_parent_id\"\xd2\x04\n" +
+	"\aProduct\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\x12\x17\n" +
+	"\x04name\x18\x02 \x01(\tH\x00R\x04name\x88\x01\x01\x12\x1f\n" +
+	"\bquantity\x18\x03 \x01(\x01H\x01R\bquantity\x88\x01\x01\x12\x17\n" +
+	"\x04unit\x18\x04 \x01(\tH\x02R\x04unit\x88\x01\x01\x12\x1e\n" +
+	"\bbrand_id\x18\x05 \x01(\x05H\x03R\abrandId\x88\x01\x01\x12 \n" +
+	"\tparent_id\x18\x06 \x01(\x05H\x04R\bparentId\x88\x01\x01\x12;\n" +
+	"\x06stores\x18\a \x03(\v2#.crproductos.v1.Product.StoresEntryR\x06stores\x12G\n" +
+	"\n" +
+	"currencies\x18\b \x03(\v2'.crproductos.v1.Product.CurrenciesEntryR\n" +
+	"currencies\x12\x1a\n" +
+	"\bbarcodes\x18\t \x03(\tR\bbarcodes\x128\n" +
+	"\n" +
+	"categories\x18\n" +
+	" \x03(\v2\x18.crproductos.v1.CategoryR\n" +
+	"categories\x12\x12\n" +
+	"\x04tags\x18\v \x03(\tR\x04tags\x1a9\n" +
+	"\vStoresEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x01R\x05value:\x028\x01\x1a=\n" +
+	"\x0fCurrenciesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01B\a\n" +
+	"\x05_nameB\v\n" +
+	"\t_quantityB\a\n" +
+	"\x05_unitB\v\n" +
+	"\t_brand_idB\f\n" +
+	"\n" +
+	"_parent_id\"\xfe\x01\n" +
+	"\x13ListProductsRequest\x12$\n" +
+	"\vcategory_id\x18\x01 \x01(\x05H\x00R\n" +
+	"categoryId\x88\x01\x01\x12\x12\n" +
+	"\x04tags\x18\x02 \x03(\tR\x04tags\x12\x1e\n" +
+	"\bbrand_id\x18\x03 \x01(\x05H\x01R\abrandId\x88\x01\x01\x12 \n" +
+	"\tparent_id\x18\x04 \x01(\x05H\x02R\bparentId\x88\x01\x01\x12 \n" +
+	"\tmax_price\x18\x05 \x01(\x01H\x03R\bmaxPrice\x88\x01\x01\x12\x10\n" +
+	"\x03ids\x18\x06 \x03(\x05R\x03idsB\x0e\n" +
+	"\f_category_idB\v\n" +
+	"\t_brand_idB\f\n" +
+	"\n" +
+	"_parent_idB\f\n" +
+	"\n" +
+	"_max_price\"K\n" +
+	"\x14ListProductsResponse\x123\n" +
+	"\bproducts\x18\x01 \x03(\v2\x17.crproductos.v1.ProductR\bproducts\"#\n" +
+	"\x11GetProductRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\"6\n" +
+	"\x1aGetProductByBarcodeRequest\x12\x18\n" +
+	"\abarcode\x18\x01 \x01(\tR\abarcode\"I\n" +
+	"\x14CreateProductRequest\x121\n" +
+	"\aproduct\x18\x01 \x01(\v2\x17.crproductos.v1.ProductR\aproduct\"Y\n" +
+	"\x14UpdateProductRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\x121\n" +
+	"\aproduct\x18\x02 \x01(\v2\x17.crproductos.v1.ProductR\aproduct\"X\n" +
+	"\x13PatchProductRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\x121\n" +
+	"\aproduct\x18\x02 \x01(\v2\x17.crproductos.v1.ProductR\aproduct\"\xa5\x01\n" +
+	"\x11PatchStoreRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\x12E\n" +
+	"\x06prices\x18\x02 \x03(\v2-.crproductos.v1.PatchStoreRequest.PricesEntryR\x06prices\x1a9\n" +
+	"\vPricesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x01R\x05value:\x028\x01\"&\n" +
+	"\x14DeleteProductRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\"\x17\n" +
+	"\x15DeleteProductResponse\"M\n" +
+	"\x12WatchPricesRequest\x12\x1f\n" +
+	"\vproduct_ids\x18\x01 \x03(\x05R\n" +
+	"productIds\x12\x16\n" +
+	"\x06stores\x18\x02 \x03(\tR\x06stores\"\xfe\x01\n" +
+	"\vPriceChange\x12\x19\n" +
+	"\bevent_id\x18\x01 \x01(\tR\aeventId\x12\x1d\n" +
+	"\n" +
+	"product_id\x18\x02 \x01(\x05R\tproductId\x12\x14\n" +
+	"\x05store\x18\x03 \x01(\tR\x05store\x12*\n" +
+	"\x0eprevious_price\x18\x04 \x01(\x01H\x00R\rpreviousPrice\x88\x01\x01\x12\x19\n" +
+	"\x05price\x18\x05 \x01(\x01H\x01R\x05price\x88\x01\x01\x12;\n" +
+	"\voccurred_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"occurredAtB\x11\n" +
+	"\x0f_previous_priceB\b\n" +
+	"\x06_price2\xf9\x05\n" +
+	"\x0eProductService\x12Y\n" +
+	"\fListProducts\x12#.crproductos.v1.ListProductsRequest\x1a$.crproductos.v1.ListProductsResponse\x12H\n" +
+	"\n" +
+	"GetProduct\x12!.crproductos.v1.GetProductRequest\x1a\x17.crproductos.v1.Product\x12Z\n" +
+	"\x13GetProductByBarcode\x12*.crproductos.v1.GetProductByBarcodeRequest\x1a\x17.crproductos.v1.Product\x12N\n" +
+	"\rCreateProduct\x12$.crproductos.v1.CreateProductRequest\x1a\x17.crproductos.v1.Product\x12N\n" +
+	"\rUpdateProduct\x12$.crproductos.v1.UpdateProductRequest\x1a\x17.crproductos.v1.Product\x12L\n" +
+	"\fPatchProduct\x12#.crproductos.v1.PatchProductRequest\x1a\x17.crproductos.v1.Product\x12H\n" +
+	"\n" +
+	"PatchStore\x12!.crproductos.v1.PatchStoreRequest\x1a\x17.crproductos.v1.Product\x12\\\n" +
+	"\rDeleteProduct\x12$.crproductos.v1.DeleteProductRequest\x1a%.crproductos.v1.DeleteProductResponse\x12P\n" +
+	"\vWatchPrices\x12\".crproductos.v1.WatchPricesRequest\x1a\x1b.crproductos.v1.PriceChange0\x01B\x19Z\x17crproductos/api/grpc/pbb\x06proto3"
+
+var (
+	file_products_proto_rawDescOnce sync.Once
+	file_products_proto_rawDescData []byte
+)
+
+func file_products_proto_rawDescGZIP() []byte {
+	file_products_proto_rawDescOnce.Do(func() {
+		file_products_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_products_proto_rawDesc), len(file_products_proto_rawDesc)))
+	})
+	return file_products_proto_rawDescData
+}
+
+var file_products_proto_msgTypes = make([]protoimpl.MessageInfo, 17)
+var file_products_proto_goTypes = []any{
+	(*Category)(nil),                   // 0: crproductos.v1.Category
+	(*Product)(nil),                    // 1: crproductos.v1.Product
+	(*ListProductsRequest)(nil),        // 2: crproductos.v1.ListProductsRequest
+	(*ListProductsResponse)(nil),       // 3: crproductos.v1.ListProductsResponse
+	(*GetProductRequest)(nil),          // 4: crproductos.v1.GetProductRequest
+	(*GetProductByBarcodeRequest)(nil), // 5: crproductos.v1.GetProductByBarcodeRequest
+	(*CreateProductRequest)(nil),       // 6: crproductos.v1.CreateProductRequest
+	(*UpdateProductRequest)(nil),       // 7: crproductos.v1.UpdateProductRequest
+	(*PatchProductRequest)(nil),        // 8: crproductos.v1.PatchProductRequest
+	(*PatchStoreRequest)(nil),          // 9: crproductos.v1.PatchStoreRequest
+	(*DeleteProductRequest)(nil),       // 10: crproductos.v1.DeleteProductRequest
+	(*DeleteProductResponse)(nil),      // 11: crproductos.v1.DeleteProductResponse
+	(*WatchPricesRequest)(nil),         // 12: crproductos.v1.WatchPricesRequest
+	(*PriceChange)(nil),                // 13: crproductos.v1.PriceChange
+	nil,                                // 14: crproductos.v1.Product.StoresEntry
+	nil,                                // 15: crproductos.v1.Product.CurrenciesEntry
+	nil,                                // 16: crproductos.v1.PatchStoreRequest.PricesEntry
+	(*timestamppb.Timestamp)(nil),      // 17: google.protobuf.Timestamp
+}
+var file_products_proto_depIdxs = []int32{
+	14, // 0: crproductos.v1.Product.stores:type_name -> crproductos.v1.Product.StoresEntry
+	15, // 1: crproductos.v1.Product.currencies:type_name -> crproductos.v1.Product.CurrenciesEntry
+	0,  // 2: crproductos.v1.Product.categories:type_name -> crproductos.v1.Category
+	1,  // 3: crproductos.v1.ListProductsResponse.products:type_name -> crproductos.v1.Product
+	1,  // 4: crproductos.v1.CreateProductRequest.product:type_name -> crproductos.v1.Product
+	1,  // 5: crproductos.v1.UpdateProductRequest.product:type_name -> crproductos.v1.Product
+	1,  // 6: crproductos.v1.PatchProductRequest.product:type_name -> crproductos.v1.Product
+	16, // 7: crproductos.v1.PatchStoreRequest.prices:type_name -> crproductos.v1.PatchStoreRequest.PricesEntry
+	17, // 8: crproductos.v1.PriceChange.occurred_at:type_name -> google.protobuf.Timestamp
+	2,  // 9: crproductos.v1.ProductService.ListProducts:input_type -> crproductos.v1.ListProductsRequest
+	4,  // 10: crproductos.v1.ProductService.GetProduct:input_type -> crproductos.v1.GetProductRequest
+	5,  // 11: crproductos.v1.ProductService.GetProductByBarcode:input_type -> crproductos.v1.GetProductByBarcodeRequest
+	6,  // 12: crproductos.v1.ProductService.CreateProduct:input_type -> crproductos.v1.CreateProductRequest
+	7,  // 13: crproductos.v1.ProductService.UpdateProduct:input_type -> crproductos.v1.UpdateProductRequest
+	8,  // 14: crproductos.v1.ProductService.PatchProduct:input_type -> crproductos.v1.PatchProductRequest
+	9,  // 15: crproductos.v1.ProductService.PatchStore:input_type -> crproductos.v1.PatchStoreRequest
+	10, // 16: crproductos.v1.ProductService.DeleteProduct:input_type -> crproductos.v1.DeleteProductRequest
+	12, // 17: crproductos.v1.ProductService.WatchPrices:input_type -> crproductos.v1.WatchPricesRequest
+	3,  // 18: crproductos.v1.ProductService.ListProducts:output_type -> crproductos.v1.ListProductsResponse
+	1,  // 19: crproductos.v1.ProductService.GetProduct:output_type -> crproductos.v1.Product
+	1,  // 20: crproductos.v1.ProductService.GetProductByBarcode:output_type -> crproductos.v1.Product
+	1,  // 21: crproductos.v1.ProductService.CreateProduct:output_type -> crproductos.v1.Product
+	1,  // 22: crproductos.v1.ProductService.UpdateProduct:output_type -> crproductos.v1.Product
+	1,  // 23: crproductos.v1.ProductService.PatchProduct:output_type -> crproductos.v1.Product
+	1,  // 24: crproductos.v1.ProductService.PatchStore:output_type -> crproductos.v1.Product
+	11, // 25: crproductos.v1.ProductService.DeleteProduct:output_type -> crproductos.v1.DeleteProductResponse
+	13, // 26: crproductos.v1.ProductService.WatchPrices:output_type -> crproductos.v1.PriceChange
+	18, // [18:27] is the sub-list for method output_type
+	9,  // [9:18] is the sub-list for method input_type
+	9,  // [9:9] is the sub-list for extension type_name
+	9,  // [9:9] is the sub-list for extension extendee
+	0,  // [0:9] is the sub-list for field type_name
+}
+
+func init() { file_products_proto_init() }
+func file_products_proto_init() {
+	if File_products_proto != nil {
+		return
+	}
+	file_products_proto_msgTypes[0].OneofWrappers = []any{}
+	file_products_proto_msgTypes[1].OneofWrappers = []any{}
+	file_products_proto_msgTypes[2].OneofWrappers = []any{}
+	file_products_proto_msgTypes[13].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_products_proto_rawDesc), len(file_products_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   17,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_products_proto_goTypes,
+		DependencyIndexes: file_products_proto_depIdxs,
+		MessageInfos:      file_products_proto_msgTypes,
+	}.Build()
+	File_products_proto = out.File
+	file_products_proto_goTypes = nil
+	file_products_proto_depIdxs = nil
+}
@@ -0,0 +1,246 @@
+// Package grpc serves the ProductService of products.proto on top of the
+// same service layer as the HTTP API
+package grpc
+
+import (
+	"context"
+	"crproductos/api/grpc/pb"
+	"crproductos/internal/events"
+	"crproductos/internal/models"
+	"crproductos/internal/service"
+	"encoding/json"
+	"log/slog"
+	"strconv"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type Server struct {
+	pb.UnimplementedProductServiceServer
+	products service.ProductService
+	broker   *events.Broker
+}
+
+// NewServer serves products, WatchPrices streams the price.changed events
+// published to broker
+func NewServer(products service.ProductService, broker *events.Broker) *Server {
+	return &Server{products: products, broker: broker}
+}
+
+func toProto(product models.ProductResponse) *pb.Product {
+	message := &pb.Product{
+		Id:       int32(product.Id),
+		Name:     product.Name,
+		Quantity: product.Quantity,
+		Unit:     product.Unit,
+		Barcodes: product.Barcodes,
+		Tags:     product.Tags,
+	}
+	if product.BrandId != nil {
+		message.BrandId = ptr(int32(*product.BrandId))
+	}
+	if product.ParentId != nil {
+		message.ParentId = ptr(int32(*product.ParentId))
+	}
+	if product.Stores != nil {
+		message.Stores = *product.Stores
+	}
+	if product.Currencies != nil {
+		message.Currencies = *product.Currencies
+	}
+	for _, category := range product.Categories {
+		c := &pb.Category{Id: int32(category.Id), Name: category.Name}
+		if category.ParentId != nil {
+			c.ParentId = ptr(int32(*category.ParentId))
+		}
+		message.Categories = append(message.Categories, c)
+	}
+	return message
+}
+
+// fromProto converts the writable fields, empty maps are left nil so
+// PatchProduct keeps the stored ones
+func fromProto(message *pb.Product) models.ProductResponse {
+	product := models.ProductResponse{
+		Name:     message.Name,
+		Quantity: message.Quantity,
+		Unit:     message.Unit,
+		Barcodes: message.Barcodes,
+	}
+	if message.BrandId != nil {
+		product.BrandId = ptr(int(*message.BrandId))
+	}
+	if message.ParentId != nil {
+		product.ParentId = ptr(int(*message.ParentId))
+	}
+	if len(message.Stores) > 0 {
+		stores := models.Stores(message.Stores)
+		product.Stores = &stores
+	}
+	if len(message.Currencies) > 0 {
+		currencies := models.StoreCurrencies(message.Currencies)
+		product.Currencies = &currencies
+	}
+	return product
+}
+
+func ptr[T any](value T) *T {
+	return &value
+}
+
+func idOf(id int32) string {
+	return strconv.Itoa(int(id))
+}
+
+func (s *Server) ListProducts(ctx context.Context, req *pb.ListProductsRequest) (*pb.ListProductsResponse, error) {
+	filter := models.ProductFilter{MaxPrice: req.MaxPrice}
+	for _, id := range req.Ids {
+		filter.Ids = append(filter.Ids, int(id))
+	}
+	for target, value := range map[**int]*int32{&filter.CategoryId: req.CategoryId, &filter.BrandId: req.BrandId, &filter.ParentId: req.ParentId} {
+		if value != nil {
+			*target = ptr(int(*value))
+		}
+	}
+	for _, tag := range req.Tags {
+		name, err := models.NormalizeTag(tag)
+		if err != nil {
+			return nil, statusOf(err)
+		}
+		filter.Tags = append(filter.Tags, name)
+	}
+	products, err := s.products.GetAllProducts(ctx, filter)
+	if err != nil {
+		return nil, statusOf(err)
+	}
+	response := &pb.ListProductsResponse{}
+	for _, product := range products {
+		response.Products = append(response.Products, toProto(product))
+	}
+	return response, nil
+}
+
+func (s *Server) GetProduct(ctx context.Context, req *pb.GetProductRequest) (*pb.Product, error) {
+	product, err := s.products.GetProductById(ctx, idOf(req.Id))
+	if err != nil {
+		return nil, statusOf(err)
+	}
+	return toProto(product.ToJSON()), nil
+}
+
+func (s *Server) GetProductByBarcode(ctx context.Context, req *pb.GetProductByBarcodeRequest) (*pb.Product, error) {
+	product, err := s.products.GetProductByBarcode(ctx, req.Barcode)
+	if err != nil {
+		return nil, statusOf(err)
+	}
+	return toProto(product.ToJSON()), nil
+}
+
+func (s *Server) CreateProduct(ctx context.Context, req *pb.CreateProductRequest) (*pb.Product, error) {
+	if err := require(ctx, models.RoleAdmin); err != nil {
+		return nil, err
+	}
+	if req.Product == nil {
+		return nil, errMissingProduct
+	}
+	product, err := s.products.CreateProduct(ctx, fromProto(req.Product))
+	if err != nil {
+		return nil, statusOf(err)
+	}
+	return toProto(product), nil
+}
+
+func (s *Server) UpdateProduct(ctx context.Context, req *pb.UpdateProductRequest) (*pb.Product, error) {
+	if err := require(ctx, models.RoleAdmin); err != nil {
+		return nil, err
+	}
+	if req.Product == nil {
+		return nil, errMissingProduct
+	}
+	product, err := s.products.UpdateProduct(ctx, idOf(req.Id), fromProto(req.Product))
+	if err != nil {
+		return nil, statusOf(err)
+	}
+	return toProto(product), nil
+}
+
+func (s *Server) PatchProduct(ctx context.Context, req *pb.PatchProductRequest) (*pb.Product, error) {
+	if err := require(ctx, models.RoleAdmin); err != nil {
+		return nil, err
+	}
+	if req.Product == nil {
+		return nil, errMissingProduct
+	}
+	product, err := s.products.PatchProduct(ctx, idOf(req.Id), fromProto(req.Product))
+	if err != nil {
+		return nil, statusOf(err)
+	}
+	return toProto(product.ToJSON()), nil
+}
+
+func (s *Server) PatchStore(ctx context.Context, req *pb.PatchStoreRequest) (*pb.Product, error) {
+	if err := require(ctx, models.RolePriceCollector); err != nil {
+		return nil, err
+	}
+	jsonStore, err := json.Marshal(models.Stores(req.Prices))
+	if err != nil {
+		return nil, statusOf(err)
+	}
+	product, err := s.products.PatchStore(ctx, idOf(req.Id), jsonStore)
+	if err != nil {
+		return nil, statusOf(err)
+	}
+	return toProto(product.ToJSON()), nil
+}
+
+func (s *Server) DeleteProduct(ctx context.Context, req *pb.DeleteProductRequest) (*pb.DeleteProductResponse, error) {
+	if err := require(ctx, models.RoleAdmin); err != nil {
+		return nil, err
+	}
+	if err := s.products.DeleteProduct(ctx, idOf(req.Id)); err != nil {
+		return nil, statusOf(err)
+	}
+	return &pb.DeleteProductResponse{}, nil
+}
+
+// WatchPrices: Sends every price.changed event of the requested products and
+// stores until the client goes away. A client too slow to keep up misses
+// events rather than holding up the others
+func (s *Server) WatchPrices(req *pb.WatchPricesRequest, stream pb.ProductService_WatchPricesServer) error {
+	products := map[int]bool{}
+	for _, id := range req.ProductIds {
+		products[int(id)] = true
+	}
+	stores := map[string]bool{}
+	for _, store := range req.Stores {
+		stores[store] = true
+	}
+	_, live, cancel := s.broker.Subscribe(events.Filter{}, "")
+	defer cancel()
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event := <-live:
+			if event.Type != events.PriceChanged || (len(products) > 0 && !products[event.ProductId]) || (len(stores) > 0 && !stores[event.Store]) {
+				continue
+			}
+			var change events.PriceChange
+			if err := json.Unmarshal(event.Data, &change); err != nil {
+				slog.ErrorContext(stream.Context(), "failed to decode price change", "event_id", event.Id, "err", err)
+				continue
+			}
+			err := stream.Send(&pb.PriceChange{
+				EventId:       event.Id,
+				ProductId:     int32(event.ProductId),
+				Store:         change.Store,
+				PreviousPrice: change.PreviousPrice,
+				Price:         change.Price,
+				OccurredAt:    timestamppb.New(event.OccurredAt),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
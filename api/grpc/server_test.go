@@ -0,0 +1,164 @@
+package grpc
+
+import (
+	"context"
+	"crproductos/api/grpc/pb"
+	"crproductos/internal/auth"
+	"crproductos/internal/events"
+	"crproductos/internal/models"
+	"database/sql"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// fakeProducts serves a single product and records what it was sent
+type fakeProducts struct {
+	created models.ProductResponse
+}
+
+func (f *fakeProducts) GetAllProducts(ctx context.Context, filter models.ProductFilter) ([]models.ProductResponse, error) {
+	return nil, nil
+}
+
+func (f *fakeProducts) ScanProducts(ctx context.Context, filter models.ProductFilter, fn func(models.ProductResponse) error) error {
+	products, err := f.GetAllProducts(ctx, filter)
+	if err != nil {
+		return err
+	}
+	for _, product := range products {
+		if err := fn(product); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fakeProducts) GetProductById(ctx context.Context, id string) (models.Product, error) {
+	if id != "1" {
+		return models.Product{}, sql.ErrNoRows
+	}
+	return models.Product{Id: 1, Name: sql.NullString{String: "Leche entera", Valid: true}, Stores: &models.Stores{"walmart": 990}}, nil
+}
+
+func (f *fakeProducts) GetProductByBarcode(ctx context.Context, barcode string) (models.Product, error) {
+	return models.Product{}, sql.ErrNoRows
+}
+
+func (f *fakeProducts) CreateProduct(ctx context.Context, product models.ProductResponse) (models.ProductResponse, error) {
+	f.created = product
+	product.Id = 2
+	return product, nil
+}
+
+func (f *fakeProducts) DeleteProduct(ctx context.Context, id string) error {
+	return nil
+}
+
+func (f *fakeProducts) UpdateProduct(ctx context.Context, id string, product models.ProductResponse) (models.ProductResponse, error) {
+	return product, nil
+}
+
+func (f *fakeProducts) PatchProduct(ctx context.Context, id string, product models.ProductResponse) (models.Product, error) {
+	return models.Product{}, nil
+}
+
+func (f *fakeProducts) PatchStore(ctx context.Context, id string, jsonStore []byte) (models.Product, error) {
+	return models.Product{}, nil
+}
+
+func (f *fakeProducts) CompareProducts(ctx context.Context, filter models.ProductFilter) ([]models.VariantComparison, error) {
+	return nil, nil
+}
+
+func newClient(t *testing.T, products *fakeProducts, broker *events.Broker) pb.ProductServiceClient {
+	t.Helper()
+	authenticators := []auth.Authenticator{auth.StaticKey("secret", models.RoleAdmin)}
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(grpc.UnaryInterceptor(UnaryAuthenticator(authenticators...)), grpc.StreamInterceptor(StreamAuthenticator(authenticators...)))
+	pb.RegisterProductServiceServer(server, NewServer(products, broker))
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewProductServiceClient(conn)
+}
+
+func TestProductCalls(t *testing.T) {
+	products := &fakeProducts{}
+	client := newClient(t, products, events.NewBroker(10))
+	ctx := context.Background()
+
+	product, err := client.GetProduct(ctx, &pb.GetProductRequest{Id: 1})
+	if err != nil || product.GetName() != "Leche entera" || product.Stores["walmart"] != 990 {
+		t.Errorf("unexpected GetProduct %v %v", product, err)
+	}
+	if _, err := client.GetProduct(ctx, &pb.GetProductRequest{Id: 3}); status.Code(err) != codes.NotFound {
+		t.Errorf("got %v, want NotFound", err)
+	}
+
+	name := "Arroz"
+	create := &pb.CreateProductRequest{Product: &pb.Product{Name: &name, Stores: map[string]float64{"pali": 1200}}}
+	if _, err := client.CreateProduct(ctx, create); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("got %v, want Unauthenticated", err)
+	}
+	wrongKey := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer wrong")
+	if _, err := client.CreateProduct(wrongKey, create); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("got %v, want Unauthenticated", err)
+	}
+	admin := metadata.AppendToOutgoingContext(ctx, "x-api-key", "secret")
+	created, err := client.CreateProduct(admin, create)
+	if err != nil || created.Id != 2 || *products.created.Name != "Arroz" || (*products.created.Stores)["pali"] != 1200 {
+		t.Errorf("unexpected CreateProduct %v %v", created, err)
+	}
+}
+
+func TestWatchPrices(t *testing.T) {
+	broker := events.NewBroker(10)
+	client := newClient(t, &fakeProducts{}, broker)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.WatchPrices(ctx, &pb.WatchPricesRequest{ProductIds: []int32{1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	price := 980.0
+	other, _ := events.New(events.PriceChanged, 2, "walmart", events.PriceChange{Store: "walmart", Price: &price})
+	change, _ := events.New(events.PriceChanged, 1, "walmart", events.PriceChange{Store: "walmart", Price: &price})
+	// the subscription is made once the call reaches the server, keep
+	// publishing until the stream picks it up
+	go func() {
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				broker.Publish(other)
+				broker.Publish(change)
+			}
+		}
+	}()
+	received, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if received.EventId != change.Id || received.ProductId != 1 || received.Store != "walmart" || received.GetPrice() != 980 || received.PreviousPrice != nil {
+		t.Errorf("unexpected price change %v", received)
+	}
+}
@@ -0,0 +1,54 @@
+package http
+
+import (
+	"crproductos/internal/models"
+	"crproductos/internal/service"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+)
+
+type APIKeyHandler struct {
+	service service.APIKeyService
+}
+
+func NewAPIKeyHandler(svc service.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{service: svc}
+}
+
+func (h *APIKeyHandler) GetAllKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.service.GetAllKeys()
+	if err != nil {
+		http.Error(w, "Failed getting all api keys", http.StatusInternalServerError)
+		return
+	}
+	render.JSON(w, r, keys)
+}
+
+// IssueKey creates a key, the response carries the plain key only this once
+func (h *APIKeyHandler) IssueKey(w http.ResponseWriter, r *http.Request) {
+	var key models.APIKey
+	if err := json.NewDecoder(r.Body).Decode(&key); err != nil {
+		slog.WarnContext(r.Context(), "invalid request body", "err", err)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	key, err := h.service.IssueKey(key)
+	if err != nil {
+		http.Error(w, "Failed issuing api key", errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, key)
+}
+func (h *APIKeyHandler) RevokeKey(w http.ResponseWriter, r *http.Request) {
+	var id = chi.URLParam(r, "id")
+	key, err := h.service.RevokeKey(id)
+	if err != nil {
+		http.Error(w, "Failed revoking api key", errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	render.JSON(w, r, key)
+}
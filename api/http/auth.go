@@ -0,0 +1,56 @@
+package http
+
+import (
+	"crproductos/internal/auth"
+	"crproductos/internal/models"
+	"errors"
+	"log/slog"
+	"net/http"
+)
+
+// authenticate runs the authenticators in order and stores the first
+// principal found in the request context. Requests without credentials go
+// through anonymously, rejected credentials are answered with 401
+func authenticate(authenticators []auth.Authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, authenticator := range authenticators {
+				principal, err := authenticator.Authenticate(r)
+				if errors.Is(err, auth.ErrNoCredentials) {
+					continue
+				}
+				if err != nil {
+					slog.Error("rejected credentials", "err", err)
+					unauthorized(w)
+					return
+				}
+				r = r.WithContext(auth.NewContext(r.Context(), principal))
+				break
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// require only lets requests through whose principal holds role
+func require(role models.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := auth.FromContext(r.Context())
+			if !ok {
+				unauthorized(w)
+				return
+			}
+			if !principal.Role.Allows(role) {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func unauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="crproductos"`)
+	http.Error(w, "Unauthorized", http.StatusUnauthorized)
+}
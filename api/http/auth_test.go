@@ -0,0 +1,71 @@
+package http
+
+import (
+	"crproductos/internal/auth"
+	"crproductos/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type fakeKeyStore map[string]models.Role
+
+func (f fakeKeyStore) Authenticate(key string) (models.APIKey, error) {
+	role, ok := f[key]
+	if !ok {
+		return models.APIKey{}, models.ErrInvalidAPIKey
+	}
+	return models.APIKey{Prefix: key[:8], Role: role}, nil
+}
+
+func TestRoutesRequireRoles(t *testing.T) {
+	keys := fakeKeyStore{
+		"crp_reader_key":    models.RoleReader,
+		"crp_collector_key": models.RolePriceCollector,
+		"crp_admin_key":     models.RoleAdmin,
+	}
+	s := NewServer(WithAuthenticators(auth.APIKeys(keys)))
+	s.MountHandlers(NewProductHandler(&mockProductService{}))
+
+	cases := []struct {
+		method string
+		path   string
+		key    string
+		want   int
+	}{
+		{"GET", "/products/", "", http.StatusOK},
+		{"DELETE", "/products/1", "", http.StatusUnauthorized},
+		{"DELETE", "/products/1", "crp_unknown", http.StatusUnauthorized},
+		{"GET", "/products/", "crp_unknown", http.StatusUnauthorized},
+		{"DELETE", "/products/1", "crp_reader_key", http.StatusForbidden},
+		{"DELETE", "/products/1", "crp_collector_key", http.StatusForbidden},
+		{"PATCH", "/products/1/store", "crp_reader_key", http.StatusForbidden},
+		{"PATCH", "/products/1/store", "crp_collector_key", http.StatusOK},
+		{"PATCH", "/products/1/store", "crp_admin_key", http.StatusOK},
+		{"DELETE", "/products/1", "crp_admin_key", http.StatusOK},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, c.path, strings.NewReader(`{"walmart": 1000}`))
+		if c.key != "" {
+			req.Header.Set("Authorization", "Bearer "+c.key)
+		}
+		response := executeRequest(req, s)
+		if response.Code != c.want {
+			t.Errorf("%s %s with %q: got %d, want %d", c.method, c.path, c.key, response.Code, c.want)
+		}
+	}
+}
+
+func TestStaticKey(t *testing.T) {
+	s := NewServer(WithAuthenticators(auth.StaticKey("bootstrap", models.RoleAdmin)))
+	s.MountHandlers(NewProductHandler(&mockProductService{}))
+
+	req := httptest.NewRequest("DELETE", "/products/1", nil)
+	req.Header.Set("X-API-Key", "bootstrap")
+	checkResponseCode(t, http.StatusOK, executeRequest(req, s).Code)
+
+	req = httptest.NewRequest("DELETE", "/products/1", nil)
+	req.Header.Set("X-API-Key", "other")
+	checkResponseCode(t, http.StatusUnauthorized, executeRequest(req, s).Code)
+}
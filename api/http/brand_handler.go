@@ -0,0 +1,73 @@
+package http
+
+import (
+	"crproductos/internal/models"
+	"crproductos/internal/service"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+)
+
+type BrandHandler struct {
+	service service.BrandService
+}
+
+func NewBrandHandler(svc service.BrandService) *BrandHandler {
+	return &BrandHandler{service: svc}
+}
+func (h *BrandHandler) GetAllBrands(w http.ResponseWriter, r *http.Request) {
+	brands, err := h.service.GetAllBrands()
+	if err != nil {
+		http.Error(w, "Failed getting all brands", http.StatusInternalServerError)
+		return
+	}
+	render.JSON(w, r, brands)
+}
+func (h *BrandHandler) GetBrandById(w http.ResponseWriter, r *http.Request) {
+	var id = chi.URLParam(r, "id")
+	brand, err := h.service.GetBrandById(id)
+	if err != nil {
+		http.Error(w, "Failed getting brand", errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	render.JSON(w, r, brand)
+}
+func (h *BrandHandler) CreateBrand(w http.ResponseWriter, r *http.Request) {
+	var brand models.Brand
+	if err := json.NewDecoder(r.Body).Decode(&brand); err != nil {
+		slog.WarnContext(r.Context(), "invalid request body", "err", err)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	brand, err := h.service.CreateBrand(brand)
+	if err != nil {
+		http.Error(w, "Failed creating brand", errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	render.JSON(w, r, brand)
+}
+func (h *BrandHandler) UpdateBrand(w http.ResponseWriter, r *http.Request) {
+	var id = chi.URLParam(r, "id")
+	var brand models.Brand
+	if err := json.NewDecoder(r.Body).Decode(&brand); err != nil {
+		slog.WarnContext(r.Context(), "invalid request body", "err", err)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	brand, err := h.service.UpdateBrand(id, brand)
+	if err != nil {
+		http.Error(w, "Failed updating brand", errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	render.JSON(w, r, brand)
+}
+func (h *BrandHandler) DeleteBrand(w http.ResponseWriter, r *http.Request) {
+	var id = chi.URLParam(r, "id")
+	if err := h.service.DeleteBrand(id); err != nil {
+		http.Error(w, "Failed deleting brand", errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	w.Write([]byte("Delete successful"))
+}
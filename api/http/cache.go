@@ -0,0 +1,52 @@
+package http
+
+import (
+	"crproductos/internal/cache"
+	"net/http"
+)
+
+// cacheStatus sets X-Cache to HIT or MISS on the responses served with
+// cached reads, HIT only when every read was
+func cacheStatus(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, status := cache.WithStatus(r.Context())
+		next.ServeHTTP(&cacheStatusWriter{ResponseWriter: w, status: status}, r.WithContext(ctx))
+	})
+}
+
+// cacheStatusWriter adds the header right before the response is written
+type cacheStatusWriter struct {
+	http.ResponseWriter
+	status      *cache.Status
+	wroteHeader bool
+}
+
+func (w *cacheStatusWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if status := w.status.String(); status != "" {
+			w.Header().Set("X-Cache", status)
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *cacheStatusWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *cacheStatusWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *cacheStatusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,90 @@
+package http
+
+import (
+	"crproductos/internal/models"
+	"crproductos/internal/service"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+)
+
+type CategoryHandler struct {
+	service service.CategoryService
+}
+
+func NewCategoryHandler(svc service.CategoryService) *CategoryHandler {
+	return &CategoryHandler{service: svc}
+}
+func (h *CategoryHandler) GetAllCategories(w http.ResponseWriter, r *http.Request) {
+	categories, err := h.service.GetAllCategories()
+	if err != nil {
+		http.Error(w, "Failed getting all categories", http.StatusInternalServerError)
+		return
+	}
+	render.JSON(w, r, categories)
+}
+func (h *CategoryHandler) GetCategoryById(w http.ResponseWriter, r *http.Request) {
+	var id = chi.URLParam(r, "id")
+	category, err := h.service.GetCategoryById(id)
+	if err != nil {
+		http.Error(w, "Failed getting category", errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	render.JSON(w, r, category)
+}
+func (h *CategoryHandler) CreateCategory(w http.ResponseWriter, r *http.Request) {
+	var category models.Category
+	if err := json.NewDecoder(r.Body).Decode(&category); err != nil {
+		slog.WarnContext(r.Context(), "invalid request body", "err", err)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	category, err := h.service.CreateCategory(category)
+	if err != nil {
+		http.Error(w, "Failed creating category", errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	render.JSON(w, r, category)
+}
+func (h *CategoryHandler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
+	var id = chi.URLParam(r, "id")
+	var category models.Category
+	if err := json.NewDecoder(r.Body).Decode(&category); err != nil {
+		slog.WarnContext(r.Context(), "invalid request body", "err", err)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	category, err := h.service.UpdateCategory(id, category)
+	if err != nil {
+		http.Error(w, "Failed updating category", errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	render.JSON(w, r, category)
+}
+func (h *CategoryHandler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	var id = chi.URLParam(r, "id")
+	if err := h.service.DeleteCategory(id); err != nil {
+		http.Error(w, "Failed deleting category", errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	w.Write([]byte("Delete successful"))
+}
+
+// SetProductCategories replaces the categories of a product with the list of
+// category ids sent in the body, e.g. [1, 4]
+func (h *CategoryHandler) SetProductCategories(w http.ResponseWriter, r *http.Request) {
+	var id = chi.URLParam(r, "id")
+	var categoryIds []int
+	if err := json.NewDecoder(r.Body).Decode(&categoryIds); err != nil {
+		slog.WarnContext(r.Context(), "invalid request body", "err", err)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	if err := h.service.SetProductCategories(id, categoryIds); err != nil {
+		http.Error(w, "Failed assigning categories", errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	render.JSON(w, r, categoryIds)
+}
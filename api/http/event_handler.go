@@ -0,0 +1,86 @@
+package http
+
+import (
+	"crproductos/internal/events"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// heartbeatInterval keeps idle event streams from being closed by proxies
+const heartbeatInterval = 15 * time.Second
+
+type EventHandler struct {
+	broker *events.Broker
+}
+
+func NewEventHandler(broker *events.Broker) *EventHandler {
+	return &EventHandler{broker: broker}
+}
+
+// StreamEvents: Streams the product and price events as server-sent events,
+// narrowed by ?product= and ?store=. Clients reconnecting with Last-Event-ID
+// first receive the buffered events they missed
+func (h *EventHandler) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	var filter events.Filter
+	if product := r.URL.Query().Get("product"); product != "" {
+		id, err := strconv.Atoi(product)
+		if err != nil {
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+		filter.ProductId = id
+	}
+	filter.Store = r.URL.Query().Get("store")
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	missed, live, cancel := h.broker.Subscribe(filter, r.Header.Get("Last-Event-ID"))
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	for _, event := range missed {
+		if err := writeEvent(w, event); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event := <-live:
+			if err := writeEvent(w, event); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// writeEvent writes a single SSE message, the event id doubles as the SSE id
+func writeEvent(w http.ResponseWriter, event events.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		slog.Error("failed to marshal event", "err", err)
+		return nil
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.Id, event.Type, data)
+	return err
+}
@@ -0,0 +1,55 @@
+package http
+
+import (
+	"bufio"
+	"crproductos/internal/events"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStreamEventsResumesAndFilters(t *testing.T) {
+	broker := events.NewBroker(10)
+	first, _ := events.New(events.PriceChanged, 1, "walmart", nil)
+	other, _ := events.New(events.PriceChanged, 2, "walmart", nil)
+	second, _ := events.New(events.PriceChanged, 1, "automercado", nil)
+	for _, event := range []events.Event{first, other, second} {
+		broker.Publish(event)
+	}
+	s := NewServer()
+	s.MountEventHandlers(NewEventHandler(broker))
+	server := httptest.NewServer(s.Router)
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/events?product=1", nil)
+	req.Header.Set("Last-Event-ID", first.Id)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+
+	lines := bufio.NewScanner(resp.Body)
+	readId := func() string {
+		for lines.Scan() {
+			if id, ok := strings.CutPrefix(lines.Text(), "id: "); ok {
+				return id
+			}
+		}
+		t.Fatal("stream ended early")
+		return ""
+	}
+	if id := readId(); id != second.Id {
+		t.Errorf("resumed with %s, want %s", id, second.Id)
+	}
+	live, _ := events.New(events.ProductUpdated, 1, "", nil)
+	broker.Publish(other)
+	broker.Publish(live)
+	if id := readId(); id != live.Id {
+		t.Errorf("live event %s, want %s", id, live.Id)
+	}
+}
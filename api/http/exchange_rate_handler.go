@@ -0,0 +1,49 @@
+package http
+
+import (
+	"crproductos/internal/models"
+	"crproductos/internal/service"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+)
+
+type ExchangeRateHandler struct {
+	service service.ExchangeRateService
+}
+
+func NewExchangeRateHandler(svc service.ExchangeRateService) *ExchangeRateHandler {
+	return &ExchangeRateHandler{service: svc}
+}
+func (h *ExchangeRateHandler) GetAllExchangeRates(w http.ResponseWriter, r *http.Request) {
+	rates, err := h.service.GetAllExchangeRates()
+	if err != nil {
+		http.Error(w, "Failed getting all exchange rates", http.StatusInternalServerError)
+		return
+	}
+	render.JSON(w, r, rates)
+}
+func (h *ExchangeRateHandler) CreateExchangeRate(w http.ResponseWriter, r *http.Request) {
+	var rate models.ExchangeRate
+	if err := json.NewDecoder(r.Body).Decode(&rate); err != nil {
+		slog.WarnContext(r.Context(), "invalid request body", "err", err)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	rate, err := h.service.CreateExchangeRate(rate)
+	if err != nil {
+		http.Error(w, "Failed creating exchange rate", errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	render.JSON(w, r, rate)
+}
+func (h *ExchangeRateHandler) DeleteExchangeRate(w http.ResponseWriter, r *http.Request) {
+	var id = chi.URLParam(r, "id")
+	if err := h.service.DeleteExchangeRate(id); err != nil {
+		http.Error(w, "Failed deleting exchange rate", errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	w.Write([]byte("Delete successful"))
+}
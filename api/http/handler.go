@@ -3,53 +3,295 @@ package http
 import (
 	"crproductos/internal/models"
 	"crproductos/internal/service"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/render"
-	"log"
+	"log/slog"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 type ProductHandler struct {
-	service service.ProductService
+	service    service.ProductService
+	rates      service.ExchangeRateService
+	promotions service.PromotionService
+	pricing    service.PricingService
 }
 
-func NewProductHandler(svc service.ProductService) *ProductHandler {
-	return &ProductHandler{service: svc}
+// ProductHandlerOption plugs optional services into the ProductHandler
+type ProductHandlerOption func(*ProductHandler)
+
+// WithExchangeRates enables the ?currency= parameter on product reads
+func WithExchangeRates(rates service.ExchangeRateService) ProductHandlerOption {
+	return func(h *ProductHandler) {
+		h.rates = rates
+	}
+}
+
+// WithPromotions adds the effective price of every store to product reads
+func WithPromotions(promotions service.PromotionService) ProductHandlerOption {
+	return func(h *ProductHandler) {
+		h.promotions = promotions
+	}
+}
+
+func NewProductHandler(svc service.ProductService, opts ...ProductHandlerOption) *ProductHandler {
+	h := &ProductHandler{service: svc}
+	for _, opt := range opts {
+		opt(h)
+	}
+	h.pricing = service.NewPricingService(h.promotions, h.rates)
+	return h
+}
+
+// errInvalidQuery flags malformed query parameters
+var errInvalidQuery = errors.New("invalid query parameter")
+
+// errorStatus maps the domain errors returned by the service to an HTTP status,
+// anything unknown is reported with the given fallback status
+func errorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return http.StatusNotFound
+	case errors.Is(err, errInvalidQuery), errors.Is(err, models.ErrInvalidBarcode):
+		return http.StatusBadRequest
+	case errors.Is(err, models.ErrInvalidCategory), errors.Is(err, models.ErrCategoryCycle),
+		errors.Is(err, models.ErrCategoryNotFound), errors.Is(err, models.ErrInvalidTag),
+		errors.Is(err, models.ErrInvalidBrand), errors.Is(err, models.ErrBrandNotFound),
+		errors.Is(err, models.ErrInvalidVariant), errors.Is(err, models.ErrInvalidCurrency),
+		errors.Is(err, models.ErrInvalidExchangeRate), errors.Is(err, models.ErrInvalidPromotion),
+		errors.Is(err, models.ErrInvalidWatch), errors.Is(err, models.ErrInvalidWebhook),
+		errors.Is(err, models.ErrInvalidAPIKey):
+		return http.StatusBadRequest
+	case errors.Is(err, models.ErrDuplicateBarcode), errors.Is(err, models.ErrCategoryInUse),
+		errors.Is(err, models.ErrDuplicateTag), errors.Is(err, models.ErrDuplicateBrand),
+		errors.Is(err, models.ErrDuplicateExchangeRate):
+		return http.StatusConflict
+	case errors.Is(err, models.ErrExchangeRateNotFound):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, errUnsupportedMediaType):
+		return http.StatusUnsupportedMediaType
+	}
+	return fallback
+}
+
+// parseProductFilter reads the ?category=, ?tag= (repeatable), ?brand=,
+// ?parent= and ?maxPrice= query parameters of GET /products
+func parseProductFilter(r *http.Request) (models.ProductFilter, error) {
+	var filter models.ProductFilter
+	query := r.URL.Query()
+	for param, target := range map[string]**int{"category": &filter.CategoryId, "brand": &filter.BrandId, "parent": &filter.ParentId} {
+		if value := query.Get(param); value != "" {
+			id, err := strconv.Atoi(value)
+			if err != nil {
+				return filter, err
+			}
+			*target = &id
+		}
+	}
+	for _, tag := range query["tag"] {
+		name, err := models.NormalizeTag(tag)
+		if err != nil {
+			return filter, err
+		}
+		filter.Tags = append(filter.Tags, name)
+	}
+	if maxPrice := query.Get("maxPrice"); maxPrice != "" {
+		price, err := strconv.ParseFloat(maxPrice, 64)
+		if err != nil {
+			return filter, err
+		}
+		filter.MaxPrice = &price
+	}
+	return filter, nil
+}
+
+// pricesAt reads the ?at= query parameter (RFC 3339 or a plain date) used to
+// pick promotions and exchange rates, defaulting to now
+func pricesAt(r *http.Request) (time.Time, error) {
+	at := r.URL.Query().Get("at")
+	if at == "" {
+		return time.Now(), nil
+	}
+	if t, err := time.Parse(time.RFC3339, at); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", at)
+}
+
+// present prices the products with the promotions active at ?at= and the
+// ?currency= conversion before they are rendered
+func (h *ProductHandler) present(r *http.Request, products []models.ProductResponse) ([]models.ProductResponse, error) {
+	at, err := pricesAt(r)
+	if err != nil {
+		return nil, errors.Join(errInvalidQuery, err)
+	}
+	return h.pricing.PriceProducts(products, r.URL.Query().Get("currency"), at)
+}
+
+// renderProduct writes a single product through present
+func (h *ProductHandler) renderProduct(w http.ResponseWriter, r *http.Request, product models.Product) {
+	products, err := h.present(r, []models.ProductResponse{product.ToJSON()})
+	if err != nil {
+		http.Error(w, "Failed pricing product", errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	respond(w, r, products[0])
 }
+
+// GetAllProducts: Streams the products in the type picked from the Accept
+// header, a batch at a time so memory stays flat however large the catalog.
+// ScanProducts releases the rows before handing them over, so pricing and a
+// slow client never hold a database cursor. Failures after the first batch
+// went out can only cut the response short
 func (h *ProductHandler) GetAllProducts(w http.ResponseWriter, r *http.Request) {
-	products, err := h.service.GetAllProducts()
+	filter, err := parseProductFilter(r)
 	if err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	stream := newProductStream(w, responseType(r))
+	batch := make([]models.ProductResponse, 0, streamBatch)
+	var pricingErr error
+	flush := func() error {
+		products, err := h.present(r, batch)
+		if err != nil {
+			pricingErr = err
+			return err
+		}
+		batch = batch[:0]
+		return stream.write(products)
+	}
+	err = h.service.ScanProducts(r.Context(), filter, func(product models.ProductResponse) error {
+		if batch = append(batch, product); len(batch) == streamBatch {
+			return flush()
+		}
+		return nil
+	})
+	if err == nil && len(batch) > 0 {
+		err = flush()
+	}
+	if err == nil {
+		err = stream.close()
+	}
+	switch {
+	case err == nil:
+	case stream.started:
+		slog.ErrorContext(r.Context(), "failed streaming products", "written", stream.count, "err", err)
+	case pricingErr != nil:
+		http.Error(w, "Failed pricing products", errorStatus(err, http.StatusInternalServerError))
+	default:
 		http.Error(w, "Failed getting all products", http.StatusInternalServerError)
 	}
-	render.JSON(w, r, products)
-
 }
+
 func (h *ProductHandler) GetProductById(w http.ResponseWriter, r *http.Request) {
 	var id = chi.URLParam(r, "id")
-	product, err := h.service.GetProductById(id)
+	product, err := h.service.GetProductById(r.Context(), id)
+	if err != nil {
+		http.Error(w, "Failed getting product", errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	h.renderProduct(w, r, product)
+}
+func (h *ProductHandler) GetProductByBarcode(w http.ResponseWriter, r *http.Request) {
+	var code = chi.URLParam(r, "code")
+	product, err := h.service.GetProductByBarcode(r.Context(), code)
+	if err != nil {
+		http.Error(w, "Failed getting product by barcode", errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	h.renderProduct(w, r, product)
+}
+
+// GetVariants lists every size variant of the product
+func (h *ProductHandler) GetVariants(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	products, err := h.service.GetAllProducts(r.Context(), models.ProductFilter{ParentId: &id})
+	if err != nil {
+		http.Error(w, "Failed getting variants", http.StatusInternalServerError)
+		return
+	}
+	products, err = h.present(r, products)
+	if err != nil {
+		http.Error(w, "Failed pricing products", errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	stream := newProductStream(w, responseType(r))
+	if err = stream.write(products); err == nil {
+		err = stream.close()
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed writing variants", "err", err)
+	}
+}
+
+// compare: Renders the unit price comparison of the products matching
+// filter. Comparisons mixing currencies are converted to colones unless
+// ?currency= asks for another one
+func (h *ProductHandler) compare(w http.ResponseWriter, r *http.Request, filter models.ProductFilter) {
+	comparison, err := h.service.CompareProducts(r.Context(), filter)
+	if err != nil {
+		http.Error(w, "Failed comparing products", http.StatusInternalServerError)
+		return
+	}
+	at, err := pricesAt(r)
+	if err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	comparison, err = h.pricing.PriceComparisons(comparison, r.URL.Query().Get("currency"), at)
+	if err != nil {
+		http.Error(w, "Failed pricing comparison", errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	respond(w, r, comparison)
+}
+
+// CompareVariants shows every size variant of the product side by side with
+// the price per base unit each store charges
+func (h *ProductHandler) CompareVariants(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Failed getting product", http.StatusInternalServerError)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
 	}
-	render.JSON(w, r, product.ToJSON())
+	h.compare(w, r, models.ProductFilter{ParentId: &id})
+}
+
+// CompareBrand shows every size of every product of the brand side by side
+// with the price per base unit each store charges
+func (h *ProductHandler) CompareBrand(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	h.compare(w, r, models.ProductFilter{BrandId: &id})
 }
 func (h *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 	var product models.ProductResponse
-	if err := json.NewDecoder(r.Body).Decode(&product); err != nil {
-		log.Println(err)
-		http.Error(w, "Bad Request", http.StatusBadRequest)
+	if !decodeBody(w, r, &product) {
 		return
 	}
-	product, err := h.service.CreateProduct(product)
+	product, err := h.service.CreateProduct(r.Context(), product)
 	if err != nil {
-		http.Error(w, "Failed creating product", http.StatusInternalServerError)
+		http.Error(w, "Failed creating product", errorStatus(err, http.StatusInternalServerError))
+		return
 	}
-	render.JSON(w, r, product)
+	respond(w, r, product)
 }
 func (h *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
 	var id = chi.URLParam(r, "id")
-	if err := h.service.DeleteProduct(id); err != nil {
-		http.Error(w, "Failed deleting product", http.StatusInternalServerError)
+	if err := h.service.DeleteProduct(r.Context(), id); err != nil {
+		http.Error(w, "Failed deleting product", errorStatus(err, http.StatusInternalServerError))
+		return
 	}
 	w.Write([]byte("Delete successful"))
 }
@@ -57,41 +299,37 @@ func (h *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
 func (h *ProductHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
 	var id = chi.URLParam(r, "id")
 	var product models.ProductResponse
-	if err := json.NewDecoder(r.Body).Decode(&product); err != nil {
-		log.Println(err)
-		http.Error(w, "Bad Request", http.StatusBadRequest)
+	if !decodeBody(w, r, &product) {
 		return
 	}
-	product, err := h.service.UpdateProduct(id, product)
+	product, err := h.service.UpdateProduct(r.Context(), id, product)
 	if err != nil {
-		http.Error(w, "Failed updating product", http.StatusInternalServerError)
+		http.Error(w, "Failed updating product", errorStatus(err, http.StatusInternalServerError))
+		return
 	}
 
-	render.JSON(w, r, product)
+	respond(w, r, product)
 
 }
 func (h *ProductHandler) PatchProduct(w http.ResponseWriter, r *http.Request) {
 	var id = chi.URLParam(r, "id")
 	var product models.ProductResponse
-	if err := json.NewDecoder(r.Body).Decode(&product); err != nil {
-		log.Println(err)
-		http.Error(w, "Bad Request", http.StatusBadRequest)
+	if !decodeBody(w, r, &product) {
 		return
 	}
-	updatedProduct, err := h.service.PatchProduct(id, product)
+	updatedProduct, err := h.service.PatchProduct(r.Context(), id, product)
 	if err != nil {
-		http.Error(w, "Failed patching product", http.StatusInternalServerError)
+		http.Error(w, "Failed patching product", errorStatus(err, http.StatusInternalServerError))
+		return
 	}
 
-	render.JSON(w, r, updatedProduct.ToJSON())
+	respond(w, r, updatedProduct.ToJSON())
 }
 
 func (h *ProductHandler) PatchStore(w http.ResponseWriter, r *http.Request) {
 	var id = chi.URLParam(r, "id")
 	var store models.Stores
-	if err := json.NewDecoder(r.Body).Decode(&store); err != nil {
-		log.Println(err)
-		http.Error(w, "Bad Request", http.StatusBadRequest)
+	if !decodeBody(w, r, &store) {
 		return
 	}
 	jsonStore, err := json.Marshal(store)
@@ -99,11 +337,12 @@ func (h *ProductHandler) PatchStore(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Unable to convert data to JSON", http.StatusInternalServerError)
 		return
 	}
-	updatedProduct, err := h.service.PatchStore(id, jsonStore)
+	updatedProduct, err := h.service.PatchStore(r.Context(), id, jsonStore)
 	if err != nil {
-		http.Error(w, "Failed patching store", http.StatusInternalServerError)
+		http.Error(w, "Failed patching store", errorStatus(err, http.StatusInternalServerError))
+		return
 	}
-	render.JSON(w, r, updatedProduct.ToJSON())
+	respond(w, r, updatedProduct.ToJSON())
 }
 func rootHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("hi"))
@@ -1,17 +1,24 @@
 package http
 
 import (
+	"context"
+	"crproductos/internal/auth"
 	"crproductos/internal/models"
+	"crproductos/internal/service"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"math"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 )
 
 type mockProductService struct{}
 
-func (s mockProductService) GetAllProducts() ([]models.ProductResponse, error) {
+func (s mockProductService) GetAllProducts(ctx context.Context, filter models.ProductFilter) ([]models.ProductResponse, error) {
 	var expectedProducts = []models.Product{
 		{
 			Id:       2,
@@ -46,7 +53,23 @@ func (s mockProductService) GetAllProducts() ([]models.ProductResponse, error) {
 	return result, nil
 }
 
-func (s mockProductService) GetProductById(id string) (models.Product, error) {
+func (s mockProductService) ScanProducts(ctx context.Context, filter models.ProductFilter, fn func(models.ProductResponse) error) error {
+	products, err := s.GetAllProducts(ctx, filter)
+	if err != nil {
+		return err
+	}
+	for _, product := range products {
+		if err := fn(product); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s mockProductService) GetProductById(ctx context.Context, id string) (models.Product, error) {
+	if id == "99" {
+		return models.Product{}, sql.ErrNoRows
+	}
 	return models.Product{
 		Id:       2,
 		Name:     sql.NullString{String: "pepsi", Valid: true},
@@ -56,7 +79,21 @@ func (s mockProductService) GetProductById(id string) (models.Product, error) {
 	}, nil
 }
 
-func (s mockProductService) CreateProduct(product models.ProductResponse) (models.ProductResponse, error) {
+func (s mockProductService) GetProductByBarcode(ctx context.Context, barcode string) (models.Product, error) {
+	if barcode != "7441029512342" {
+		return models.Product{}, sql.ErrNoRows
+	}
+	return models.Product{
+		Id:       1,
+		Name:     sql.NullString{String: "coca", Valid: true},
+		Quantity: sql.NullFloat64{Float64: 2.500000, Valid: true},
+		Unit:     sql.NullString{String: "litros", Valid: true},
+		Stores:   &models.Stores{},
+		Barcodes: models.Barcodes{"7441029512342"},
+	}, nil
+}
+
+func (s mockProductService) CreateProduct(ctx context.Context, product models.ProductResponse) (models.ProductResponse, error) {
 	var createdProduct = models.Product{
 		Id:       4,
 		Name:     sql.NullString{String: "te verde", Valid: true},
@@ -70,18 +107,28 @@ func (s mockProductService) CreateProduct(product models.ProductResponse) (model
 	}
 	return createdProduct.ToJSON(), nil
 }
-func (s mockProductService) DeleteProduct(id string) error {
+func (s mockProductService) DeleteProduct(ctx context.Context, id string) error {
+	if id == "99" {
+		return sql.ErrNoRows
+	}
 	return nil
 }
-func (s mockProductService) UpdateProduct(id string, product models.ProductResponse) (models.ProductResponse, error) {
+func (s mockProductService) UpdateProduct(ctx context.Context, id string, product models.ProductResponse) (models.ProductResponse, error) {
 	return models.ProductResponse{}, nil
 }
-func (s mockProductService) PatchProduct(id string, product models.ProductResponse) (models.Product, error) {
+func (s mockProductService) PatchProduct(ctx context.Context, id string, product models.ProductResponse) (models.Product, error) {
 	return models.Product{}, nil
 }
-func (s mockProductService) PatchStore(id string, jsonStore []byte) (models.Product, error) {
+func (s mockProductService) PatchStore(ctx context.Context, id string, jsonStore []byte) (models.Product, error) {
+	if id == "99" {
+		return models.Product{}, sql.ErrNoRows
+	}
 	return models.Product{}, nil
 }
+func (s mockProductService) CompareProducts(ctx context.Context, filter models.ProductFilter) ([]models.VariantComparison, error) {
+	products, _ := s.GetAllProducts(ctx, filter)
+	return models.CompareVariants(products), nil
+}
 
 func executeRequest(req *http.Request, s *Server) *httptest.ResponseRecorder {
 	rr := httptest.NewRecorder()
@@ -107,6 +154,125 @@ func TestGetAllProducts(t *testing.T) {
 	fmt.Printf("Body: %v", response.Body.String())
 }
 
+func TestGetAllProductsBadFilter(t *testing.T) {
+	s := NewServer()
+	s.MountHandlers(NewProductHandler(&mockProductService{}))
+
+	for _, query := range []string{"category=bebidas", "maxPrice=barato", "tag=%20"} {
+		response := executeRequest(httptest.NewRequest("GET", "/products/?"+query, nil), s)
+		checkResponseCode(t, http.StatusBadRequest, response.Code)
+	}
+	response := executeRequest(httptest.NewRequest("GET", "/products/?category=1&tag=bebidas&maxPrice=2000", nil), s)
+	checkResponseCode(t, http.StatusOK, response.Code)
+}
+
+func TestGetProductByBarcode(t *testing.T) {
+	s := NewServer()
+	s.MountHandlers(NewProductHandler(&mockProductService{}))
+
+	response := executeRequest(httptest.NewRequest("GET", "/products/by-barcode/7441029512342", nil), s)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var result models.ProductResponse
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		t.Fatalf("Test failed, reason: %v", err)
+	}
+	if len(result.Barcodes) != 1 || result.Barcodes[0] != "7441029512342" {
+		t.Errorf("Test failed, reason: Barcodes do not match\n Actual: %+v\n", result.Barcodes)
+	}
+
+	response = executeRequest(httptest.NewRequest("GET", "/products/by-barcode/0000000000000", nil), s)
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+}
+
+func TestMissingProduct(t *testing.T) {
+	s := NewServer(WithAuthenticators(auth.StaticKey("bootstrap", models.RoleAdmin)))
+	s.MountHandlers(NewProductHandler(&mockProductService{}))
+
+	response := executeRequest(httptest.NewRequest("GET", "/v1/products/99", nil), s)
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+
+	req := httptest.NewRequest("PATCH", "/v1/products/99/store", strings.NewReader(`{"pali": 900}`))
+	req.Header.Set("X-API-Key", "bootstrap")
+	response = executeRequest(req, s)
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+	if strings.Contains(response.Body.String(), "{") {
+		t.Errorf("a failed patch should not render a product, got %q", response.Body.String())
+	}
+}
+
+func TestDeleteProduct(t *testing.T) {
+	s := NewServer(WithAuthenticators(auth.StaticKey("bootstrap", models.RoleAdmin)))
+	s.MountHandlers(NewProductHandler(&mockProductService{}))
+	remove := func(id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("DELETE", "/v1/products/"+id, nil)
+		req.Header.Set("X-API-Key", "bootstrap")
+		return executeRequest(req, s)
+	}
+
+	response := remove("2")
+	checkResponseCode(t, http.StatusOK, response.Code)
+	if response.Body.String() != "Delete successful" {
+		t.Errorf("unexpected body %q", response.Body.String())
+	}
+	response = remove("99")
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+	if strings.Contains(response.Body.String(), "Delete successful") {
+		t.Errorf("a failed delete should not report success, got %q", response.Body.String())
+	}
+}
+
+// mixedCurrencyCatalog compares a litre priced in colones with two litres
+// priced in dollars
+type mixedCurrencyCatalog struct {
+	mockProductService
+}
+
+func (c mixedCurrencyCatalog) CompareProducts(ctx context.Context, filter models.ProductFilter) ([]models.VariantComparison, error) {
+	litre, litres := 1.0, 2.0
+	unit := "litros"
+	return models.CompareVariants([]models.ProductResponse{
+		{Id: 1, Quantity: &litre, Unit: &unit, Stores: &models.Stores{"pali": 1000}},
+		{Id: 2, Quantity: &litres, Unit: &unit, Stores: &models.Stores{"amazon": 3}, Currencies: &models.StoreCurrencies{"amazon": "USD"}},
+	}), nil
+}
+
+// dollarRate knows a single USD to CRC rate
+type dollarRate struct{}
+
+func (dollarRate) GetAllExchangeRates() ([]models.ExchangeRate, error) { return nil, nil }
+func (dollarRate) CreateExchangeRate(rate models.ExchangeRate) (models.ExchangeRate, error) {
+	return rate, nil
+}
+func (dollarRate) DeleteExchangeRate(id string) error { return nil }
+func (dollarRate) FindExchangeRate(base string, quote string, at time.Time) (models.ExchangeRate, error) {
+	if base == "USD" && quote == "CRC" {
+		return models.ExchangeRate{Id: 1, Base: base, Quote: quote, Rate: 520}, nil
+	}
+	return models.ExchangeRate{}, models.ErrExchangeRateNotFound
+}
+
+func TestCompareMixedCurrencies(t *testing.T) {
+	s := NewServer()
+	s.MountHandlers(NewProductHandler(mixedCurrencyCatalog{}, WithExchangeRates(service.NewExchangeRateService(dollarRate{}))))
+
+	response := executeRequest(httptest.NewRequest("GET", "/products/1/comparison", nil), s)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var comparison []models.VariantComparison
+	if err := json.NewDecoder(response.Body).Decode(&comparison); err != nil {
+		t.Fatal(err)
+	}
+	if len(comparison) != 2 || math.Abs(comparison[1].UnitPrices["amazon"]-780) > 1e-9 || comparison[0].UnitPrices["pali"] != 1000 {
+		t.Errorf("Expected unit prices in colones, got %+v", comparison)
+	}
+	if comparison[1].Conversion == nil || comparison[1].Conversion.Currency != "CRC" || comparison[1].Currencies != nil {
+		t.Errorf("Expected the dollar prices converted to CRC, got %+v", comparison[1])
+	}
+
+	s = NewServer()
+	s.MountHandlers(NewProductHandler(mixedCurrencyCatalog{}))
+	checkResponseCode(t, http.StatusUnprocessableEntity, executeRequest(httptest.NewRequest("GET", "/products/1/comparison", nil), s).Code)
+}
+
 //
 // //TODO: Create test for the rest of handlers
 //
@@ -0,0 +1,49 @@
+package http
+
+import (
+	"bytes"
+	"crproductos/internal/logging"
+	"encoding/json"
+	"log/slog"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestLogging(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+	var out bytes.Buffer
+	logging.Setup(&out, "info")
+
+	s := NewServer()
+	s.MountHandlers(NewProductHandler(&mockProductService{}))
+	req := httptest.NewRequest("GET", "/products/2", nil)
+	req.Header.Set("X-Request-ID", "abc-123")
+	response := executeRequest(req, s)
+
+	if got := response.Header().Get("X-Request-ID"); got != "abc-123" {
+		t.Errorf("X-Request-ID = %q, want abc-123", got)
+	}
+	var record map[string]any
+	if err := json.Unmarshal(out.Bytes(), &record); err != nil {
+		t.Fatalf("log line is not JSON: %v\n%s", err, out.String())
+	}
+	want := map[string]any{"msg": "request", "request_id": "abc-123", "method": "GET", "route": "/products/{id}", "status": float64(200)}
+	for key, value := range want {
+		if record[key] != value {
+			t.Errorf("%s = %v, want %v", key, record[key], value)
+		}
+	}
+}
+
+func TestRequestIDGenerated(t *testing.T) {
+	s := NewServer()
+	s.MountHandlers(NewProductHandler(&mockProductService{}))
+	for _, sent := range []string{"", "bad id\nwith newline"} {
+		req := httptest.NewRequest("GET", "/products/2", nil)
+		req.Header.Set("X-Request-ID", sent)
+		response := executeRequest(req, s)
+		if got := response.Header().Get("X-Request-ID"); got == "" || got == sent {
+			t.Errorf("sent %q, got X-Request-ID %q", sent, got)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package http
+
+import (
+	"crproductos/internal/metrics"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type httpMetrics struct {
+	registry *metrics.Registry
+	requests *metrics.CounterVec
+	duration *metrics.HistogramVec
+}
+
+// WithMetrics counts every request and its latency per route pattern and
+// status in registry, MountMetricsHandler exposes them
+func WithMetrics(registry *metrics.Registry) ServerOption {
+	return func(s *Server) {
+		s.metrics = &httpMetrics{
+			registry: registry,
+			requests: registry.NewCounter("crproductos_http_requests_total", "HTTP requests served.", "method", "route", "status"),
+			duration: registry.NewHistogram("crproductos_http_request_duration_seconds", "Time taken to serve HTTP requests.", nil, "method", "route", "status"),
+		}
+	}
+}
+
+// instrument records the request once served. The route pattern is only
+// known after chi routed it, unmatched requests are grouped together so
+// random paths cannot blow up the number of series
+func (m *httpMetrics) instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		m.requests.Inc(r.Method, route, strconv.Itoa(status))
+		m.duration.ObserveDuration(start, r.Method, route, strconv.Itoa(status))
+	})
+}
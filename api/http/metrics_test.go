@@ -0,0 +1,35 @@
+package http
+
+import (
+	"crproductos/internal/metrics"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	registry := metrics.NewRegistry()
+	s := NewServer(WithMetrics(registry))
+	s.MountHandlers(NewProductHandler(&mockProductService{}))
+	s.MountMetricsHandler()
+
+	executeRequest(httptest.NewRequest("GET", "/products/1", nil), s)
+	executeRequest(httptest.NewRequest("GET", "/products/2", nil), s)
+	executeRequest(httptest.NewRequest("DELETE", "/products/2", nil), s)
+	executeRequest(httptest.NewRequest("GET", "/nowhere", nil), s)
+
+	response := executeRequest(httptest.NewRequest("GET", "/metrics", nil), s)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	body := response.Body.String()
+	for _, want := range []string{
+		`crproductos_http_requests_total{method="GET",route="/products/{id}",status="200"} 2`,
+		`crproductos_http_requests_total{method="DELETE",route="/products/{id}",status="401"} 1`,
+		`crproductos_http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`crproductos_http_request_duration_seconds_count{method="GET",route="/products/{id}",status="200"} 2`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics lack %s\n%s", want, body)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crproductos/internal/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/render"
+	"github.com/vmihailenco/msgpack/v5"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The media types the product endpoints speak, JSON unless the Accept
+// header asks for another one
+const (
+	contentTypeJSON    = "application/json"
+	contentTypeNDJSON  = "application/x-ndjson"
+	contentTypeCSV     = "text/csv"
+	contentTypeMsgPack = "application/msgpack"
+)
+
+// mediaTypeAliases maps the names some clients still send to the ones we use
+var mediaTypeAliases = map[string]string{"application/x-msgpack": contentTypeMsgPack}
+
+// errUnsupportedMediaType flags request bodies in a media type the route
+// does not read
+var errUnsupportedMediaType = errors.New("unsupported media type")
+
+func init() {
+	// Date would go out as the binary form of its time.Time, keep it a
+	// "2006-01-02" string like in JSON
+	msgpack.Register(models.Date{},
+		func(e *msgpack.Encoder, v reflect.Value) error {
+			return e.EncodeString(v.Interface().(models.Date).Format(time.DateOnly))
+		},
+		func(d *msgpack.Decoder, v reflect.Value) error {
+			value, err := d.DecodeString()
+			if err != nil {
+				return err
+			}
+			t, err := time.Parse(time.DateOnly, value)
+			if err != nil {
+				return err
+			}
+			v.Set(reflect.ValueOf(models.Date{Time: t}))
+			return nil
+		})
+}
+
+// newMsgPackEncoder encodes with the json struct tags so both formats carry
+// the same field names
+func newMsgPackEncoder(w io.Writer) *msgpack.Encoder {
+	encoder := msgpack.NewEncoder(w)
+	encoder.SetCustomStructTag("json")
+	return encoder
+}
+
+func newMsgPackDecoder(r io.Reader) *msgpack.Decoder {
+	decoder := msgpack.NewDecoder(r)
+	decoder.SetCustomStructTag("json")
+	return decoder
+}
+
+// mediaRange is one of the comma separated entries of an Accept header
+type mediaRange struct {
+	mediaType string
+	q         float64
+}
+
+// parseAccept reads the media ranges of an Accept header, skipping the
+// malformed ones
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if alias, ok := mediaTypeAliases[mediaType]; ok {
+			mediaType = alias
+		}
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+		ranges = append(ranges, mediaRange{mediaType: mediaType, q: q})
+	}
+	return ranges
+}
+
+// specificity tells how closely the range matches mediaType, 2 for the
+// type itself, 1 for type/* and 0 for */*, -1 when it does not match
+func (m mediaRange) specificity(mediaType string) int {
+	switch {
+	case m.mediaType == mediaType:
+		return 2
+	case m.mediaType == "*/*":
+		return 0
+	case strings.HasSuffix(m.mediaType, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(m.mediaType, "*")):
+		return 1
+	}
+	return -1
+}
+
+// negotiate: Picks the offered media type the Accept header rates highest,
+// each one rated by the most specific range matching it. Ties go to the
+// earliest offer and an empty header takes the first one. Returns "" when
+// the header accepts none of them
+func negotiate(accept string, offered []string) string {
+	if strings.TrimSpace(accept) == "" {
+		return offered[0]
+	}
+	ranges := parseAccept(accept)
+	best, bestQ := "", 0.0
+	for _, offer := range offered {
+		q, specificity := 0.0, -1
+		for _, m := range ranges {
+			if s := m.specificity(offer); s > specificity {
+				q, specificity = m.q, s
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+type responseTypeKey struct{}
+
+// offers: Picks the media type of the response among types, listed in our
+// order of preference, from the Accept header. Requests accepting none of
+// them are answered 406 with the list of types on offer
+func offers(types ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept")
+			mediaType := negotiate(r.Header.Get("Accept"), types)
+			if mediaType == "" {
+				http.Error(w, "Not Acceptable, available types: "+strings.Join(types, ", "), http.StatusNotAcceptable)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), responseTypeKey{}, mediaType)))
+		})
+	}
+}
+
+// responseType returns the media type offers picked, JSON on routes without
+func responseType(r *http.Request) string {
+	if mediaType, ok := r.Context().Value(responseTypeKey{}).(string); ok {
+		return mediaType
+	}
+	return contentTypeJSON
+}
+
+// respond writes v as MessagePack when offers picked it and as JSON otherwise
+func respond(w http.ResponseWriter, r *http.Request, v any) {
+	if responseType(r) != contentTypeMsgPack {
+		render.JSON(w, r, v)
+		return
+	}
+	var buf bytes.Buffer
+	if err := newMsgPackEncoder(&buf).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentTypeMsgPack)
+	if status, ok := r.Context().Value(render.StatusCtxKey).(int); ok {
+		w.WriteHeader(status)
+	}
+	w.Write(buf.Bytes())
+}
+
+// decode reads the request body into v as JSON or MessagePack, going by its
+// Content-Type. Bodies without one are taken as JSON
+func decode(r *http.Request, v any) error {
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		return json.NewDecoder(r.Body).Decode(v)
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return errors.Join(errUnsupportedMediaType, err)
+	}
+	if alias, ok := mediaTypeAliases[mediaType]; ok {
+		mediaType = alias
+	}
+	switch mediaType {
+	case contentTypeJSON:
+		return json.NewDecoder(r.Body).Decode(v)
+	case contentTypeMsgPack:
+		return newMsgPackDecoder(r.Body).Decode(v)
+	}
+	return fmt.Errorf("%w %s", errUnsupportedMediaType, mediaType)
+}
+
+// decodeBody decodes the request body into v, answering 400 when it is
+// malformed and 415 when it comes in a type decode does not read
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := decode(r, v); err != nil {
+		slog.WarnContext(r.Context(), "invalid request body", "err", err)
+		status := errorStatus(err, http.StatusBadRequest)
+		http.Error(w, http.StatusText(status), status)
+		return false
+	}
+	return true
+}
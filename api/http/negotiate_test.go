@@ -0,0 +1,124 @@
+package http
+
+import (
+	"bytes"
+	"crproductos/internal/auth"
+	"crproductos/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNegotiate(t *testing.T) {
+	offered := []string{contentTypeJSON, contentTypeNDJSON, contentTypeCSV, contentTypeMsgPack}
+	cases := map[string]string{
+		"":                                     contentTypeJSON,
+		"*/*":                                  contentTypeJSON,
+		"text/*":                               contentTypeCSV,
+		"application/x-msgpack":                contentTypeMsgPack,
+		"application/json;q=0.5, text/csv":     contentTypeCSV,
+		"*/*;q=0.1, application/x-ndjson;q=.2": contentTypeNDJSON,
+		"*/*, application/json;q=0":            contentTypeNDJSON,
+		"text/html, image/*":                   "",
+		"application/json;q=0":                 "",
+	}
+	for accept, want := range cases {
+		if got := negotiate(accept, offered); got != want {
+			t.Errorf("Accept %q: got %q, want %q", accept, got, want)
+		}
+	}
+}
+
+func TestProductContentNegotiation(t *testing.T) {
+	s := NewServer()
+	s.MountHandlers(NewProductHandler(&mockProductService{}))
+	get := func(path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Accept", accept)
+		return executeRequest(req, s)
+	}
+
+	response := get("/v1/products", "text/html")
+	checkResponseCode(t, http.StatusNotAcceptable, response.Code)
+	if !strings.Contains(response.Body.String(), contentTypeMsgPack) {
+		t.Errorf("406 should list the available types, got %q", response.Body.String())
+	}
+	checkResponseCode(t, http.StatusNotAcceptable, get("/v1/products/2", "text/csv").Code)
+
+	response = get("/v1/products", "text/csv")
+	checkResponseCode(t, http.StatusOK, response.Code)
+	if response.Header().Get("Content-Type") != contentTypeCSV || response.Header().Get("Vary") != "Accept" {
+		t.Errorf("unexpected headers %v", response.Header())
+	}
+	products, err := models.ReadProductsCSV(response.Body)
+	if err != nil || len(products) != 3 || (*products[2].Stores)["pali"] != 6000 {
+		t.Errorf("unexpected csv products %+v, %v", products, err)
+	}
+
+	response = get("/v1/products", "application/msgpack")
+	if response.Header().Get("Content-Type") != contentTypeMsgPack {
+		t.Errorf("got Content-Type %q", response.Header().Get("Content-Type"))
+	}
+	products = nil
+	if err := newMsgPackDecoder(response.Body).Decode(&products); err != nil || len(products) != 3 || *products[1].Name != "coca" {
+		t.Errorf("unexpected msgpack products %+v, %v", products, err)
+	}
+
+	var product models.ProductResponse
+	response = get("/v1/products/by-barcode/7441029512342", "application/msgpack")
+	if err := newMsgPackDecoder(response.Body).Decode(&product); err != nil || product.Barcodes[0] != "7441029512342" {
+		t.Errorf("unexpected msgpack product %+v, %v", product, err)
+	}
+
+	var comparison []models.VariantComparison
+	response = get("/v1/products/1/comparison", "application/msgpack")
+	if err := newMsgPackDecoder(response.Body).Decode(&comparison); err != nil || len(comparison) == 0 {
+		t.Errorf("unexpected msgpack comparison %+v, %v", comparison, err)
+	}
+}
+
+func TestProductRequestBodies(t *testing.T) {
+	s := NewServer(WithAuthenticators(auth.StaticKey("bootstrap", models.RoleAdmin)))
+	s.MountHandlers(NewProductHandler(&mockProductService{}))
+	post := func(contentType string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/v1/products", bytes.NewReader(body))
+		req.Header.Set("X-API-Key", "bootstrap")
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Accept", "application/msgpack")
+		return executeRequest(req, s)
+	}
+
+	var body bytes.Buffer
+	name := "te verde"
+	if err := newMsgPackEncoder(&body).Encode(models.ProductResponse{Name: &name, Stores: &models.Stores{"pali": 6000}}); err != nil {
+		t.Fatal(err)
+	}
+	response := post("application/msgpack", body.Bytes())
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var created models.ProductResponse
+	if err := newMsgPackDecoder(response.Body).Decode(&created); err != nil || created.Id != 4 {
+		t.Errorf("unexpected created product %+v, %v", created, err)
+	}
+
+	checkResponseCode(t, http.StatusUnsupportedMediaType, post("text/csv", []byte("name\nte verde\n")).Code)
+	checkResponseCode(t, http.StatusBadRequest, post("application/msgpack", []byte{0xc1}).Code)
+	checkResponseCode(t, http.StatusOK, post("application/json; charset=utf-8", []byte(`{"name":"te verde"}`)).Code)
+}
+
+func TestMsgPackDate(t *testing.T) {
+	rate := models.ExchangeRate{Base: "USD", Quote: "CRC", Rate: 505.5,
+		EffectiveDate: models.Date{Time: time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)}}
+	var buf bytes.Buffer
+	if err := newMsgPackEncoder(&buf).Encode(rate); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("2026-10-19")) {
+		t.Errorf("the date should travel as a string, got %x", buf.Bytes())
+	}
+	var decoded models.ExchangeRate
+	if err := newMsgPackDecoder(&buf).Decode(&decoded); err != nil || !decoded.EffectiveDate.Equal(rate.EffectiveDate.Time) {
+		t.Errorf("got %+v, %v", decoded, err)
+	}
+}
@@ -0,0 +1,80 @@
+package http
+
+import (
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestOpenAPIRoutes(t *testing.T) {
+	var spec struct {
+		OpenAPI string                                `json:"openapi"`
+		Paths   map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(openAPISpec, &spec); err != nil {
+		t.Fatalf("invalid openapi.json: %v", err)
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.1") {
+		t.Errorf("openapi = %q, want 3.1", spec.OpenAPI)
+	}
+	documented := map[string]bool{}
+	for path, item := range spec.Paths {
+		for method := range item {
+			if method != "parameters" {
+				documented[strings.ToUpper(method)+" "+path] = true
+			}
+		}
+	}
+
+	s := NewServer()
+	s.MountHandlers(NewProductHandler(&mockProductService{}))
+	registered := map[string]bool{}
+	err := chi.Walk(s.v1, func(method string, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if len(route) > 1 {
+			route = strings.TrimSuffix(route, "/")
+		}
+		registered[method+" "+route] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if missing := difference(registered, documented); len(missing) > 0 {
+		t.Errorf("routes missing from openapi.json: %v", missing)
+	}
+	if stale := difference(documented, registered); len(stale) > 0 {
+		t.Errorf("openapi.json documents unknown routes: %v", stale)
+	}
+}
+
+func difference(a, b map[string]bool) []string {
+	var keys []string
+	for key := range a {
+		if !b[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestDocsHandlers(t *testing.T) {
+	s := NewServer()
+	s.MountDocsHandlers()
+
+	response := executeRequest(httptest.NewRequest("GET", "/openapi.json", nil), s)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	if !json.Valid(response.Body.Bytes()) || response.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("unexpected /openapi.json response %q", response.Header().Get("Content-Type"))
+	}
+	response = executeRequest(httptest.NewRequest("GET", "/docs", nil), s)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	if !strings.Contains(response.Body.String(), `spec-url="openapi.json"`) {
+		t.Errorf("docs page does not load the spec")
+	}
+}
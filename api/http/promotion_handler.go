@@ -0,0 +1,122 @@
+package http
+
+import (
+	"crproductos/internal/models"
+	"crproductos/internal/service"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+type PromotionHandler struct {
+	service service.PromotionService
+}
+
+func NewPromotionHandler(svc service.PromotionService) *PromotionHandler {
+	return &PromotionHandler{service: svc}
+}
+
+// parsePromotionFilter reads ?product=, ?store= and ?active=true, the latter
+// keeps the promotions running at ?at= (now by default)
+func parsePromotionFilter(r *http.Request) (models.PromotionFilter, error) {
+	var filter models.PromotionFilter
+	query := r.URL.Query()
+	if product := query.Get("product"); product != "" {
+		id, err := strconv.Atoi(product)
+		if err != nil {
+			return filter, err
+		}
+		filter.ProductIds = []int{id}
+	}
+	filter.Store = query.Get("store")
+	if query.Get("active") == "true" {
+		at, err := pricesAt(r)
+		if err != nil {
+			return filter, err
+		}
+		filter.ActiveAt = &at
+	}
+	return filter, nil
+}
+
+func (h *PromotionHandler) GetPromotions(w http.ResponseWriter, r *http.Request) {
+	filter, err := parsePromotionFilter(r)
+	if err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	promotions, err := h.service.GetPromotions(filter)
+	if err != nil {
+		http.Error(w, "Failed getting promotions", http.StatusInternalServerError)
+		return
+	}
+	render.JSON(w, r, promotions)
+}
+func (h *PromotionHandler) GetProductPromotions(w http.ResponseWriter, r *http.Request) {
+	filter, err := parsePromotionFilter(r)
+	if err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	filter.ProductIds = []int{id}
+	promotions, err := h.service.GetPromotions(filter)
+	if err != nil {
+		http.Error(w, "Failed getting promotions", http.StatusInternalServerError)
+		return
+	}
+	render.JSON(w, r, promotions)
+}
+func (h *PromotionHandler) GetPromotionById(w http.ResponseWriter, r *http.Request) {
+	var id = chi.URLParam(r, "id")
+	promotion, err := h.service.GetPromotionById(id)
+	if err != nil {
+		http.Error(w, "Failed getting promotion", errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	render.JSON(w, r, promotion)
+}
+func (h *PromotionHandler) CreatePromotion(w http.ResponseWriter, r *http.Request) {
+	var promotion models.Promotion
+	if err := json.NewDecoder(r.Body).Decode(&promotion); err != nil {
+		slog.WarnContext(r.Context(), "invalid request body", "err", err)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	promotion, err := h.service.CreatePromotion(promotion)
+	if err != nil {
+		http.Error(w, "Failed creating promotion", errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	render.JSON(w, r, promotion)
+}
+func (h *PromotionHandler) UpdatePromotion(w http.ResponseWriter, r *http.Request) {
+	var id = chi.URLParam(r, "id")
+	var promotion models.Promotion
+	if err := json.NewDecoder(r.Body).Decode(&promotion); err != nil {
+		slog.WarnContext(r.Context(), "invalid request body", "err", err)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	promotion, err := h.service.UpdatePromotion(id, promotion)
+	if err != nil {
+		http.Error(w, "Failed updating promotion", errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	render.JSON(w, r, promotion)
+}
+func (h *PromotionHandler) DeletePromotion(w http.ResponseWriter, r *http.Request) {
+	var id = chi.URLParam(r, "id")
+	if err := h.service.DeletePromotion(id); err != nil {
+		http.Error(w, "Failed deleting promotion", errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	w.Write([]byte("Delete successful"))
+}
@@ -0,0 +1,67 @@
+package http
+
+import (
+	"crproductos/internal/auth"
+	"crproductos/internal/ratelimit"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RateLimits are the limits applied per caller, reads and writes are counted
+// in separate buckets
+type RateLimits struct {
+	Store ratelimit.Store
+	Read  ratelimit.Limit
+	Write ratelimit.Limit
+}
+
+// rateLimit: Takes a token from the bucket of the caller, keyed by its
+// principal or else its IP, and answers 429 once the bucket is empty. The
+// RateLimit-* headers report the state of the bucket on every response.
+// When the store fails the request goes through
+func rateLimit(limits RateLimits) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			class, limit := "write", limits.Write
+			if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+				class, limit = "read", limits.Read
+			}
+			result, err := limits.Store.Take(class+":"+rateLimitKey(r), limit, time.Now())
+			if err != nil {
+				slog.Error("failed to check rate limit", "err", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			w.Header().Set("RateLimit-Reset", ceilSeconds(result.Reset))
+			if !result.Allowed {
+				w.Header().Set("Retry-After", ceilSeconds(result.RetryAfter))
+				http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// rateLimitKey identifies the caller, authenticated callers share a bucket
+// whatever address they call from
+func rateLimitKey(r *http.Request) string {
+	if principal, ok := auth.FromContext(r.Context()); ok {
+		return principal.Subject
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
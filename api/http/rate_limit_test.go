@@ -0,0 +1,49 @@
+package http
+
+import (
+	"crproductos/internal/auth"
+	"crproductos/internal/models"
+	"crproductos/internal/ratelimit"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRateLimit(t *testing.T) {
+	s := NewServer(
+		WithAuthenticators(auth.StaticKey("admin", models.RoleAdmin)),
+		WithRateLimits(RateLimits{
+			Store: ratelimit.NewMemoryStore(),
+			Read:  ratelimit.Limit{Rate: 0.5, Burst: 2},
+			Write: ratelimit.Limit{Rate: 0.5, Burst: 1},
+		}),
+	)
+	s.MountHandlers(NewProductHandler(&mockProductService{}))
+	request := func(method string, path string, remoteAddr string, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = remoteAddr
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		return executeRequest(req, s)
+	}
+
+	first := request("GET", "/products/", "10.0.0.1:1234", "")
+	checkResponseCode(t, http.StatusOK, first.Code)
+	if first.Header().Get("RateLimit-Limit") != "2" || first.Header().Get("RateLimit-Remaining") != "1" || first.Header().Get("RateLimit-Reset") != "2" {
+		t.Errorf("unexpected headers %v", first.Header())
+	}
+	checkResponseCode(t, http.StatusOK, request("GET", "/products/", "10.0.0.1:4321", "").Code)
+	limited := request("GET", "/products/", "10.0.0.1:1234", "")
+	checkResponseCode(t, http.StatusTooManyRequests, limited.Code)
+	if limited.Header().Get("Retry-After") != "2" {
+		t.Errorf("Retry-After = %q", limited.Header().Get("Retry-After"))
+	}
+
+	// other addresses and callers with keys get buckets of their own, and
+	// writes do not draw from the read bucket
+	checkResponseCode(t, http.StatusOK, request("GET", "/products/", "10.0.0.2:1234", "").Code)
+	checkResponseCode(t, http.StatusOK, request("GET", "/products/", "10.0.0.1:1234", "admin").Code)
+	checkResponseCode(t, http.StatusOK, request("DELETE", "/products/1", "10.0.0.1:1234", "admin").Code)
+	checkResponseCode(t, http.StatusTooManyRequests, request("DELETE", "/products/1", "10.0.0.3:1234", "admin").Code)
+}
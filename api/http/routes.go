@@ -1,30 +1,254 @@
 package http
 
 import (
+	"crproductos/internal/auth"
+	"crproductos/internal/models"
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
-	"github.com/go-chi/render"
+	"net/http"
+	"strconv"
+	"time"
 )
 
 type Server struct {
 	Router *chi.Mux
+	// v1 serves the routes under /v1, the ProductResponse shape it renders
+	// is frozen. Breaking changes go to a new version router mounted next
+	// to it
+	v1             chi.Router
+	sunset         time.Time
+	authenticators []auth.Authenticator
+	rateLimits     *RateLimits
+	metrics        *httpMetrics
 }
 
-func NewServer() *Server {
-	return &Server{Router: chi.NewRouter()}
+// ServerOption configures the Server
+type ServerOption func(*Server)
+
+// WithAuthenticators sets how callers are identified, the first
+// authenticator recognising the credentials of a request wins. Without any,
+// every route requiring a role answers 401
+func WithAuthenticators(authenticators ...auth.Authenticator) ServerOption {
+	return func(s *Server) {
+		s.authenticators = append(s.authenticators, authenticators...)
+	}
+}
+
+// The roles guarding the routes. Catalog reads are public, price collectors
+// may update store prices and admins manage everything else
+var (
+	reader    = require(models.RoleReader)
+	collector = require(models.RolePriceCollector)
+	admin     = require(models.RoleAdmin)
+)
+
+// WithRateLimits limits how many requests each caller may make
+func WithRateLimits(limits RateLimits) ServerOption {
+	return func(s *Server) {
+		s.rateLimits = &limits
+	}
+}
+
+// legacyDeprecation is when the unprefixed routes were deprecated in favour
+// of /v1
+var legacyDeprecation = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+
+// WithLegacySunset sets when the unprefixed routes stop being served, it is
+// announced in their Sunset header. Defaults to six months after they were
+// deprecated
+func WithLegacySunset(sunset time.Time) ServerOption {
+	return func(s *Server) {
+		s.sunset = sunset
+	}
+}
+
+// NewServer creates the router with the middlewares every route shares
+func NewServer(opts ...ServerOption) *Server {
+	s := &Server{Router: chi.NewRouter(), v1: chi.NewRouter(), sunset: legacyDeprecation.AddDate(0, 6, 0)}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.Router.Use(requestID)
+	s.Router.Use(traceRequests)
+	if s.metrics != nil {
+		s.Router.Use(s.metrics.instrument)
+	}
+	s.Router.Use(logRequests)
+	s.Router.Use(cacheStatus)
+	s.Router.Use(authenticate(s.authenticators))
+	if s.rateLimits != nil {
+		s.Router.Use(rateLimit(*s.rateLimits))
+	}
+	s.Router.Mount("/v1", s.v1)
+	return s
 }
 
+// api registers the routes of the API under /v1 and again at the root, where
+// they are deprecated aliases kept for the clients predating /v1
+func (s *Server) api(routes func(r chi.Router)) {
+	routes(s.v1)
+	s.Router.Group(func(r chi.Router) {
+		r.Use(deprecated(s.sunset))
+		routes(r)
+	})
+}
+
+// deprecated announces that the route is going away and where it moved
+func deprecated(sunset time.Time) func(http.Handler) http.Handler {
+	deprecation := "@" + strconv.FormatInt(legacyDeprecation.Unix(), 10)
+	sunsetDate := sunset.UTC().Format(http.TimeFormat)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", deprecation)
+			w.Header().Set("Sunset", sunsetDate)
+			w.Header().Add("Link", `</v1`+r.URL.EscapedPath()+`>; rel="successor-version"`)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// MountHandlers registers the product routes. Lists come as JSON, NDJSON,
+// CSV or MessagePack, single products and comparisons as JSON or MessagePack
 func (s *Server) MountHandlers(productHandler *ProductHandler) {
-	s.Router.Use(middleware.Logger)
-	s.Router.Use(render.SetContentType(render.ContentTypeJSON))
+	list := offers(contentTypeJSON, contentTypeNDJSON, contentTypeCSV, contentTypeMsgPack)
+	single := offers(contentTypeJSON, contentTypeMsgPack)
 	s.Router.Get("/", rootHandler)
-	s.Router.Route("/products", func(r chi.Router) {
-		r.Get("/", productHandler.GetAllProducts)
-		r.Get("/{id}", productHandler.GetProductById)
-		r.Post("/", productHandler.CreateProduct)
-		r.Put("/{id}", productHandler.UpdateProduct)
-		r.Delete("/{id}", productHandler.DeleteProduct)
-		r.Patch("/{id}", productHandler.PatchProduct)
-		r.Patch("/{id}/store", productHandler.PatchStore)
+	s.api(func(r chi.Router) {
+		r.Route("/products", func(r chi.Router) {
+			r.With(list).Get("/", productHandler.GetAllProducts)
+			r.With(single).Get("/{id}", productHandler.GetProductById)
+			r.With(single).Get("/by-barcode/{code}", productHandler.GetProductByBarcode)
+			r.With(list).Get("/{id}/variants", productHandler.GetVariants)
+			r.With(single).Get("/{id}/comparison", productHandler.CompareVariants)
+			r.With(admin, single).Post("/", productHandler.CreateProduct)
+			r.With(admin, single).Put("/{id}", productHandler.UpdateProduct)
+			r.With(admin).Delete("/{id}", productHandler.DeleteProduct)
+			r.With(admin, single).Patch("/{id}", productHandler.PatchProduct)
+			r.With(collector, single).Patch("/{id}/store", productHandler.PatchStore)
+		})
+		r.With(single).Get("/brands/{id}/comparison", productHandler.CompareBrand)
+	})
+}
+
+func (s *Server) MountCategoryHandlers(categoryHandler *CategoryHandler) {
+	s.api(func(r chi.Router) {
+		r.Route("/categories", func(r chi.Router) {
+			r.Get("/", categoryHandler.GetAllCategories)
+			r.Get("/{id}", categoryHandler.GetCategoryById)
+			r.With(admin).Post("/", categoryHandler.CreateCategory)
+			r.With(admin).Put("/{id}", categoryHandler.UpdateCategory)
+			r.With(admin).Delete("/{id}", categoryHandler.DeleteCategory)
+		})
+		r.With(admin).Put("/products/{id}/categories", categoryHandler.SetProductCategories)
+	})
+}
+
+func (s *Server) MountTagHandlers(tagHandler *TagHandler) {
+	s.api(func(r chi.Router) {
+		r.Route("/tags", func(r chi.Router) {
+			r.Get("/", tagHandler.GetAllTags)
+			r.With(admin).Post("/", tagHandler.CreateTag)
+			r.With(admin).Put("/{id}", tagHandler.UpdateTag)
+			r.With(admin).Delete("/{id}", tagHandler.DeleteTag)
+		})
+		r.With(admin).Put("/products/{id}/tags", tagHandler.SetProductTags)
+	})
+}
+
+func (s *Server) MountBrandHandlers(brandHandler *BrandHandler) {
+	s.api(func(r chi.Router) {
+		r.Route("/brands", func(r chi.Router) {
+			r.Get("/", brandHandler.GetAllBrands)
+			r.Get("/{id}", brandHandler.GetBrandById)
+			r.With(admin).Post("/", brandHandler.CreateBrand)
+			r.With(admin).Put("/{id}", brandHandler.UpdateBrand)
+			r.With(admin).Delete("/{id}", brandHandler.DeleteBrand)
+		})
+	})
+}
+
+func (s *Server) MountExchangeRateHandlers(exchangeRateHandler *ExchangeRateHandler) {
+	s.api(func(r chi.Router) {
+		r.Route("/exchange-rates", func(r chi.Router) {
+			r.Get("/", exchangeRateHandler.GetAllExchangeRates)
+			r.With(admin).Post("/", exchangeRateHandler.CreateExchangeRate)
+			r.With(admin).Delete("/{id}", exchangeRateHandler.DeleteExchangeRate)
+		})
+	})
+}
+
+func (s *Server) MountPromotionHandlers(promotionHandler *PromotionHandler) {
+	s.api(func(r chi.Router) {
+		r.Route("/promotions", func(r chi.Router) {
+			r.Get("/", promotionHandler.GetPromotions)
+			r.Get("/{id}", promotionHandler.GetPromotionById)
+			r.With(admin).Post("/", promotionHandler.CreatePromotion)
+			r.With(admin).Put("/{id}", promotionHandler.UpdatePromotion)
+			r.With(admin).Delete("/{id}", promotionHandler.DeletePromotion)
+		})
+		r.Get("/products/{id}/promotions", promotionHandler.GetProductPromotions)
+	})
+}
+
+func (s *Server) MountWatchlistHandlers(watchlistHandler *WatchlistHandler) {
+	s.api(func(r chi.Router) {
+		r.Route("/watchlist", func(r chi.Router) {
+			r.Use(reader)
+			r.Get("/", watchlistHandler.GetWatches)
+			r.Get("/{id}", watchlistHandler.GetWatchById)
+			r.Post("/", watchlistHandler.CreateWatch)
+			r.Put("/{id}", watchlistHandler.UpdateWatch)
+			r.Delete("/{id}", watchlistHandler.DeleteWatch)
+		})
+		r.Route("/alerts", func(r chi.Router) {
+			r.Use(reader)
+			r.Get("/", watchlistHandler.GetAlerts)
+			r.Post("/{id}/read", watchlistHandler.MarkAlertRead)
+		})
 	})
 }
+
+func (s *Server) MountWebhookHandlers(webhookHandler *WebhookHandler) {
+	s.api(func(r chi.Router) {
+		r.Route("/webhooks", func(r chi.Router) {
+			r.Use(admin)
+			r.Get("/", webhookHandler.GetAllWebhooks)
+			r.Get("/{id}", webhookHandler.GetWebhookById)
+			r.Post("/", webhookHandler.CreateWebhook)
+			r.Put("/{id}", webhookHandler.UpdateWebhook)
+			r.Delete("/{id}", webhookHandler.DeleteWebhook)
+			r.Get("/{id}/deliveries", webhookHandler.GetDeliveries)
+			r.Post("/deliveries/{id}/replay", webhookHandler.ReplayDelivery)
+		})
+	})
+}
+
+func (s *Server) MountEventHandlers(eventHandler *EventHandler) {
+	s.api(func(r chi.Router) {
+		r.Get("/events", eventHandler.StreamEvents)
+	})
+}
+
+func (s *Server) MountAPIKeyHandlers(apiKeyHandler *APIKeyHandler) {
+	s.api(func(r chi.Router) {
+		r.Route("/api-keys", func(r chi.Router) {
+			r.Use(admin)
+			r.Get("/", apiKeyHandler.GetAllKeys)
+			r.Post("/", apiKeyHandler.IssueKey)
+			r.Delete("/{id}", apiKeyHandler.RevokeKey)
+		})
+	})
+}
+
+// MountGraphQLHandler serves handler at /graphql. Mutations check the caller
+// role themselves, the principal is in the request context
+func (s *Server) MountGraphQLHandler(handler http.Handler) {
+	s.Router.Method(http.MethodGet, "/graphql", handler)
+	s.Router.Method(http.MethodPost, "/graphql", handler)
+}
+
+// MountMetricsHandler serves the registry given to WithMetrics at /metrics
+func (s *Server) MountMetricsHandler() {
+	if s.metrics != nil {
+		s.Router.Method(http.MethodGet, "/metrics", s.metrics.registry.Handler())
+	}
+}
@@ -0,0 +1,49 @@
+package http
+
+import (
+	"crproductos/internal/cache"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestVersionedRoutes(t *testing.T) {
+	sunset := time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC)
+	s := NewServer(WithLegacySunset(sunset))
+	s.MountHandlers(NewProductHandler(&mockProductService{}))
+
+	response := executeRequest(httptest.NewRequest("GET", "/v1/products/2", nil), s)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	if response.Header().Get("Deprecation") != "" || response.Header().Get("Sunset") != "" {
+		t.Errorf("/v1 route should not be deprecated, got headers %v", response.Header())
+	}
+
+	response = executeRequest(httptest.NewRequest("GET", "/products/2", nil), s)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	for header, want := range map[string]string{
+		"Deprecation": "@1792368000",
+		"Sunset":      "Fri, 30 Apr 2027 00:00:00 GMT",
+		"Link":        `</v1/products/2>; rel="successor-version"`,
+	} {
+		if got := response.Header().Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestCacheStatusHeader(t *testing.T) {
+	s := NewServer()
+	s.Router.Get("/cached", func(w http.ResponseWriter, r *http.Request) {
+		cache.Record(r.Context(), true)
+		w.Write([]byte("[]"))
+	})
+	response := executeRequest(httptest.NewRequest("GET", "/cached", nil), s)
+	if response.Header().Get("X-Cache") != "HIT" {
+		t.Errorf("got X-Cache %q, want HIT", response.Header().Get("X-Cache"))
+	}
+	response = executeRequest(httptest.NewRequest("GET", "/v1/products/", nil), s)
+	if response.Header().Get("X-Cache") != "" {
+		t.Errorf("got X-Cache %q without cached reads", response.Header().Get("X-Cache"))
+	}
+}
@@ -0,0 +1,119 @@
+package http
+
+import (
+	"bytes"
+	"crproductos/internal/models"
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// streamBatch is how many products are priced and written at a time, the
+// promotions and exchange rates are looked up once per batch
+const streamBatch = 500
+
+// productStream: Writes products as they come, as a JSON array, as NDJSON
+// with one product per line or as CSV rows. Nothing is written before the
+// first batch so errors found until then can still be answered with a
+// status. MessagePack arrays start with their length, so those products are
+// buffered and only written on close
+type productStream struct {
+	w         http.ResponseWriter
+	mediaType string
+	encoder   *json.Encoder
+	csv       *models.ProductCSVWriter
+	buffered  bytes.Buffer
+	started   bool
+	count     int
+}
+
+func newProductStream(w http.ResponseWriter, mediaType string) *productStream {
+	s := &productStream{w: w, mediaType: mediaType}
+	switch mediaType {
+	case contentTypeCSV:
+		s.csv = models.NewProductCSVWriter(w)
+	case contentTypeNDJSON, contentTypeJSON:
+		s.encoder = json.NewEncoder(w)
+	}
+	return s
+}
+
+func (s *productStream) start() error {
+	s.started = true
+	s.w.Header().Set("Content-Type", s.mediaType)
+	if s.mediaType != contentTypeJSON {
+		return nil
+	}
+	_, err := io.WriteString(s.w, "[")
+	return err
+}
+
+// write writes a batch of products and flushes it to the client
+func (s *productStream) write(products []models.ProductResponse) error {
+	if s.mediaType == contentTypeMsgPack {
+		encoder := newMsgPackEncoder(&s.buffered)
+		for _, product := range products {
+			if err := encoder.Encode(product); err != nil {
+				return err
+			}
+			s.count++
+		}
+		return nil
+	}
+	if !s.started {
+		if err := s.start(); err != nil {
+			return err
+		}
+	}
+	for _, product := range products {
+		if err := s.writeProduct(product); err != nil {
+			return err
+		}
+		s.count++
+	}
+	if s.csv != nil {
+		if err := s.csv.Flush(); err != nil {
+			return err
+		}
+	}
+	http.NewResponseController(s.w).Flush()
+	return nil
+}
+
+func (s *productStream) writeProduct(product models.ProductResponse) error {
+	switch s.mediaType {
+	case contentTypeCSV:
+		return s.csv.Write(product)
+	case contentTypeJSON:
+		if s.count > 0 {
+			if _, err := io.WriteString(s.w, ","); err != nil {
+				return err
+			}
+		}
+	}
+	return s.encoder.Encode(product)
+}
+
+// close ends the list, an empty one is written as [], a CSV header alone or
+// nothing at all in NDJSON
+func (s *productStream) close() error {
+	if !s.started {
+		if err := s.start(); err != nil {
+			return err
+		}
+	}
+	switch s.mediaType {
+	case contentTypeCSV:
+		return s.csv.Flush()
+	case contentTypeMsgPack:
+		if err := newMsgPackEncoder(s.w).EncodeArrayLen(s.count); err != nil {
+			return err
+		}
+		_, err := s.buffered.WriteTo(s.w)
+		return err
+	case contentTypeJSON:
+		_, err := io.WriteString(s.w, "]\n")
+		return err
+	}
+	return nil
+}
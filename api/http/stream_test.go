@@ -0,0 +1,89 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"crproductos/internal/models"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// largeCatalog scans count products, failing after failAfter of them when set
+type largeCatalog struct {
+	mockProductService
+	count     int
+	failAfter int
+}
+
+func (c largeCatalog) ScanProducts(ctx context.Context, filter models.ProductFilter, fn func(models.ProductResponse) error) error {
+	for i := 1; i <= c.count; i++ {
+		if c.failAfter > 0 && i > c.failAfter {
+			return errors.New("connection reset")
+		}
+		name := "producto"
+		if err := fn(models.ProductResponse{Id: i, Name: &name, Stores: &models.Stores{"pali": float64(i)}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestStreamProducts(t *testing.T) {
+	s := NewServer()
+	s.MountHandlers(NewProductHandler(largeCatalog{count: 2*streamBatch + 3}))
+
+	response := executeRequest(httptest.NewRequest("GET", "/v1/products", nil), s)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var products []models.ProductResponse
+	if err := json.Unmarshal(response.Body.Bytes(), &products); err != nil {
+		t.Fatal(err)
+	}
+	if len(products) != 2*streamBatch+3 || products[streamBatch].Id != streamBatch+1 {
+		t.Errorf("got %d products", len(products))
+	}
+
+	req := httptest.NewRequest("GET", "/v1/products", nil)
+	req.Header.Set("Accept", "application/json;q=0.5, application/x-ndjson")
+	response = executeRequest(req, s)
+	if response.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Errorf("got Content-Type %q", response.Header().Get("Content-Type"))
+	}
+	lines := 0
+	scanner := bufio.NewScanner(response.Body)
+	for scanner.Scan() {
+		var product models.ProductResponse
+		if err := json.Unmarshal(scanner.Bytes(), &product); err != nil || product.Id != lines+1 {
+			t.Fatalf("line %d: %s %v", lines+1, scanner.Text(), err)
+		}
+		lines++
+	}
+	if lines != 2*streamBatch+3 {
+		t.Errorf("got %d lines", lines)
+	}
+}
+
+func TestStreamProductsFailures(t *testing.T) {
+	s := NewServer()
+	s.MountHandlers(NewProductHandler(largeCatalog{}))
+	response := executeRequest(httptest.NewRequest("GET", "/v1/products", nil), s)
+	if strings.TrimSpace(response.Body.String()) != "[]" {
+		t.Errorf("empty list should be [], got %q", response.Body.String())
+	}
+
+	s = NewServer()
+	s.MountHandlers(NewProductHandler(largeCatalog{count: 10, failAfter: 5}))
+	checkResponseCode(t, http.StatusInternalServerError, executeRequest(httptest.NewRequest("GET", "/v1/products", nil), s).Code)
+
+	// once a batch went out the response can only be cut short
+	s = NewServer()
+	s.MountHandlers(NewProductHandler(largeCatalog{count: streamBatch + 10, failAfter: streamBatch + 5}))
+	response = executeRequest(httptest.NewRequest("GET", "/v1/products", nil), s)
+	var products []models.ProductResponse
+	if response.Code != http.StatusOK || json.Unmarshal(response.Body.Bytes(), &products) == nil {
+		t.Errorf("expected a truncated array, got %d", response.Code)
+	}
+}
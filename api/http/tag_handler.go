@@ -0,0 +1,81 @@
+package http
+
+import (
+	"crproductos/internal/models"
+	"crproductos/internal/service"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+)
+
+type TagHandler struct {
+	service service.TagService
+}
+
+func NewTagHandler(svc service.TagService) *TagHandler {
+	return &TagHandler{service: svc}
+}
+func (h *TagHandler) GetAllTags(w http.ResponseWriter, r *http.Request) {
+	tags, err := h.service.GetAllTags()
+	if err != nil {
+		http.Error(w, "Failed getting all tags", http.StatusInternalServerError)
+		return
+	}
+	render.JSON(w, r, tags)
+}
+func (h *TagHandler) CreateTag(w http.ResponseWriter, r *http.Request) {
+	var tag models.Tag
+	if err := json.NewDecoder(r.Body).Decode(&tag); err != nil {
+		slog.WarnContext(r.Context(), "invalid request body", "err", err)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	tag, err := h.service.CreateTag(tag)
+	if err != nil {
+		http.Error(w, "Failed creating tag", errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	render.JSON(w, r, tag)
+}
+func (h *TagHandler) UpdateTag(w http.ResponseWriter, r *http.Request) {
+	var id = chi.URLParam(r, "id")
+	var tag models.Tag
+	if err := json.NewDecoder(r.Body).Decode(&tag); err != nil {
+		slog.WarnContext(r.Context(), "invalid request body", "err", err)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	tag, err := h.service.UpdateTag(id, tag)
+	if err != nil {
+		http.Error(w, "Failed updating tag", errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	render.JSON(w, r, tag)
+}
+func (h *TagHandler) DeleteTag(w http.ResponseWriter, r *http.Request) {
+	var id = chi.URLParam(r, "id")
+	if err := h.service.DeleteTag(id); err != nil {
+		http.Error(w, "Failed deleting tag", errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	w.Write([]byte("Delete successful"))
+}
+
+// SetProductTags replaces the tags of a product with the names sent in the
+// body, e.g. ["bebidas", "sin azucar"]. Unknown tags are created
+func (h *TagHandler) SetProductTags(w http.ResponseWriter, r *http.Request) {
+	var id = chi.URLParam(r, "id")
+	var tags []string
+	if err := json.NewDecoder(r.Body).Decode(&tags); err != nil {
+		slog.WarnContext(r.Context(), "invalid request body", "err", err)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	if err := h.service.SetProductTags(id, tags); err != nil {
+		http.Error(w, "Failed assigning tags", errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	render.JSON(w, r, tags)
+}
@@ -0,0 +1,50 @@
+package http
+
+import (
+	"crproductos/internal/trace"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"net/http"
+)
+
+// traceRequests: Starts a server span per request, continuing the trace of
+// an incoming traceparent header. The span is renamed after the route pattern
+// once chi matched it, and the traceparent of the span is sent back so
+// callers can find the trace
+func traceRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if parent, err := trace.ParseTraceparent(r.Header.Get("traceparent")); err == nil {
+			ctx = trace.ContextWithRemote(ctx, parent)
+		}
+		ctx, span := trace.Start(ctx, r.Method, trace.KindServer)
+		if span == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		defer span.End()
+		w.Header().Set("traceparent", span.Context().Traceparent())
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r.WithContext(ctx))
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			span.SetName(r.Method + " " + rctx.RoutePattern())
+			span.SetAttribute("http.route", rctx.RoutePattern())
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		span.SetAttribute("http.method", r.Method)
+		span.SetAttribute("http.target", r.URL.RequestURI())
+		span.SetAttribute("http.status_code", status)
+		if status >= 500 {
+			span.RecordError(errStatus(status))
+		}
+	})
+}
+
+type errStatus int
+
+func (e errStatus) Error() string {
+	return http.StatusText(int(e))
+}
@@ -0,0 +1,49 @@
+package http
+
+import (
+	"crproductos/internal/service"
+	"crproductos/internal/trace"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+type spanRecorder struct {
+	mu    sync.Mutex
+	spans []trace.SpanData
+}
+
+func (r *spanRecorder) Export(span trace.SpanData) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, span)
+}
+
+func TestTraceRequests(t *testing.T) {
+	rec := &spanRecorder{}
+	trace.SetTracer(trace.NewTracer(rec))
+	defer trace.SetTracer(nil)
+
+	s := NewServer()
+	s.MountHandlers(NewProductHandler(service.NewTracedProductService(&mockProductService{})))
+	req := httptest.NewRequest("GET", "/products/2", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	response := executeRequest(req, s)
+
+	if len(rec.spans) != 2 {
+		t.Fatalf("exported %d spans, want 2", len(rec.spans))
+	}
+	serviceSpan, serverSpan := rec.spans[0], rec.spans[1]
+	if serverSpan.Name != "GET /products/{id}" || serverSpan.Attributes["http.status_code"] != 200 {
+		t.Errorf("unexpected server span %+v", serverSpan)
+	}
+	if serverSpan.Context.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || serverSpan.Parent.String() != "00f067aa0ba902b7" {
+		t.Errorf("server span does not continue the incoming trace: %+v", serverSpan)
+	}
+	if serviceSpan.Name != "ProductService.GetProductById" || serviceSpan.Parent != serverSpan.Context.SpanID {
+		t.Errorf("unexpected service span %+v", serviceSpan)
+	}
+	if got := response.Header().Get("traceparent"); got != serverSpan.Context.Traceparent() {
+		t.Errorf("traceparent response header = %q", got)
+	}
+}
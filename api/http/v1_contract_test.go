@@ -0,0 +1,108 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crproductos/internal/models"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of the /v1 contract")
+
+// fullCatalog returns products with every field set, so a field renamed,
+// removed or added to the models shows up in the /v1 golden files
+type fullCatalog struct {
+	mockProductService
+}
+
+func fullProduct() models.ProductResponse {
+	name, unit := "Leche entera", "litros"
+	quantity, brand, parent := 1.0, 3, 7
+	starts := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	ends := starts.AddDate(0, 1, 0)
+	return models.ProductResponse{
+		Id: 8, Name: &name, Quantity: &quantity, Unit: &unit, BrandId: &brand, ParentId: &parent,
+		Stores:          &models.Stores{"pali": 990, "amazon": 2},
+		Currencies:      &models.StoreCurrencies{"amazon": "USD"},
+		Barcodes:        models.Barcodes{"7441001603217"},
+		Categories:      models.Categories{{Id: 1, Name: "Lácteos", ParentId: &parent}},
+		Tags:            models.Tags{"sin-lactosa"},
+		EffectivePrices: &models.Stores{"pali": 792, "amazon": 2},
+		Promotions: []models.Promotion{{Id: 5, ProductId: 8, Store: "pali", Type: models.PromotionPercentage, Value: 20,
+			Buy: 2, Pay: 1, StartsAt: starts, EndsAt: &ends, Description: "20% off",
+			Conditions: models.PromotionConditions{MinQuantity: 1, MaxPerCustomer: 6, MembersOnly: true}}},
+		Conversion: &models.Conversion{Currency: "CRC", Rates: []models.ExchangeRate{
+			{Id: 2, Base: "USD", Quote: "CRC", Rate: 505.5, EffectiveDate: models.Date{Time: starts}}}},
+	}
+}
+
+func (c fullCatalog) ScanProducts(ctx context.Context, filter models.ProductFilter, fn func(models.ProductResponse) error) error {
+	return fn(fullProduct())
+}
+
+func (c fullCatalog) GetProductById(ctx context.Context, id string) (models.Product, error) {
+	parent := int64(7)
+	return models.Product{
+		Id:         8,
+		Name:       sql.NullString{String: "Leche entera", Valid: true},
+		Quantity:   sql.NullFloat64{Float64: 1, Valid: true},
+		Unit:       sql.NullString{String: "litros", Valid: true},
+		BrandId:    sql.NullInt64{Int64: 3, Valid: true},
+		ParentId:   sql.NullInt64{Int64: parent, Valid: true},
+		Stores:     &models.Stores{"pali": 990},
+		Currencies: &models.StoreCurrencies{"pali": "CRC"},
+		Barcodes:   models.Barcodes{"7441001603217"},
+		Categories: models.Categories{{Id: 1, Name: "Lácteos"}},
+		Tags:       models.Tags{"sin-lactosa"},
+	}, nil
+}
+
+func (c fullCatalog) CompareProducts(ctx context.Context, filter models.ProductFilter) ([]models.VariantComparison, error) {
+	product := fullProduct()
+	product.Currencies = nil
+	comparisons := models.CompareVariants([]models.ProductResponse{product})
+	comparisons[0].EffectiveUnitPrices = models.Stores{"pali": 792}
+	return comparisons, nil
+}
+
+// TestV1Contract: Freezes the JSON shape of the /v1 product responses. A
+// failure means the stable contract changed, bump the API version or, for
+// additive changes, rerun with -update and review the golden files
+func TestV1Contract(t *testing.T) {
+	s := NewServer()
+	s.MountHandlers(NewProductHandler(fullCatalog{}))
+	for golden, path := range map[string]string{
+		"v1_products.json":   "/v1/products",
+		"v1_product.json":    "/v1/products/8",
+		"v1_comparison.json": "/v1/products/7/comparison",
+	} {
+		response := executeRequest(httptest.NewRequest("GET", path, nil), s)
+		checkResponseCode(t, http.StatusOK, response.Code)
+		var got bytes.Buffer
+		if err := json.Indent(&got, response.Body.Bytes(), "", "  "); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		file := filepath.Join("testdata", golden)
+		if *update {
+			if err := os.WriteFile(file, got.Bytes(), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		want, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(bytes.TrimSpace(got.Bytes()), bytes.TrimSpace(want)) {
+			t.Errorf("%s no longer matches %s:\n%s", path, file, got.String())
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package http
+
+import (
+	"crproductos/internal/auth"
+	"crproductos/internal/models"
+	"crproductos/internal/service"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+type WatchlistHandler struct {
+	service service.WatchlistService
+}
+
+func NewWatchlistHandler(svc service.WatchlistService) *WatchlistHandler {
+	return &WatchlistHandler{service: svc}
+}
+
+// subscriberOf: Returns the subscriber the caller acts as, the subject it
+// authenticated with. Admins act for every subscriber, they get "" and may
+// name the subscriber in the request instead
+func subscriberOf(r *http.Request) string {
+	principal, _ := auth.FromContext(r.Context())
+	if principal.Role.Allows(models.RoleAdmin) {
+		return ""
+	}
+	return principal.Subject
+}
+
+// GetWatches lists the caller's watches, narrowed by ?product=. Admins see
+// every watch, narrowed by ?subscriber= as well
+func (h *WatchlistHandler) GetWatches(w http.ResponseWriter, r *http.Request) {
+	filter := models.WatchFilter{Subscriber: r.URL.Query().Get("subscriber")}
+	if subscriber := subscriberOf(r); subscriber != "" {
+		filter.Subscriber = subscriber
+	}
+	if product := r.URL.Query().Get("product"); product != "" {
+		id, err := strconv.Atoi(product)
+		if err != nil {
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+		filter.ProductId = &id
+	}
+	watches, err := h.service.GetWatches(filter)
+	if err != nil {
+		http.Error(w, "Failed getting watches", http.StatusInternalServerError)
+		return
+	}
+	render.JSON(w, r, watches)
+}
+func (h *WatchlistHandler) GetWatchById(w http.ResponseWriter, r *http.Request) {
+	var id = chi.URLParam(r, "id")
+	watch, err := h.service.GetWatchById(id, subscriberOf(r))
+	if err != nil {
+		http.Error(w, "Failed getting watch", errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	render.JSON(w, r, watch)
+}
+func (h *WatchlistHandler) CreateWatch(w http.ResponseWriter, r *http.Request) {
+	var watch models.Watch
+	if err := json.NewDecoder(r.Body).Decode(&watch); err != nil {
+		slog.WarnContext(r.Context(), "invalid request body", "err", err)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	if subscriber := subscriberOf(r); subscriber != "" {
+		watch.Subscriber = subscriber
+	}
+	watch, err := h.service.CreateWatch(watch)
+	if err != nil {
+		http.Error(w, "Failed creating watch", errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	render.JSON(w, r, watch)
+}
+func (h *WatchlistHandler) UpdateWatch(w http.ResponseWriter, r *http.Request) {
+	var id = chi.URLParam(r, "id")
+	var watch models.Watch
+	if err := json.NewDecoder(r.Body).Decode(&watch); err != nil {
+		slog.WarnContext(r.Context(), "invalid request body", "err", err)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	subscriber := subscriberOf(r)
+	if subscriber != "" {
+		watch.Subscriber = subscriber
+	}
+	watch, err := h.service.UpdateWatch(id, subscriber, watch)
+	if err != nil {
+		http.Error(w, "Failed updating watch", errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	render.JSON(w, r, watch)
+}
+func (h *WatchlistHandler) DeleteWatch(w http.ResponseWriter, r *http.Request) {
+	var id = chi.URLParam(r, "id")
+	if err := h.service.DeleteWatch(id, subscriberOf(r)); err != nil {
+		http.Error(w, "Failed deleting watch", errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	w.Write([]byte("Delete successful"))
+}
+
+// GetAlerts is the caller's alert inbox, narrowed by ?unread=true. Admins
+// see every alert, narrowed by ?subscriber= as well
+func (h *WatchlistHandler) GetAlerts(w http.ResponseWriter, r *http.Request) {
+	filter := models.AlertFilter{
+		Subscriber: r.URL.Query().Get("subscriber"),
+		UnreadOnly: r.URL.Query().Get("unread") == "true",
+	}
+	if subscriber := subscriberOf(r); subscriber != "" {
+		filter.Subscriber = subscriber
+	}
+	alerts, err := h.service.GetAlerts(filter)
+	if err != nil {
+		http.Error(w, "Failed getting alerts", http.StatusInternalServerError)
+		return
+	}
+	render.JSON(w, r, alerts)
+}
+func (h *WatchlistHandler) MarkAlertRead(w http.ResponseWriter, r *http.Request) {
+	var id = chi.URLParam(r, "id")
+	alert, err := h.service.MarkAlertRead(id, subscriberOf(r))
+	if err != nil {
+		http.Error(w, "Failed marking alert as read", errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	render.JSON(w, r, alert)
+}
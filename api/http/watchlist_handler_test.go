@@ -0,0 +1,145 @@
+package http
+
+import (
+	"crproductos/internal/auth"
+	"crproductos/internal/models"
+	"crproductos/internal/service"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// subjectHeader authenticates X-Subject as a reader, or as an admin when the
+// subject is "admin"
+type subjectHeader struct{}
+
+func (subjectHeader) Authenticate(r *http.Request) (auth.Principal, error) {
+	subject := r.Header.Get("X-Subject")
+	switch subject {
+	case "":
+		return auth.Principal{}, auth.ErrNoCredentials
+	case "admin":
+		return auth.Principal{Subject: subject, Role: models.RoleAdmin}, nil
+	}
+	return auth.Principal{Subject: subject, Role: models.RoleReader}, nil
+}
+
+// memoryWatchlist scopes watches and alerts by subscriber like the watchlist
+// repository
+type memoryWatchlist struct {
+	watches []models.Watch
+	alerts  []models.Alert
+}
+
+func (m *memoryWatchlist) GetWatches(filter models.WatchFilter) ([]models.Watch, error) {
+	watches := []models.Watch{}
+	for _, watch := range m.watches {
+		if filter.Subscriber == "" || watch.Subscriber == filter.Subscriber {
+			watches = append(watches, watch)
+		}
+	}
+	return watches, nil
+}
+func (m *memoryWatchlist) find(id string, subscriber string) (int, error) {
+	for i, watch := range m.watches {
+		if strconv.Itoa(watch.Id) == id && (subscriber == "" || watch.Subscriber == subscriber) {
+			return i, nil
+		}
+	}
+	return 0, sql.ErrNoRows
+}
+func (m *memoryWatchlist) GetWatchById(id string, subscriber string) (models.Watch, error) {
+	i, err := m.find(id, subscriber)
+	if err != nil {
+		return models.Watch{}, err
+	}
+	return m.watches[i], nil
+}
+func (m *memoryWatchlist) CreateWatch(watch models.Watch) (models.Watch, error) {
+	watch.Id = len(m.watches) + 1
+	m.watches = append(m.watches, watch)
+	return watch, nil
+}
+func (m *memoryWatchlist) UpdateWatch(id string, subscriber string, watch models.Watch) (models.Watch, error) {
+	i, err := m.find(id, subscriber)
+	if err != nil {
+		return watch, err
+	}
+	watch.Id = m.watches[i].Id
+	m.watches[i] = watch
+	return watch, nil
+}
+func (m *memoryWatchlist) DeleteWatch(id string, subscriber string) error {
+	i, err := m.find(id, subscriber)
+	if err != nil {
+		return err
+	}
+	m.watches = append(m.watches[:i], m.watches[i+1:]...)
+	return nil
+}
+func (m *memoryWatchlist) GetAlerts(filter models.AlertFilter) ([]models.Alert, error) {
+	alerts := []models.Alert{}
+	for _, alert := range m.alerts {
+		if filter.Subscriber == "" || alert.Subscriber == filter.Subscriber {
+			alerts = append(alerts, alert)
+		}
+	}
+	return alerts, nil
+}
+func (m *memoryWatchlist) CreateAlert(alert models.Alert) (models.Alert, error) {
+	return alert, nil
+}
+func (m *memoryWatchlist) MarkAlertRead(id string, subscriber string) (models.Alert, error) {
+	for _, alert := range m.alerts {
+		if strconv.Itoa(alert.Id) == id && (subscriber == "" || alert.Subscriber == subscriber) {
+			return alert, nil
+		}
+	}
+	return models.Alert{}, sql.ErrNoRows
+}
+
+func TestWatchlistIsScopedToTheCaller(t *testing.T) {
+	repo := &memoryWatchlist{
+		watches: []models.Watch{{Id: 1, Subscriber: "ana", ProductId: 1, TargetPrice: 900}},
+		alerts:  []models.Alert{{Id: 1, WatchId: 1, Subscriber: "ana", ProductId: 1}},
+	}
+	s := NewServer(WithAuthenticators(subjectHeader{}))
+	s.MountWatchlistHandlers(NewWatchlistHandler(service.NewWatchlistService(repo)))
+	as := func(subject, method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-Subject", subject)
+		return executeRequest(req, s)
+	}
+
+	for _, response := range []*httptest.ResponseRecorder{
+		as("luis", "GET", "/v1/watchlist/1", ""),
+		as("luis", "PUT", "/v1/watchlist/1", `{"subscriber":"ana","productId":1,"targetPrice":1}`),
+		as("luis", "DELETE", "/v1/watchlist/1", ""),
+		as("luis", "POST", "/v1/alerts/1/read", ""),
+	} {
+		checkResponseCode(t, http.StatusNotFound, response.Code)
+	}
+	for _, path := range []string{"/v1/watchlist?subscriber=ana", "/v1/alerts?subscriber=ana"} {
+		if body := as("luis", "GET", path, "").Body.String(); strings.TrimSpace(body) != "[]" {
+			t.Errorf("%s showed another subscriber's data: %s", path, body)
+		}
+	}
+
+	response := as("luis", "POST", "/v1/watchlist", `{"subscriber":"ana","productId":1,"targetPrice":800}`)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var created models.Watch
+	if err := json.NewDecoder(response.Body).Decode(&created); err != nil || created.Subscriber != "luis" {
+		t.Errorf("expected the watch to belong to the caller, got %+v, %v", created, err)
+	}
+
+	checkResponseCode(t, http.StatusOK, as("ana", "GET", "/v1/watchlist/1", "").Code)
+	checkResponseCode(t, http.StatusOK, as("admin", "POST", "/v1/alerts/1/read", "").Code)
+	var watches []models.Watch
+	if err := json.NewDecoder(as("admin", "GET", "/v1/watchlist", "").Body).Decode(&watches); err != nil || len(watches) != 2 {
+		t.Errorf("expected admins to see every watch, got %+v, %v", watches, err)
+	}
+}
@@ -0,0 +1,100 @@
+package http
+
+import (
+	"crproductos/internal/models"
+	"crproductos/internal/service"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+)
+
+type WebhookHandler struct {
+	service service.WebhookService
+}
+
+func NewWebhookHandler(svc service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{service: svc}
+}
+
+func (h *WebhookHandler) GetAllWebhooks(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := h.service.GetAllWebhooks()
+	if err != nil {
+		http.Error(w, "Failed getting all webhooks", http.StatusInternalServerError)
+		return
+	}
+	render.JSON(w, r, webhooks)
+}
+func (h *WebhookHandler) GetWebhookById(w http.ResponseWriter, r *http.Request) {
+	var id = chi.URLParam(r, "id")
+	webhook, err := h.service.GetWebhookById(id)
+	if err != nil {
+		http.Error(w, "Failed getting webhook", errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	render.JSON(w, r, webhook)
+}
+
+// CreateWebhook registers a webhook, the response carries the signing secret
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var webhook models.Webhook
+	if err := json.NewDecoder(r.Body).Decode(&webhook); err != nil {
+		slog.WarnContext(r.Context(), "invalid request body", "err", err)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	webhook, err := h.service.CreateWebhook(webhook)
+	if err != nil {
+		http.Error(w, "Failed creating webhook", errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, webhook)
+}
+func (h *WebhookHandler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	var id = chi.URLParam(r, "id")
+	var webhook models.Webhook
+	if err := json.NewDecoder(r.Body).Decode(&webhook); err != nil {
+		slog.WarnContext(r.Context(), "invalid request body", "err", err)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	webhook, err := h.service.UpdateWebhook(id, webhook)
+	if err != nil {
+		http.Error(w, "Failed updating webhook", errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	render.JSON(w, r, webhook)
+}
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	var id = chi.URLParam(r, "id")
+	if err := h.service.DeleteWebhook(id); err != nil {
+		http.Error(w, "Failed deleting webhook", errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	w.Write([]byte("Delete successful"))
+}
+
+// GetDeliveries is the delivery log of the webhook, one entry per attempt
+func (h *WebhookHandler) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	var id = chi.URLParam(r, "id")
+	deliveries, err := h.service.GetDeliveries(id)
+	if err != nil {
+		http.Error(w, "Failed getting deliveries", errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	render.JSON(w, r, deliveries)
+}
+
+// ReplayDelivery queues a logged delivery again and answers 202 right away
+func (h *WebhookHandler) ReplayDelivery(w http.ResponseWriter, r *http.Request) {
+	var id = chi.URLParam(r, "id")
+	delivery, err := h.service.ReplayDelivery(id)
+	if err != nil {
+		http.Error(w, "Failed replaying delivery", errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	render.Status(r, http.StatusAccepted)
+	render.JSON(w, r, delivery)
+}
@@ -0,0 +1,129 @@
+package http
+
+import (
+	"crproductos/internal/auth"
+	"crproductos/internal/events"
+	"crproductos/internal/models"
+	"crproductos/internal/service"
+	"crproductos/internal/webhook"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// memoryWebhooks keeps webhooks like the webhook table, only active ones are
+// handed to the dispatcher
+type memoryWebhooks struct {
+	mu       sync.Mutex
+	webhooks []models.Webhook
+	pending  []models.PendingDelivery
+}
+
+func (m *memoryWebhooks) GetAllWebhooks() ([]models.Webhook, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]models.Webhook{}, m.webhooks...), nil
+}
+func (m *memoryWebhooks) GetWebhookById(id string) (models.Webhook, error) {
+	webhooks, _ := m.GetAllWebhooks()
+	for _, webhook := range webhooks {
+		if strconv.Itoa(webhook.Id) == id {
+			return webhook, nil
+		}
+	}
+	return models.Webhook{}, sql.ErrNoRows
+}
+func (m *memoryWebhooks) CreateWebhook(webhook models.Webhook) (models.Webhook, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	webhook.Id = len(m.webhooks) + 1
+	m.webhooks = append(m.webhooks, webhook)
+	return webhook, nil
+}
+func (m *memoryWebhooks) UpdateWebhook(id string, webhook models.Webhook) (models.Webhook, error) {
+	return webhook, nil
+}
+func (m *memoryWebhooks) DeleteWebhook(id string) error {
+	return nil
+}
+func (m *memoryWebhooks) GetActiveWebhooks() ([]models.Webhook, error) {
+	webhooks, _ := m.GetAllWebhooks()
+	active := []models.Webhook{}
+	for _, webhook := range webhooks {
+		if webhook.Active != nil && *webhook.Active {
+			active = append(active, webhook)
+		}
+	}
+	return active, nil
+}
+func (m *memoryWebhooks) CreateDelivery(delivery models.WebhookDelivery) (models.WebhookDelivery, error) {
+	return delivery, nil
+}
+func (m *memoryWebhooks) GetDeliveries(webhookId string) ([]models.WebhookDelivery, error) {
+	return nil, nil
+}
+func (m *memoryWebhooks) GetDeliveryById(id string) (models.WebhookDelivery, error) {
+	return models.WebhookDelivery{}, sql.ErrNoRows
+}
+func (m *memoryWebhooks) QueueDelivery(pending models.PendingDelivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pending = append(m.pending, pending)
+	return nil
+}
+func (m *memoryWebhooks) ClaimDueDeliveries(limit int, lease time.Duration) ([]models.PendingDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	claimed := m.pending
+	m.pending = nil
+	return claimed, nil
+}
+func (m *memoryWebhooks) RescheduleDelivery(id int64, attempt int, at time.Time) error {
+	return nil
+}
+func (m *memoryWebhooks) DeletePendingDelivery(id int64) error {
+	return nil
+}
+
+func TestCreatedWebhookIsActive(t *testing.T) {
+	var received atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Add(1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	repo := &memoryWebhooks{}
+	dispatcher := webhook.NewDispatcher(repo, webhook.WithRetries(1, time.Millisecond, time.Millisecond))
+	s := NewServer(WithAuthenticators(auth.StaticKey("bootstrap", models.RoleAdmin)))
+	s.MountWebhookHandlers(NewWebhookHandler(service.NewWebhookService(repo, dispatcher)))
+
+	req := httptest.NewRequest("POST", "/v1/webhooks", strings.NewReader(`{"url":"`+receiver.URL+`"}`))
+	req.Header.Set("X-API-Key", "bootstrap")
+	response := executeRequest(req, s)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var created models.Webhook
+	if err := json.NewDecoder(response.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+	if created.Active == nil || !*created.Active {
+		t.Errorf("Expected a webhook created without active to be active, got %+v", created)
+	}
+
+	if err := dispatcher.Publish(events.Event{Id: "1", Type: events.PriceChanged}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dispatcher.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if received.Load() != 1 {
+		t.Errorf("Expected the new webhook to receive the event, got %d deliveries", received.Load())
+	}
+}
@@ -0,0 +1,204 @@
+// Package client calls the CRProductos HTTP API from other Go services
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// apiPrefix is the version of the API the client speaks
+const apiPrefix = "/v1"
+
+// Client talks to a single CRProductos server, it is safe for concurrent use
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	apiKey     string
+	userAgent  string
+	retries    int
+	backoff    time.Duration
+}
+
+// Option configures the Client
+type Option func(*Client)
+
+// WithHTTPClient replaces http.DefaultClient, to set timeouts or transports
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithAPIKey sends key as a bearer token, it may be an API key or a JWT
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+// WithUserAgent identifies the calling service in the server logs
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithRetries sets how many times a failed idempotent request is tried
+// again, waiting backoff before the first retry and doubling it after that.
+// A Retry-After header sent by the server takes precedence over backoff
+func WithRetries(retries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.retries = retries
+		c.backoff = backoff
+	}
+}
+
+// New creates a client for the server at baseURL, e.g. "https://api.example.com".
+// By default failed idempotent requests are retried twice
+func New(baseURL string, opts ...Option) (*Client, error) {
+	parsed, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, err
+	}
+	if parsed.Scheme == "" || parsed.Host == "" {
+		return nil, fmt.Errorf("client: base URL %q needs a scheme and host", baseURL)
+	}
+	c := &Client{
+		baseURL:    parsed,
+		httpClient: http.DefaultClient,
+		userAgent:  "crproductos-go-client",
+		retries:    2,
+		backoff:    200 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// Error is returned when the server answers with a non 2xx status
+type Error struct {
+	StatusCode int
+	// Message is the plain text body the server sent
+	Message string
+	// RetryAfter is set on 429 responses
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("crproductos: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("crproductos: %d %s", e.StatusCode, e.Message)
+}
+
+// decodeError reads the error body of response
+func decodeError(response *http.Response) *Error {
+	body, _ := io.ReadAll(io.LimitReader(response.Body, 4096))
+	apiErr := &Error{StatusCode: response.StatusCode, Message: strings.TrimSpace(string(body))}
+	if seconds, err := strconv.Atoi(response.Header.Get("Retry-After")); err == nil {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return apiErr
+}
+
+// idempotent reports whether a request may be sent again without changing
+// the outcome
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// retryable reports whether a request that failed with status may succeed later
+func retryable(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// do sends the request, retrying idempotent ones, and decodes the JSON
+// response into out when it is not nil
+func (c *Client) do(ctx context.Context, method string, path string, query url.Values, in interface{}, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+	target := *c.baseURL
+	target.Path += apiPrefix + path
+	target.RawQuery = query.Encode()
+
+	attempts := 1
+	if idempotent(method) {
+		attempts += c.retries
+	}
+	wait := c.backoff
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			if apiErr, ok := lastErr.(*Error); ok && apiErr.RetryAfter > 0 {
+				wait = apiErr.RetryAfter
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(wait):
+			}
+			wait *= 2
+		}
+		var retry bool
+		retry, lastErr = c.send(ctx, method, target.String(), body, out)
+		if !retry {
+			return lastErr
+		}
+	}
+	return lastErr
+}
+
+// send makes a single attempt, the returned flag tells whether it is worth
+// trying again
+func (c *Client) send(ctx context.Context, method string, target string, body []byte, out interface{}) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	response, err := c.httpClient.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return retryable(response.StatusCode), decodeError(response)
+	}
+	if out == nil {
+		io.Copy(io.Discard, response.Body)
+		return false, nil
+	}
+	if err := json.NewDecoder(response.Body).Decode(out); err != nil {
+		return false, fmt.Errorf("client: decoding %s %s: %w", method, req.URL.Path, err)
+	}
+	return false, nil
+}
@@ -0,0 +1,341 @@
+package client
+
+import (
+	"context"
+	apiHttp "crproductos/api/http"
+	"crproductos/internal/auth"
+	"crproductos/internal/models"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const adminKey = "crp_test_admin"
+
+// memoryProducts is a ProductService keeping products in a map
+type memoryProducts struct {
+	mu       sync.Mutex
+	nextId   int
+	products map[int]models.ProductResponse
+}
+
+func newMemoryProducts() *memoryProducts {
+	return &memoryProducts{nextId: 1, products: map[int]models.ProductResponse{}}
+}
+
+func toProduct(p models.ProductResponse) models.Product {
+	product := models.Product{Id: p.Id, Stores: p.Stores, Barcodes: p.Barcodes}
+	if p.Name != nil {
+		product.Name = sql.NullString{String: *p.Name, Valid: true}
+	}
+	if p.Quantity != nil {
+		product.Quantity = sql.NullFloat64{Float64: *p.Quantity, Valid: true}
+	}
+	if p.Unit != nil {
+		product.Unit = sql.NullString{String: *p.Unit, Valid: true}
+	}
+	return product
+}
+
+func (m *memoryProducts) get(id string) (models.ProductResponse, error) {
+	key, _ := strconv.Atoi(id)
+	product, ok := m.products[key]
+	if !ok {
+		return product, sql.ErrNoRows
+	}
+	return product, nil
+}
+
+func (m *memoryProducts) GetAllProducts(ctx context.Context, filter models.ProductFilter) ([]models.ProductResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	products := []models.ProductResponse{}
+	for id := 1; id < m.nextId; id++ {
+		if product, ok := m.products[id]; ok && (filter.MaxPrice == nil || product.Stores == nil || cheapest(*product.Stores) <= *filter.MaxPrice) {
+			products = append(products, product)
+		}
+	}
+	return products, nil
+}
+
+func (m *memoryProducts) ScanProducts(ctx context.Context, filter models.ProductFilter, fn func(models.ProductResponse) error) error {
+	products, err := m.GetAllProducts(ctx, filter)
+	if err != nil {
+		return err
+	}
+	for _, product := range products {
+		if err := fn(product); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func cheapest(stores models.Stores) float64 {
+	min := -1.0
+	for _, price := range stores {
+		if min < 0 || price < min {
+			min = price
+		}
+	}
+	return min
+}
+
+func (m *memoryProducts) GetProductById(ctx context.Context, id string) (models.Product, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	product, err := m.get(id)
+	return toProduct(product), err
+}
+
+func (m *memoryProducts) GetProductByBarcode(ctx context.Context, barcode string) (models.Product, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, product := range m.products {
+		for _, code := range product.Barcodes {
+			if code == barcode {
+				return toProduct(product), nil
+			}
+		}
+	}
+	return models.Product{}, sql.ErrNoRows
+}
+
+func (m *memoryProducts) CreateProduct(ctx context.Context, product models.ProductResponse) (models.ProductResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	product.Id = m.nextId
+	m.nextId++
+	m.products[product.Id] = product
+	return product, nil
+}
+
+func (m *memoryProducts) DeleteProduct(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	product, err := m.get(id)
+	delete(m.products, product.Id)
+	return err
+}
+
+func (m *memoryProducts) UpdateProduct(ctx context.Context, id string, product models.ProductResponse) (models.ProductResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	existing, err := m.get(id)
+	if err != nil {
+		return product, err
+	}
+	product.Id = existing.Id
+	m.products[product.Id] = product
+	return product, nil
+}
+
+func (m *memoryProducts) PatchProduct(ctx context.Context, id string, patch models.ProductResponse) (models.Product, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	product, err := m.get(id)
+	if err != nil {
+		return models.Product{}, err
+	}
+	if patch.Name != nil {
+		product.Name = patch.Name
+	}
+	if patch.Stores != nil {
+		product.Stores = patch.Stores
+	}
+	m.products[product.Id] = product
+	return toProduct(product), nil
+}
+
+func (m *memoryProducts) PatchStore(ctx context.Context, id string, jsonStore []byte) (models.Product, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	product, err := m.get(id)
+	if err != nil {
+		return models.Product{}, err
+	}
+	var prices models.Stores
+	if err := json.Unmarshal(jsonStore, &prices); err != nil {
+		return models.Product{}, err
+	}
+	stores := models.Stores{}
+	if product.Stores != nil {
+		for store, price := range *product.Stores {
+			stores[store] = price
+		}
+	}
+	for store, price := range prices {
+		stores[store] = price
+	}
+	product.Stores = &stores
+	m.products[product.Id] = product
+	return toProduct(product), nil
+}
+
+func (m *memoryProducts) CompareProducts(ctx context.Context, filter models.ProductFilter) ([]models.VariantComparison, error) {
+	products, _ := m.GetAllProducts(ctx, filter)
+	return models.CompareVariants(products), nil
+}
+
+func newTestServer(t *testing.T, wrap func(http.Handler) http.Handler) *httptest.Server {
+	s := apiHttp.NewServer(apiHttp.WithAuthenticators(auth.StaticKey(adminKey, models.RoleAdmin)))
+	s.MountHandlers(apiHttp.NewProductHandler(newMemoryProducts()))
+	var handler http.Handler = s.Router
+	if wrap != nil {
+		handler = wrap(handler)
+	}
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestClient(t *testing.T, server *httptest.Server, opts ...Option) *Client {
+	c, err := New(server.URL, append([]Option{WithRetries(2, time.Millisecond)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func ptr[T any](value T) *T {
+	return &value
+}
+
+func TestProducts(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t, newTestServer(t, nil), WithAPIKey(adminKey))
+
+	created, err := c.CreateProduct(ctx, Product{Name: ptr("Leche Dos Pinos"), Quantity: ptr(1.0), Unit: ptr("litros"),
+		Stores: &Stores{"automercado": 1100}, Barcodes: models.Barcodes{"7441001600019"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Id == 0 || *created.Name != "Leche Dos Pinos" {
+		t.Fatalf("unexpected created product %+v", created)
+	}
+
+	got, err := c.GetProduct(ctx, created.Id, PriceOptions{})
+	if err != nil || *got.Name != "Leche Dos Pinos" {
+		t.Fatalf("GetProduct = %+v, %v", got, err)
+	}
+	if got, err = c.GetProductByBarcode(ctx, "7441001600019", PriceOptions{}); err != nil || got.Id != created.Id {
+		t.Fatalf("GetProductByBarcode = %+v, %v", got, err)
+	}
+
+	if got, err = c.SetStorePrices(ctx, created.Id, Stores{"walmart": 990}); err != nil {
+		t.Fatal(err)
+	}
+	if len(*got.Stores) != 2 || (*got.Stores)["walmart"] != 990 {
+		t.Errorf("unexpected stores after SetStorePrices %v", *got.Stores)
+	}
+	if got, err = c.PatchProduct(ctx, created.Id, Product{Name: ptr("Leche Deslactosada")}); err != nil || *got.Name != "Leche Deslactosada" {
+		t.Fatalf("PatchProduct = %+v, %v", got, err)
+	}
+	if got, err = c.UpdateProduct(ctx, created.Id, Product{Name: ptr("Leche"), Stores: &Stores{"pali": 950}}); err != nil || *got.Name != "Leche" {
+		t.Fatalf("UpdateProduct = %+v, %v", got, err)
+	}
+
+	products, err := c.ListProducts(ctx, ListOptions{MaxPrice: ptr(1000.0)})
+	if err != nil || len(products) != 1 {
+		t.Fatalf("ListProducts = %v, %v", products, err)
+	}
+	if products, err = c.ListVariants(ctx, created.Id, PriceOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.CompareBrand(ctx, 1, PriceOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.DeleteProduct(ctx, created.Id); err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.GetProductByBarcode(ctx, "7441001600019", PriceOptions{})
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || apiErr.Message != "Failed getting product by barcode" {
+		t.Errorf("expected a 404 Error, got %v", err)
+	}
+}
+
+func TestUnauthorized(t *testing.T) {
+	server := newTestServer(t, nil)
+	for name, c := range map[string]*Client{
+		"anonymous": newTestClient(t, server),
+		"wrong key": newTestClient(t, server, WithAPIKey("crp_wrong")),
+	} {
+		_, err := c.CreateProduct(context.Background(), Product{Name: ptr("Arroz")})
+		var apiErr *Error
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+			t.Errorf("%s: expected a 401 Error, got %v", name, err)
+		}
+	}
+}
+
+// failFirst answers the first n requests with status
+func failFirst(n int32, status int, calls *int32) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(calls, 1) <= n {
+				w.Header().Set("Retry-After", "0")
+				http.Error(w, http.StatusText(status), status)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func TestRetries(t *testing.T) {
+	var calls int32
+	c := newTestClient(t, newTestServer(t, failFirst(2, http.StatusServiceUnavailable, &calls)), WithAPIKey(adminKey))
+	if _, err := c.ListProducts(context.Background(), ListOptions{}); err != nil {
+		t.Fatalf("expected the third attempt to succeed, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("made %d calls, want 3", calls)
+	}
+
+	calls = 0
+	_, err := c.CreateProduct(context.Background(), Product{Name: ptr("Arroz")})
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable || calls != 1 {
+		t.Errorf("POST should not be retried, got %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	c = newTestClient(t, newTestServer(t, failFirst(5, http.StatusTooManyRequests, &calls)))
+	if _, err := c.ListProducts(context.Background(), ListOptions{}); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("expected the last 429 once retries run out, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("made %d calls, want 3", calls)
+	}
+}
+
+func TestContextCanceled(t *testing.T) {
+	var calls int32
+	server := newTestServer(t, failFirst(5, http.StatusServiceUnavailable, &calls))
+	c, err := New(server.URL, WithRetries(5, time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := c.ListProducts(ctx, ListOptions{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the deadline to stop the retries, got %v", err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, baseURL := range []string{"", "localhost:8080", "://bad"} {
+		if _, err := New(baseURL); err == nil {
+			t.Errorf("New(%q) should fail", baseURL)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package client
+
+import (
+	"context"
+	"crproductos/internal/models"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// The models the API exchanges, aliased so callers outside this module can
+// name them
+type (
+	Product           = models.ProductResponse
+	Stores            = models.Stores
+	VariantComparison = models.VariantComparison
+)
+
+// PriceOptions picks the promotions and exchange rates applied to the prices
+// of a read, zero values mean now and the store currencies
+type PriceOptions struct {
+	At       time.Time
+	Currency string
+}
+
+func (o PriceOptions) query() url.Values {
+	query := url.Values{}
+	if !o.At.IsZero() {
+		query.Set("at", o.At.Format(time.RFC3339))
+	}
+	if o.Currency != "" {
+		query.Set("currency", o.Currency)
+	}
+	return query
+}
+
+// ListOptions filters ListProducts, nil and empty fields don't filter
+type ListOptions struct {
+	PriceOptions
+	CategoryId *int
+	BrandId    *int
+	ParentId   *int
+	Tags       []string
+	MaxPrice   *float64
+}
+
+func (o ListOptions) query() url.Values {
+	query := o.PriceOptions.query()
+	for param, value := range map[string]*int{"category": o.CategoryId, "brand": o.BrandId, "parent": o.ParentId} {
+		if value != nil {
+			query.Set(param, strconv.Itoa(*value))
+		}
+	}
+	for _, tag := range o.Tags {
+		query.Add("tag", tag)
+	}
+	if o.MaxPrice != nil {
+		query.Set("maxPrice", strconv.FormatFloat(*o.MaxPrice, 'f', -1, 64))
+	}
+	return query
+}
+
+func productPath(id int, suffix string) string {
+	return "/products/" + strconv.Itoa(id) + suffix
+}
+
+// ListProducts: GET /products
+func (c *Client) ListProducts(ctx context.Context, opts ListOptions) ([]models.ProductResponse, error) {
+	var products []models.ProductResponse
+	err := c.do(ctx, http.MethodGet, "/products", opts.query(), nil, &products)
+	return products, err
+}
+
+// GetProduct: GET /products/{id}
+func (c *Client) GetProduct(ctx context.Context, id int, opts PriceOptions) (models.ProductResponse, error) {
+	var product models.ProductResponse
+	err := c.do(ctx, http.MethodGet, productPath(id, ""), opts.query(), nil, &product)
+	return product, err
+}
+
+// GetProductByBarcode: GET /products/by-barcode/{code}
+func (c *Client) GetProductByBarcode(ctx context.Context, code string, opts PriceOptions) (models.ProductResponse, error) {
+	var product models.ProductResponse
+	err := c.do(ctx, http.MethodGet, "/products/by-barcode/"+url.PathEscape(code), opts.query(), nil, &product)
+	return product, err
+}
+
+// ListVariants: GET /products/{id}/variants
+func (c *Client) ListVariants(ctx context.Context, id int, opts PriceOptions) ([]models.ProductResponse, error) {
+	var products []models.ProductResponse
+	err := c.do(ctx, http.MethodGet, productPath(id, "/variants"), opts.query(), nil, &products)
+	return products, err
+}
+
+// CompareVariants: GET /products/{id}/comparison
+func (c *Client) CompareVariants(ctx context.Context, id int, opts PriceOptions) ([]models.VariantComparison, error) {
+	var comparison []models.VariantComparison
+	err := c.do(ctx, http.MethodGet, productPath(id, "/comparison"), opts.query(), nil, &comparison)
+	return comparison, err
+}
+
+// CompareBrand: GET /brands/{id}/comparison
+func (c *Client) CompareBrand(ctx context.Context, id int, opts PriceOptions) ([]models.VariantComparison, error) {
+	var comparison []models.VariantComparison
+	err := c.do(ctx, http.MethodGet, "/brands/"+strconv.Itoa(id)+"/comparison", opts.query(), nil, &comparison)
+	return comparison, err
+}
+
+// CreateProduct: POST /products, never retried
+func (c *Client) CreateProduct(ctx context.Context, product models.ProductResponse) (models.ProductResponse, error) {
+	var created models.ProductResponse
+	err := c.do(ctx, http.MethodPost, "/products", nil, product, &created)
+	return created, err
+}
+
+// UpdateProduct: PUT /products/{id}
+func (c *Client) UpdateProduct(ctx context.Context, id int, product models.ProductResponse) (models.ProductResponse, error) {
+	var updated models.ProductResponse
+	err := c.do(ctx, http.MethodPut, productPath(id, ""), nil, product, &updated)
+	return updated, err
+}
+
+// PatchProduct: PATCH /products/{id}, only the non nil fields of product change
+func (c *Client) PatchProduct(ctx context.Context, id int, product models.ProductResponse) (models.ProductResponse, error) {
+	var patched models.ProductResponse
+	err := c.do(ctx, http.MethodPatch, productPath(id, ""), nil, product, &patched)
+	return patched, err
+}
+
+// SetStorePrices: PATCH /products/{id}/store, stores missing from prices
+// keep their price
+func (c *Client) SetStorePrices(ctx context.Context, id int, prices models.Stores) (models.ProductResponse, error) {
+	var patched models.ProductResponse
+	err := c.do(ctx, http.MethodPatch, productPath(id, "/store"), nil, prices, &patched)
+	return patched, err
+}
+
+// DeleteProduct: DELETE /products/{id}
+func (c *Client) DeleteProduct(ctx context.Context, id int) error {
+	return c.do(ctx, http.MethodDelete, productPath(id, ""), nil, nil, nil)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"crproductos/client"
+	"crproductos/internal/models"
+	"crproductos/internal/service"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// catalog is what the subcommands need from the products, served by the
+// service layer against the database or by a remote server
+type catalog interface {
+	List(ctx context.Context, filter models.ProductFilter) ([]models.ProductResponse, error)
+	Get(ctx context.Context, id int) (models.ProductResponse, error)
+	GetByBarcode(ctx context.Context, barcode string) (models.ProductResponse, error)
+	Create(ctx context.Context, product models.ProductResponse) (models.ProductResponse, error)
+	Update(ctx context.Context, id int, product models.ProductResponse) (models.ProductResponse, error)
+	Patch(ctx context.Context, id int, product models.ProductResponse) (models.ProductResponse, error)
+	SetStorePrices(ctx context.Context, id int, prices models.Stores) (models.ProductResponse, error)
+	Delete(ctx context.Context, id int) error
+}
+
+type localCatalog struct {
+	products service.ProductService
+}
+
+func (c localCatalog) List(ctx context.Context, filter models.ProductFilter) ([]models.ProductResponse, error) {
+	return c.products.GetAllProducts(ctx, filter)
+}
+
+func (c localCatalog) Get(ctx context.Context, id int) (models.ProductResponse, error) {
+	product, err := c.products.GetProductById(ctx, strconv.Itoa(id))
+	return product.ToJSON(), err
+}
+
+func (c localCatalog) GetByBarcode(ctx context.Context, barcode string) (models.ProductResponse, error) {
+	product, err := c.products.GetProductByBarcode(ctx, barcode)
+	return product.ToJSON(), err
+}
+
+func (c localCatalog) Create(ctx context.Context, product models.ProductResponse) (models.ProductResponse, error) {
+	return c.products.CreateProduct(ctx, product)
+}
+
+func (c localCatalog) Update(ctx context.Context, id int, product models.ProductResponse) (models.ProductResponse, error) {
+	return c.products.UpdateProduct(ctx, strconv.Itoa(id), product)
+}
+
+func (c localCatalog) Patch(ctx context.Context, id int, product models.ProductResponse) (models.ProductResponse, error) {
+	patched, err := c.products.PatchProduct(ctx, strconv.Itoa(id), product)
+	return patched.ToJSON(), err
+}
+
+func (c localCatalog) SetStorePrices(ctx context.Context, id int, prices models.Stores) (models.ProductResponse, error) {
+	jsonStore, err := json.Marshal(prices)
+	if err != nil {
+		return models.ProductResponse{}, err
+	}
+	patched, err := c.products.PatchStore(ctx, strconv.Itoa(id), jsonStore)
+	return patched.ToJSON(), err
+}
+
+func (c localCatalog) Delete(ctx context.Context, id int) error {
+	return c.products.DeleteProduct(ctx, strconv.Itoa(id))
+}
+
+type remoteCatalog struct {
+	client *client.Client
+}
+
+func (c remoteCatalog) List(ctx context.Context, filter models.ProductFilter) ([]models.ProductResponse, error) {
+	products, err := c.client.ListProducts(ctx, client.ListOptions{
+		CategoryId: filter.CategoryId,
+		BrandId:    filter.BrandId,
+		ParentId:   filter.ParentId,
+		Tags:       filter.Tags,
+		MaxPrice:   filter.MaxPrice,
+	})
+	if err != nil || len(filter.Ids) == 0 {
+		return products, err
+	}
+	// the HTTP API has no id filter, keep the requested ones here
+	wanted := map[int]bool{}
+	for _, id := range filter.Ids {
+		wanted[id] = true
+	}
+	var kept []models.ProductResponse
+	for _, product := range products {
+		if wanted[product.Id] {
+			kept = append(kept, product)
+		}
+	}
+	return kept, nil
+}
+
+func (c remoteCatalog) Get(ctx context.Context, id int) (models.ProductResponse, error) {
+	return c.client.GetProduct(ctx, id, client.PriceOptions{})
+}
+
+// GetByBarcode reports an unknown barcode with sql.ErrNoRows, as the
+// service layer does
+func (c remoteCatalog) GetByBarcode(ctx context.Context, barcode string) (models.ProductResponse, error) {
+	product, err := c.client.GetProductByBarcode(ctx, barcode, client.PriceOptions{})
+	var apiErr *client.Error
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return product, sql.ErrNoRows
+	}
+	return product, err
+}
+
+func (c remoteCatalog) Create(ctx context.Context, product models.ProductResponse) (models.ProductResponse, error) {
+	return c.client.CreateProduct(ctx, product)
+}
+
+func (c remoteCatalog) Update(ctx context.Context, id int, product models.ProductResponse) (models.ProductResponse, error) {
+	return c.client.UpdateProduct(ctx, id, product)
+}
+
+func (c remoteCatalog) Patch(ctx context.Context, id int, product models.ProductResponse) (models.ProductResponse, error) {
+	return c.client.PatchProduct(ctx, id, product)
+}
+
+func (c remoteCatalog) SetStorePrices(ctx context.Context, id int, prices models.Stores) (models.ProductResponse, error) {
+	return c.client.SetStorePrices(ctx, id, prices)
+}
+
+func (c remoteCatalog) Delete(ctx context.Context, id int) error {
+	return c.client.DeleteProduct(ctx, id)
+}
+
+// fixtureTarget loads fixtures through a catalog
+type fixtureTarget struct {
+	catalog catalog
+}
+
+func (t fixtureTarget) GetProductByBarcode(ctx context.Context, barcode string) (models.Product, error) {
+	product, err := t.catalog.GetByBarcode(ctx, barcode)
+	return models.Product{Id: product.Id}, err
+}
+
+func (t fixtureTarget) CreateProduct(ctx context.Context, product models.ProductResponse) (models.ProductResponse, error) {
+	return t.catalog.Create(ctx, product)
+}
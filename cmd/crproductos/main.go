@@ -0,0 +1,534 @@
+// Command crproductos manages the catalog from the command line, either
+// straight against the database (configured like the server, DB_HOST and
+// friends) or through a running server given with -server
+package main
+
+import (
+	"context"
+	"crproductos/client"
+	"crproductos/internal/db"
+	"crproductos/internal/fixtures"
+	"crproductos/internal/logging"
+	"crproductos/internal/models"
+	"crproductos/internal/notify"
+	"crproductos/internal/repository"
+	"crproductos/internal/service"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+const usage = `Usage: crproductos [-server URL] [-api-key KEY] [-o table|json|csv] COMMAND [ARGS]
+
+Commands:
+  list [-category ID] [-brand ID] [-parent ID] [-tag NAME]... [-max-price N]
+  get ID...
+  create [PRODUCT FLAGS]
+  update ID [PRODUCT FLAGS]
+  patch ID [PRODUCT FLAGS]
+  set-store-price ID STORE PRICE
+  delete ID
+  migrate
+  import FILE.csv
+  export [-f FILE.csv] [LIST FLAGS]
+  seed [FIXTURES.json]
+
+Product flags: -name, -quantity, -unit, -brand, -parent, -barcode CODE...,
+-price STORE=PRICE..., -currency STORE=CODE..., or -file PRODUCT.json
+
+Without -server (or CRPRODUCTOS_SERVER) the commands run against the
+database, migrate only runs there.
+`
+
+// app holds the global flags and the catalog the subcommands work on
+type app struct {
+	catalog catalog
+	conn    *sql.DB
+	format  string
+	out     io.Writer
+}
+
+func main() {
+	flags := flag.NewFlagSet("crproductos", flag.ExitOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	server := flags.String("server", os.Getenv("CRPRODUCTOS_SERVER"), "base URL of a crproductos server")
+	apiKey := flags.String("api-key", os.Getenv("CRPRODUCTOS_API_KEY"), "API key sent to the server")
+	format := flags.String("o", "table", "output format, table, json or csv")
+	flags.Parse(os.Args[1:])
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	// the env file configures the database, remote mode runs anywhere without it
+	if *server == "" {
+		db.LoadEnv()
+	}
+	level := os.Getenv("LOG_LEVEL")
+	if level == "" {
+		level = "warn"
+	}
+	logging.Setup(os.Stderr, level)
+
+	a := &app{format: *format, out: os.Stdout}
+	if *server != "" {
+		c, err := client.New(*server, client.WithAPIKey(*apiKey), client.WithUserAgent("crproductos-cli"), client.WithRetries(2, 500*time.Millisecond))
+		if err != nil {
+			fail(err)
+		}
+		a.catalog = remoteCatalog{client: c}
+	} else {
+		a.conn = db.ConnectToPostgres()
+		defer a.conn.Close()
+		// local writes evaluate the price watches like the servers do
+		alertEvaluator := service.NewAlertEvaluator(repository.NewWatchlistRepository(a.conn), notify.LogNotifier{},
+			service.NewExchangeRateService(repository.NewExchangeRateRepository(a.conn)))
+		a.catalog = localCatalog{products: service.NewProductService(repository.NewProductRepository(a.conn), service.WithAlertEvaluator(alertEvaluator))}
+	}
+	if err := a.run(context.Background(), flags.Arg(0), flags.Args()[1:]); err != nil {
+		fail(err)
+	}
+}
+
+func fail(err error) {
+	if errors.Is(err, sql.ErrNoRows) {
+		err = errors.New("not found")
+	}
+	fmt.Fprintln(os.Stderr, "crproductos:", err)
+	os.Exit(1)
+}
+
+func (a *app) run(ctx context.Context, command string, args []string) error {
+	switch command {
+	case "list":
+		return a.list(ctx, args)
+	case "get":
+		return a.get(ctx, args)
+	case "create":
+		return a.write(ctx, command, args, false)
+	case "update", "patch":
+		return a.write(ctx, command, args, true)
+	case "set-store-price":
+		return a.setStorePrice(ctx, args)
+	case "delete":
+		return a.delete(ctx, args)
+	case "migrate":
+		return a.migrate()
+	case "import":
+		return a.importCSV(ctx, args)
+	case "export":
+		return a.exportCSV(ctx, args)
+	case "seed":
+		return a.seed(ctx, args)
+	}
+	return fmt.Errorf("unknown command %q, run crproductos -h for the list", command)
+}
+
+// pairsFlag collects repeated KEY=VALUE flags
+type pairsFlag map[string]string
+
+func (p pairsFlag) String() string {
+	joined := make([]string, 0, len(p))
+	for key, value := range p {
+		joined = append(joined, key+"="+value)
+	}
+	sort.Strings(joined)
+	return strings.Join(joined, ";")
+}
+
+func (p pairsFlag) Set(value string) error {
+	key, v, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("expected KEY=VALUE, got %q", value)
+	}
+	p[key] = v
+	return nil
+}
+
+// listFlag collects repeated flags
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// parseWithId: Parses args allowing the id before the flags, as in
+// "update 3 -name Leche". Returns the positional arguments
+func parseWithId(flags *flag.FlagSet, args []string) ([]string, error) {
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	positional := []string{}
+	for flags.NArg() > 0 {
+		positional = append(positional, flags.Arg(0))
+		if err := flags.Parse(flags.Args()[1:]); err != nil {
+			return nil, err
+		}
+	}
+	return positional, nil
+}
+
+func parseId(value string) (int, error) {
+	id, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid product id %q", value)
+	}
+	return id, nil
+}
+
+// filterFlags registers the list filters on flags
+func filterFlags(flags *flag.FlagSet) func() (models.ProductFilter, error) {
+	category := flags.String("category", "", "category id, subcategories included")
+	brand := flags.String("brand", "", "brand id")
+	parent := flags.String("parent", "", "parent product id")
+	maxPrice := flags.String("max-price", "", "sold at or below this price in some store")
+	var tags listFlag
+	flags.Var(&tags, "tag", "tag the products must have, repeatable")
+	return func() (models.ProductFilter, error) {
+		var filter models.ProductFilter
+		var err error
+		for target, value := range map[**int]string{&filter.CategoryId: *category, &filter.BrandId: *brand, &filter.ParentId: *parent} {
+			if *target, err = parseInt(value); err != nil {
+				return filter, err
+			}
+		}
+		if filter.MaxPrice, err = parseFloat(*maxPrice); err != nil {
+			return filter, err
+		}
+		for _, tag := range tags {
+			name, err := models.NormalizeTag(tag)
+			if err != nil {
+				return filter, err
+			}
+			filter.Tags = append(filter.Tags, name)
+		}
+		return filter, nil
+	}
+}
+
+// productFlags: Registers the product fields on flags, the returned product
+// only has the fields whose flag was given so patch leaves the others alone
+func productFlags(flags *flag.FlagSet) func() (models.ProductResponse, error) {
+	file := flags.String("file", "", "JSON file holding the product, - for stdin")
+	name := flags.String("name", "", "product name")
+	quantity := flags.String("quantity", "", "size of the product, in unit")
+	unit := flags.String("unit", "", "unit of the quantity")
+	brand := flags.String("brand", "", "brand id")
+	parent := flags.String("parent", "", "id of the product this is a size variant of")
+	var barcodes listFlag
+	flags.Var(&barcodes, "barcode", "barcode, repeatable")
+	prices := pairsFlag{}
+	flags.Var(prices, "price", "STORE=PRICE, repeatable")
+	currencies := pairsFlag{}
+	flags.Var(currencies, "currency", "STORE=CODE of a price not in colones, repeatable")
+	return func() (models.ProductResponse, error) {
+		var product models.ProductResponse
+		if *file != "" {
+			if err := readJSON(*file, &product); err != nil {
+				return product, err
+			}
+		}
+		var err error
+		given := map[string]bool{}
+		flags.Visit(func(f *flag.Flag) { given[f.Name] = true })
+		if given["name"] {
+			product.Name = name
+		}
+		if given["unit"] {
+			product.Unit = unit
+		}
+		if given["quantity"] {
+			if product.Quantity, err = parseFloat(*quantity); err != nil {
+				return product, err
+			}
+		}
+		if given["brand"] {
+			if product.BrandId, err = parseInt(*brand); err != nil {
+				return product, err
+			}
+		}
+		if given["parent"] {
+			if product.ParentId, err = parseInt(*parent); err != nil {
+				return product, err
+			}
+		}
+		if len(barcodes) > 0 {
+			product.Barcodes = models.Barcodes(barcodes)
+		}
+		if len(prices) > 0 {
+			stores := models.Stores{}
+			for store, value := range prices {
+				if stores[store], err = strconv.ParseFloat(value, 64); err != nil {
+					return product, fmt.Errorf("invalid price %q for %s", value, store)
+				}
+			}
+			product.Stores = &stores
+		}
+		if len(currencies) > 0 {
+			storeCurrencies := models.StoreCurrencies(currencies)
+			product.Currencies = &storeCurrencies
+		}
+		return product, nil
+	}
+}
+
+// openInput opens path for reading, - is stdin
+func openInput(path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(path)
+}
+
+func readJSON(path string, v any) error {
+	r, err := openInput(path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return json.NewDecoder(r).Decode(v)
+}
+
+func (a *app) print(products ...models.ProductResponse) error {
+	return writeProducts(a.out, a.format, products)
+}
+
+func (a *app) list(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("list", flag.ContinueOnError)
+	filter := filterFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	f, err := filter()
+	if err != nil {
+		return err
+	}
+	products, err := a.catalog.List(ctx, f)
+	if err != nil {
+		return err
+	}
+	return a.print(products...)
+}
+
+func (a *app) get(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New("get needs at least one product id")
+	}
+	var products []models.ProductResponse
+	for _, arg := range args {
+		id, err := parseId(arg)
+		if err != nil {
+			return err
+		}
+		product, err := a.catalog.Get(ctx, id)
+		if err != nil {
+			return fmt.Errorf("product %d: %w", id, err)
+		}
+		products = append(products, product)
+	}
+	return a.print(products...)
+}
+
+// write runs create, update and patch, withId tells whether the command
+// takes the product id
+func (a *app) write(ctx context.Context, command string, args []string, withId bool) error {
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
+	fields := productFlags(flags)
+	positional, err := parseWithId(flags, args)
+	if err != nil {
+		return err
+	}
+	want := 0
+	if withId {
+		want = 1
+	}
+	if len(positional) != want {
+		return fmt.Errorf("%s takes %d product id, got %d arguments", command, want, len(positional))
+	}
+	product, err := fields()
+	if err != nil {
+		return err
+	}
+	var written models.ProductResponse
+	if command == "create" {
+		written, err = a.catalog.Create(ctx, product)
+	} else {
+		var id int
+		if id, err = parseId(positional[0]); err != nil {
+			return err
+		}
+		if command == "update" {
+			written, err = a.catalog.Update(ctx, id, product)
+		} else {
+			written, err = a.catalog.Patch(ctx, id, product)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return a.print(written)
+}
+
+func (a *app) setStorePrice(ctx context.Context, args []string) error {
+	if len(args) != 3 {
+		return errors.New("set-store-price takes ID STORE PRICE")
+	}
+	id, err := parseId(args[0])
+	if err != nil {
+		return err
+	}
+	price, err := strconv.ParseFloat(args[2], 64)
+	if err != nil {
+		return fmt.Errorf("invalid price %q", args[2])
+	}
+	product, err := a.catalog.SetStorePrices(ctx, id, models.Stores{args[1]: price})
+	if err != nil {
+		return err
+	}
+	return a.print(product)
+}
+
+func (a *app) delete(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return errors.New("delete takes one product id")
+	}
+	id, err := parseId(args[0])
+	if err != nil {
+		return err
+	}
+	return a.catalog.Delete(ctx, id)
+}
+
+func (a *app) migrate() error {
+	if a.conn == nil {
+		return errors.New("migrate runs against the database, drop -server")
+	}
+	return db.Migrate(a.conn)
+}
+
+// importCSV: Updates the rows of FILE with an id or a barcode already in the
+// catalog and creates the others. A failing row doesn't stop the import, every
+// failure is reported with its line and the import fails once all rows ran
+func (a *app) importCSV(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return errors.New("import takes one CSV file, - for stdin")
+	}
+	r, err := openInput(args[0])
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	products, err := models.ReadProductsCSV(r)
+	if err != nil {
+		return err
+	}
+	created, updated, failed := 0, 0, 0
+	for i, product := range products {
+		wasNew, err := a.importProduct(ctx, product)
+		switch {
+		case err != nil:
+			// line 1 is the header
+			fmt.Fprintf(os.Stderr, "line %d, %s: %v\n", i+2, deref(product.Name), err)
+			failed++
+		case wasNew:
+			created++
+		default:
+			updated++
+		}
+	}
+	fmt.Fprintf(os.Stderr, "created %d, updated %d products\n", created, updated)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d rows failed", failed, len(products))
+	}
+	return nil
+}
+
+// importProduct writes a single imported row, matching rows without an id to
+// the product holding one of their barcodes. Reports whether it was created
+func (a *app) importProduct(ctx context.Context, product models.ProductResponse) (bool, error) {
+	for _, barcode := range product.Barcodes {
+		if product.Id != 0 {
+			break
+		}
+		existing, err := a.catalog.GetByBarcode(ctx, barcode)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return false, err
+		}
+		product.Id = existing.Id
+	}
+	if product.Id == 0 {
+		_, err := a.catalog.Create(ctx, product)
+		return true, err
+	}
+	_, err := a.catalog.Update(ctx, product.Id, product)
+	return false, err
+}
+
+func (a *app) exportCSV(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	file := flags.String("f", "-", "file to write, - for stdout")
+	filter := filterFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	f, err := filter()
+	if err != nil {
+		return err
+	}
+	products, err := a.catalog.List(ctx, f)
+	if err != nil {
+		return err
+	}
+	if *file == "-" {
+		return models.WriteProductsCSV(a.out, products)
+	}
+	out, err := os.Create(*file)
+	if err != nil {
+		return err
+	}
+	if err := models.WriteProductsCSV(out, products); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// seed loads a fixture file, the bundled Costa Rican dataset without one.
+// Products whose barcode is already in the catalog are skipped
+func (a *app) seed(ctx context.Context, args []string) error {
+	if len(args) > 1 {
+		return errors.New("seed takes at most one fixture file, - for stdin")
+	}
+	set := fixtures.CostaRica()
+	if len(args) == 1 {
+		r, err := openInput(args[0])
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		if set, err = fixtures.Parse(r); err != nil {
+			return err
+		}
+	}
+	ids, err := fixtures.Load(ctx, fixtureTarget{catalog: a.catalog}, set)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "loaded %d products\n", len(ids))
+	return nil
+}
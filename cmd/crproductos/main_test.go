@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crproductos/internal/fixtures"
+	"crproductos/internal/models"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func ptr[T any](value T) *T {
+	return &value
+}
+
+// fakeCatalog records the last write it was sent, creating a product named
+// failing fails
+type fakeCatalog struct {
+	products []models.ProductResponse
+	written  models.ProductResponse
+	id       int
+	failing  string
+}
+
+func (f *fakeCatalog) List(ctx context.Context, filter models.ProductFilter) ([]models.ProductResponse, error) {
+	return f.products, nil
+}
+
+func (f *fakeCatalog) Get(ctx context.Context, id int) (models.ProductResponse, error) {
+	return f.products[0], nil
+}
+
+func (f *fakeCatalog) GetByBarcode(ctx context.Context, barcode string) (models.ProductResponse, error) {
+	for _, product := range f.products {
+		for _, code := range product.Barcodes {
+			if code == barcode {
+				return product, nil
+			}
+		}
+	}
+	return models.ProductResponse{}, sql.ErrNoRows
+}
+
+func (f *fakeCatalog) Create(ctx context.Context, product models.ProductResponse) (models.ProductResponse, error) {
+	if f.failing != "" && product.Name != nil && *product.Name == f.failing {
+		return product, errors.New("rejected")
+	}
+	product.Id = len(f.products) + 1
+	f.written = product
+	f.products = append(f.products, product)
+	return product, nil
+}
+
+func (f *fakeCatalog) Update(ctx context.Context, id int, product models.ProductResponse) (models.ProductResponse, error) {
+	f.id, f.written = id, product
+	return product, nil
+}
+
+func (f *fakeCatalog) Patch(ctx context.Context, id int, product models.ProductResponse) (models.ProductResponse, error) {
+	f.id, f.written = id, product
+	return product, nil
+}
+
+func (f *fakeCatalog) SetStorePrices(ctx context.Context, id int, prices models.Stores) (models.ProductResponse, error) {
+	f.id, f.written = id, models.ProductResponse{Stores: &prices}
+	return f.written, nil
+}
+
+func (f *fakeCatalog) Delete(ctx context.Context, id int) error {
+	f.id = id
+	return nil
+}
+
+func TestWriteCommands(t *testing.T) {
+	catalog := &fakeCatalog{}
+	var out bytes.Buffer
+	a := &app{catalog: catalog, format: "json", out: &out}
+	ctx := context.Background()
+
+	if err := a.run(ctx, "seed", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.run(ctx, "seed", nil); err != nil {
+		t.Fatal(err)
+	}
+	if want := fixtures.Products(fixtures.CostaRica()); !reflect.DeepEqual(catalog.products, want) {
+		t.Errorf("seeding twice gave %d products, want %d", len(catalog.products), len(want))
+	}
+
+	if err := a.run(ctx, "patch", []string{"3", "-name", "Leche descremada", "-price", "pali=900"}); err != nil {
+		t.Fatal(err)
+	}
+	want := models.ProductResponse{Name: ptr("Leche descremada"), Stores: &models.Stores{"pali": 900}}
+	if catalog.id != 3 || !reflect.DeepEqual(catalog.written, want) {
+		t.Errorf("patch sent %d %+v", catalog.id, catalog.written)
+	}
+
+	if err := a.run(ctx, "set-store-price", []string{"4", "walmart", "1250.5"}); err != nil {
+		t.Fatal(err)
+	}
+	if catalog.id != 4 || (*catalog.written.Stores)["walmart"] != 1250.5 {
+		t.Errorf("set-store-price sent %d %+v", catalog.id, catalog.written)
+	}
+
+	if err := a.run(ctx, "create", []string{"5", "-name", "Arroz"}); err == nil {
+		t.Error("create should refuse an id")
+	}
+	if err := a.run(ctx, "update", []string{"-name", "Arroz"}); err == nil {
+		t.Error("update should require an id")
+	}
+
+	catalog.products = []models.ProductResponse{{Id: 1, Name: ptr("Leche entera"), Quantity: ptr(1.0), Unit: ptr("litros"), Stores: &models.Stores{"pali": 950}}}
+	out.Reset()
+	a.format = "table"
+	if err := a.run(ctx, "list", nil); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); len(lines) != 2 || !strings.Contains(lines[1], "1 litros") || !strings.HasSuffix(lines[1], "pali=950") {
+		t.Errorf("unexpected table\n%s", out.String())
+	}
+}
+
+func TestImportMatchesBarcodes(t *testing.T) {
+	catalog := &fakeCatalog{failing: "Cafe", products: []models.ProductResponse{
+		{Id: 1, Name: ptr("Leche entera"), Barcodes: models.Barcodes{"7441001603217"}}}}
+	a := &app{catalog: catalog, format: "json", out: &bytes.Buffer{}}
+	file := filepath.Join(t.TempDir(), "products.csv")
+	var csv bytes.Buffer
+	if err := models.WriteProductsCSV(&csv, []models.ProductResponse{
+		{Name: ptr("Cafe")},
+		{Name: ptr("Arroz"), Barcodes: models.Barcodes{"7440000000002"}},
+		{Name: ptr("Leche entera"), Barcodes: models.Barcodes{"7440000000001", "7441001603217"}, Stores: &models.Stores{"pali": 990}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, csv.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := a.run(context.Background(), "import", []string{file})
+	if err == nil || !strings.Contains(err.Error(), "1 of 3 rows failed") {
+		t.Errorf("expected the failing row to be reported, got %v", err)
+	}
+	if catalog.id != 1 || (*catalog.written.Stores)["pali"] != 990 {
+		t.Errorf("expected the milk matched by barcode to be updated, got %d %+v", catalog.id, catalog.written)
+	}
+	if len(catalog.products) != 2 || *catalog.products[1].Name != "Arroz" {
+		t.Errorf("expected the rows after the failure to be imported, got %+v", catalog.products)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"crproductos/internal/models"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// writeProducts prints products in format, "table", "json" or "csv"
+func writeProducts(w io.Writer, format string, products []models.ProductResponse) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(products)
+	case "csv":
+		return models.WriteProductsCSV(w, products)
+	case "table":
+		return writeTable(w, products)
+	}
+	return fmt.Errorf("unknown output format %q, use table, json or csv", format)
+}
+
+func writeTable(w io.Writer, products []models.ProductResponse) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tSIZE\tBRAND\tPARENT\tSTORES")
+	for _, product := range products {
+		size := strings.TrimSpace(formatFloat(product.Quantity) + " " + deref(product.Unit))
+		stores := strings.ReplaceAll(models.FormatStorePrices(product.Stores), ";", " ")
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\n", product.Id, deref(product.Name), size, formatInt(product.BrandId), formatInt(product.ParentId), stores)
+	}
+	return tw.Flush()
+}
+
+func deref(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+func formatInt(value *int) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.Itoa(*value)
+}
+
+func formatFloat(value *float64) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*value, 'f', -1, 64)
+}
+
+func parseInt(value string) (*int, error) {
+	if value == "" {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %q", value)
+	}
+	return &n, nil
+}
+
+func parseFloat(value string) (*float64, error) {
+	if value == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %q", value)
+	}
+	return &f, nil
+}
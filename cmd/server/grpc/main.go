@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	apiGrpc "crproductos/api/grpc"
+	"crproductos/api/grpc/pb"
+	"crproductos/cmd/server/internal/setup"
+	"crproductos/internal/db"
+	"crproductos/internal/events"
+	"crproductos/internal/logging"
+	"crproductos/internal/notify"
+	"crproductos/internal/outbox"
+	"crproductos/internal/repository"
+	"crproductos/internal/service"
+	"flag"
+	"log"
+	"log/slog"
+	"net"
+	"os"
+
+	_ "github.com/lib/pq"
+	"google.golang.org/grpc"
+)
+
+func main() {
+	seed := setup.SeedFlags()
+	flag.Parse()
+	db.LoadEnv()
+	logging.Setup(os.Stdout, os.Getenv("LOG_LEVEL"))
+	conn := db.ConnectToPostgres()
+	defer conn.Close()
+	if err := db.Migrate(conn); err != nil {
+		log.Fatal("Failed to migrate: ", err)
+	}
+	if shutdown := setup.Tracing(); shutdown != nil {
+		defer shutdown()
+	}
+	watchlistRepo := repository.NewWatchlistRepository(conn)
+	alertEvaluator := service.NewAlertEvaluator(watchlistRepo, notify.LogNotifier{},
+		service.NewExchangeRateService(repository.NewExchangeRateRepository(conn)))
+	productService := service.NewTracedProductService(service.NewProductService(repository.NewProductRepository(conn), service.WithAlertEvaluator(alertEvaluator)))
+	seed(context.Background(), productService)
+	// The HTTP server relays the outbox and delivers the webhooks, WatchPrices
+	// gets the events it publishes through the listener
+	broker := events.NewBroker(1000)
+	outboxListener := outbox.NewListener(db.ConnInfo(), repository.NewOutboxRepository(conn), events.NewDedup(broker, 1000))
+	go func() {
+		if err := outboxListener.Run(context.Background()); err != nil {
+			log.Fatal("Failed to listen for outbox events: ", err)
+		}
+	}()
+	authenticators := setup.Authenticators(service.NewAPIKeyService(repository.NewAPIKeyRepository(conn)))
+
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(apiGrpc.UnaryAuthenticator(authenticators...)),
+		grpc.StreamInterceptor(apiGrpc.StreamAuthenticator(authenticators...)),
+	)
+	pb.RegisterProductServiceServer(server, apiGrpc.NewServer(productService, broker))
+
+	// GRPC_ADDR defaults to :9090, next to the HTTP server on :8080
+	addr := os.Getenv("GRPC_ADDR")
+	if addr == "" {
+		addr = ":9090"
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Fatal("Failed to listen: ", err)
+	}
+	slog.Info("serving grpc", "addr", addr)
+	if err := server.Serve(listener); err != nil {
+		log.Fatal("Failed to serve: ", err)
+	}
+}
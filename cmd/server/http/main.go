@@ -1,23 +1,199 @@
 package main
 
 import (
+	"context"
+	"crproductos/api/graphql"
 	apiHttp "crproductos/api/http"
+	"crproductos/cmd/server/internal/setup"
+	"crproductos/internal/cache"
 	"crproductos/internal/db"
+	"crproductos/internal/events"
+	"crproductos/internal/logging"
+	"crproductos/internal/metrics"
+	"crproductos/internal/notify"
+	"crproductos/internal/outbox"
+	"crproductos/internal/ratelimit"
 	"crproductos/internal/repository"
 	"crproductos/internal/service"
+	"crproductos/internal/webhook"
+	"database/sql"
+	"flag"
+	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"strconv"
+	"time"
 
 	_ "github.com/lib/pq"
 )
 
+// envLimit reads a rate limit from the environment, falling back to fallback
+func envLimit(env string, fallback string) ratelimit.Limit {
+	value := os.Getenv(env)
+	if value == "" {
+		value = fallback
+	}
+	limit, err := ratelimit.ParseLimit(value)
+	if err != nil {
+		log.Fatalf("Failed to read %s: %v", env, err)
+	}
+	return limit
+}
+
+// rateLimits: Reads RATE_LIMIT_READ and RATE_LIMIT_WRITE ("300/m" style) and
+// RATE_LIMIT_STORE, "postgres" to share the buckets between replicas
+func rateLimits(conn *sql.DB) apiHttp.RateLimits {
+	limits := apiHttp.RateLimits{
+		Store: ratelimit.NewMemoryStore(),
+		Read:  envLimit("RATE_LIMIT_READ", "300/m"),
+		Write: envLimit("RATE_LIMIT_WRITE", "60/m"),
+	}
+	if os.Getenv("RATE_LIMIT_STORE") == "postgres" {
+		store := repository.NewRateLimitRepository(conn)
+		go func() {
+			for range time.Tick(time.Hour) {
+				if _, err := store.DeleteIdle(time.Now().Add(-time.Hour)); err != nil {
+					slog.Error("failed to prune rate limit buckets", "err", err)
+				}
+			}
+		}()
+		limits.Store = store
+	}
+	return limits
+}
+
+// productCache: Reads CACHE_SIZE, the number of product reads kept (1000 by
+// default, 0 disables the cache), and CACHE_TTL, how long they are kept
+// ("5m" by default)
+func productCache() (cache.Store, time.Duration) {
+	size, ttl := 1000, 5*time.Minute
+	if value := os.Getenv("CACHE_SIZE"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			log.Fatalf("Failed to read CACHE_SIZE: %v", err)
+		}
+		size = n
+	}
+	if value := os.Getenv("CACHE_TTL"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			log.Fatalf("Failed to read CACHE_TTL: %v", err)
+		}
+		ttl = d
+	}
+	if size <= 0 {
+		return nil, 0
+	}
+	return cache.NewLRU(size), ttl
+}
+
 func main() {
 	// Self explanatory, need to look if there is way to mock db to separate tests into unit and integration testing
-	db := db.ConnectToPostgres()
-	defer db.Close()
-	productRepo := repository.NewProductRepository(db)
-	productService := service.NewProductService(productRepo)
-	productHandler := apiHttp.NewProductHandler(productService)
-	server := apiHttp.NewServer()
+	seed := setup.SeedFlags()
+	flag.Parse()
+	db.LoadEnv()
+	logging.Setup(os.Stdout, os.Getenv("LOG_LEVEL"))
+	conn := db.ConnectToPostgres()
+	defer conn.Close()
+	if err := db.Migrate(conn); err != nil {
+		log.Fatal("Failed to migrate: ", err)
+	}
+	if shutdown := setup.Tracing(); shutdown != nil {
+		defer shutdown()
+	}
+	registry := metrics.NewRegistry()
+	metrics.RegisterDBStats(registry, conn)
+	queryDuration := registry.NewHistogram("crproductos_repository_query_duration_seconds", "Time taken by repository methods.", nil, "method")
+	productRepo := repository.NewTimedProductRepository(repository.NewProductRepository(conn), func(method string, start time.Time) {
+		queryDuration.ObserveDuration(start, method)
+	})
+	stats := repository.NewStatsRepository(conn)
+	registry.NewGaugeFunc("crproductos_products", "Number of products in the catalog.", func() (float64, error) {
+		count, err := stats.CountProducts()
+		return float64(count), err
+	})
+	registry.NewGaugeFunc("crproductos_stores", "Number of stores listing at least one product.", func() (float64, error) {
+		count, err := stats.CountStores()
+		return float64(count), err
+	})
+	watchlistRepo := repository.NewWatchlistRepository(conn)
+	exchangeRateService := service.NewExchangeRateService(repository.NewExchangeRateRepository(conn))
+	alertEvaluator := service.NewAlertEvaluator(watchlistRepo, notify.LogNotifier{}, exchangeRateService)
+	webhookRepo := repository.NewWebhookRepository(conn)
+	dispatcher := webhook.NewDispatcher(webhookRepo)
+	go dispatcher.Run(context.Background())
+	broker := events.NewBroker(1000)
+	eventHandler := apiHttp.NewEventHandler(broker)
+	// This is the only relay, it claims every event once for the webhooks. The
+	// brokers of this and the gRPC server get the events it publishes through
+	// their listener
+	outboxRepo := repository.NewOutboxRepository(conn)
+	go outbox.NewRelay(outboxRepo, dispatcher).Run(context.Background())
+	webhookHandler := apiHttp.NewWebhookHandler(service.NewWebhookService(webhookRepo, dispatcher))
+	productService := service.NewTracedProductService(service.NewProductService(productRepo, service.WithAlertEvaluator(alertEvaluator)))
+	listened := events.Multi{events.NewDedup(broker, 1000)}
+	var catalogOpts []service.CatalogOption
+	if store, ttl := productCache(); store != nil {
+		cacheLookups := registry.NewCounter("crproductos_cache_lookups_total", "Product reads looked up in the cache, by result.", "method", "result")
+		cached := service.NewCachedProductService(productService, store, ttl, func(method string, hit bool) {
+			result := "miss"
+			if hit {
+				result = "hit"
+			}
+			cacheLookups.Inc(method, result)
+		})
+		// the outbox events drop what the gRPC server and the CLI write
+		listened = append(listened, cached)
+		catalogOpts = append(catalogOpts, service.WithProductCache(cached))
+		productService = cached
+	}
+	outboxListener := outbox.NewListener(db.ConnInfo(), outboxRepo, listened)
+	go func() {
+		if err := outboxListener.Run(context.Background()); err != nil {
+			log.Fatal("Failed to listen for outbox events: ", err)
+		}
+	}()
+	seed(context.Background(), productService)
+	watchlistHandler := apiHttp.NewWatchlistHandler(service.NewWatchlistService(watchlistRepo))
+	exchangeRateHandler := apiHttp.NewExchangeRateHandler(exchangeRateService)
+	promotionService := service.NewPromotionService(repository.NewPromotionRepository(conn))
+	promotionHandler := apiHttp.NewPromotionHandler(promotionService)
+	productHandler := apiHttp.NewProductHandler(productService, apiHttp.WithExchangeRates(exchangeRateService), apiHttp.WithPromotions(promotionService))
+	categoryService := service.NewCategoryService(repository.NewCategoryRepository(conn), catalogOpts...)
+	categoryHandler := apiHttp.NewCategoryHandler(categoryService)
+	tagService := service.NewTagService(repository.NewTagRepository(conn), catalogOpts...)
+	tagHandler := apiHttp.NewTagHandler(tagService)
+	brandService := service.NewBrandService(repository.NewBrandRepository(conn))
+	brandHandler := apiHttp.NewBrandHandler(brandService)
+	priceHistoryService := service.NewPriceHistoryService(repository.NewPriceHistoryRepository(conn))
+	pricingService := service.NewPricingService(promotionService, exchangeRateService)
+	graphqlHandler := graphql.NewHandler(productService, brandService, priceHistoryService, pricingService)
+	apiKeyService := service.NewAPIKeyService(repository.NewAPIKeyRepository(conn))
+	apiKeyHandler := apiHttp.NewAPIKeyHandler(apiKeyService)
+	authenticators := setup.Authenticators(apiKeyService)
+	serverOpts := []apiHttp.ServerOption{apiHttp.WithAuthenticators(authenticators...), apiHttp.WithRateLimits(rateLimits(conn)), apiHttp.WithMetrics(registry)}
+	// LEGACY_SUNSET (YYYY-MM-DD) is announced as the date the unprefixed routes go away
+	if sunset := os.Getenv("LEGACY_SUNSET"); sunset != "" {
+		date, err := time.Parse("2006-01-02", sunset)
+		if err != nil {
+			log.Fatal("Failed to read LEGACY_SUNSET: ", err)
+		}
+		serverOpts = append(serverOpts, apiHttp.WithLegacySunset(date))
+	}
+	server := apiHttp.NewServer(serverOpts...)
 	server.MountHandlers(productHandler)
+	server.MountCategoryHandlers(categoryHandler)
+	server.MountTagHandlers(tagHandler)
+	server.MountBrandHandlers(brandHandler)
+	server.MountExchangeRateHandlers(exchangeRateHandler)
+	server.MountPromotionHandlers(promotionHandler)
+	server.MountWatchlistHandlers(watchlistHandler)
+	server.MountWebhookHandlers(webhookHandler)
+	server.MountEventHandlers(eventHandler)
+	server.MountAPIKeyHandlers(apiKeyHandler)
+	server.MountGraphQLHandler(graphqlHandler)
+	server.MountMetricsHandler()
+	server.MountDocsHandlers()
 	http.ListenAndServe(":8080", server.Router)
 }
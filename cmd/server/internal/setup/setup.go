@@ -0,0 +1,99 @@
+// Package setup holds the configuration read from the environment that the
+// HTTP and gRPC servers share
+package setup
+
+import (
+	"context"
+	"crproductos/internal/auth"
+	"crproductos/internal/fixtures"
+	"crproductos/internal/models"
+	"crproductos/internal/trace"
+	"flag"
+	"log"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// Tracing: Installs the tracer picked by TRACE_EXPORTER, "stdout" or
+// "otlp" (posting to OTLP_ENDPOINT). Returns the function flushing the
+// exporter, nil when tracing is off
+func Tracing() func() {
+	switch os.Getenv("TRACE_EXPORTER") {
+	case "stdout":
+		trace.SetTracer(trace.NewTracer(trace.NewWriterExporter(os.Stdout)))
+	case "otlp":
+		endpoint := os.Getenv("OTLP_ENDPOINT")
+		if endpoint == "" {
+			endpoint = "http://localhost:4318/v1/traces"
+		}
+		exporter := trace.NewOTLPExporter(endpoint, "crproductos", 5*time.Second, 512)
+		trace.SetTracer(trace.NewTracer(exporter))
+		return exporter.Shutdown
+	}
+	return nil
+}
+
+// Authenticators: Accepts the keys issued through keys, plus ADMIN_API_KEY
+// and the JWTs signed by JWT_JWKS when they are set. JWT_JWKS also needs
+// JWT_ISSUER and JWT_AUDIENCE, startup fails without them
+func Authenticators(keys auth.KeyStore) []auth.Authenticator {
+	var authenticators []auth.Authenticator
+	// ADMIN_API_KEY bootstraps the first admin, use it to issue real keys
+	if key := os.Getenv("ADMIN_API_KEY"); key != "" {
+		authenticators = append(authenticators, auth.StaticKey(key, models.RoleAdmin))
+	}
+	authenticators = append(authenticators, auth.APIKeys(keys))
+	// JWT_JWKS (a file path or URL) enables tokens issued by the SSO
+	if source := os.Getenv("JWT_JWKS"); source != "" {
+		keySet, err := auth.LoadKeySet(source)
+		if err != nil {
+			log.Fatal("Failed to load JWKS: ", err)
+		}
+		roles, err := auth.ParseRoleMap(os.Getenv("JWT_ROLES"))
+		if err != nil {
+			log.Fatal("Failed to read JWT_ROLES: ", err)
+		}
+		jwt, err := auth.JWT(auth.JWTConfig{
+			Keys:      keySet,
+			Issuer:    os.Getenv("JWT_ISSUER"),
+			Audience:  os.Getenv("JWT_AUDIENCE"),
+			RoleClaim: os.Getenv("JWT_ROLE_CLAIM"),
+			Roles:     roles,
+			Leeway:    time.Minute,
+		})
+		if err != nil {
+			log.Fatal("JWT_JWKS needs JWT_ISSUER and JWT_AUDIENCE: ", err)
+		}
+		authenticators = append(authenticators, jwt)
+	}
+	return authenticators
+}
+
+// SeedFlags registers -seed and -fixtures, the returned function loads the
+// fixtures into target when -seed was given. Call it after flag.Parse
+func SeedFlags() func(ctx context.Context, target fixtures.Target) {
+	seed := flag.Bool("seed", false, "load the fixtures into the catalog at startup, skipping the products already there")
+	path := flag.String("fixtures", "", "fixture file loaded by -seed, the bundled Costa Rican dataset by default")
+	return func(ctx context.Context, target fixtures.Target) {
+		if !*seed {
+			return
+		}
+		set := fixtures.CostaRica()
+		if *path != "" {
+			f, err := os.Open(*path)
+			if err != nil {
+				log.Fatal("Failed to open fixtures: ", err)
+			}
+			defer f.Close()
+			if set, err = fixtures.Parse(f); err != nil {
+				log.Fatal("Failed to read fixtures: ", err)
+			}
+		}
+		ids, err := fixtures.Load(ctx, target, set)
+		if err != nil {
+			log.Fatal("Failed to seed: ", err)
+		}
+		slog.Info("seeded the catalog", "products", len(ids))
+	}
+}
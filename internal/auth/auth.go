@@ -0,0 +1,136 @@
+// Package auth identifies the caller of a request and the role it holds.
+// Authenticators turn request credentials into a Principal, the HTTP layer
+// checks the role of the Principal against what each route requires
+package auth
+
+import (
+	"context"
+	"crproductos/internal/models"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+var (
+	// ErrNoCredentials means the request carries no credentials the
+	// authenticator understands, the next one gets a chance
+	ErrNoCredentials = errors.New("no credentials")
+	// ErrInvalidCredentials means the credentials were understood but rejected
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// KeyPrefix starts every issued API key, it tells API keys apart from other
+// bearer tokens
+const KeyPrefix = "crp_"
+
+// Principal is the authenticated caller
+type Principal struct {
+	Subject string
+	Role    models.Role
+}
+
+// Authenticator extracts a Principal from the request credentials
+type Authenticator interface {
+	Authenticate(r *http.Request) (Principal, error)
+}
+
+type principalKey struct{}
+
+// NewContext returns a copy of ctx carrying the principal
+func NewContext(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// FromContext returns the principal of the request, ok is false for
+// anonymous requests
+func FromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
+
+// BearerToken returns the token of an "Authorization: Bearer" header
+func BearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// GenerateKey: Returns a new random API key and its prefix, the prefix is
+// safe to show and store as is
+func GenerateKey() (key string, prefix string, err error) {
+	id := make([]byte, 4)
+	secret := make([]byte, 32)
+	if _, err = rand.Read(id); err != nil {
+		return "", "", err
+	}
+	if _, err = rand.Read(secret); err != nil {
+		return "", "", err
+	}
+	prefix = KeyPrefix + hex.EncodeToString(id)
+	return prefix + "_" + hex.EncodeToString(secret), prefix, nil
+}
+
+// HashKey returns the hash stored for key. Keys are long and random, a
+// plain sha256 is enough to keep them from being usable if the table leaks
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// KeyStore resolves a plain API key to the key it was issued as
+type KeyStore interface {
+	Authenticate(key string) (models.APIKey, error)
+}
+
+type apiKeyAuthenticator struct {
+	keys KeyStore
+}
+
+// APIKeys authenticates the X-API-Key header, or a bearer token starting with
+// KeyPrefix, against the issued keys
+func APIKeys(keys KeyStore) Authenticator {
+	return apiKeyAuthenticator{keys: keys}
+}
+
+func (a apiKeyAuthenticator) Authenticate(r *http.Request) (Principal, error) {
+	key := r.Header.Get("X-API-Key")
+	if token := BearerToken(r); key == "" && strings.HasPrefix(token, KeyPrefix) {
+		key = token
+	}
+	if key == "" {
+		return Principal{}, ErrNoCredentials
+	}
+	apiKey, err := a.keys.Authenticate(key)
+	if err != nil {
+		return Principal{}, errors.Join(ErrInvalidCredentials, err)
+	}
+	return Principal{Subject: "key:" + apiKey.Prefix, Role: apiKey.Role}, nil
+}
+
+type staticKey struct {
+	hash string
+	role models.Role
+}
+
+// StaticKey accepts a single key configured outside the database, used to
+// bootstrap the first admin before any key is issued
+func StaticKey(key string, role models.Role) Authenticator {
+	return staticKey{hash: HashKey(key), role: role}
+}
+
+func (s staticKey) Authenticate(r *http.Request) (Principal, error) {
+	key := r.Header.Get("X-API-Key")
+	if key == "" {
+		key = BearerToken(r)
+	}
+	if key == "" || subtle.ConstantTimeCompare([]byte(HashKey(key)), []byte(s.hash)) != 1 {
+		return Principal{}, ErrNoCredentials
+	}
+	return Principal{Subject: "static", Role: s.role}, nil
+}
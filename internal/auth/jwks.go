@@ -0,0 +1,215 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// jwk is a single JSON Web Key, only the RSA and P-256 EC fields are read
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// ParseJWKS: Decodes a JSON Web Key Set into public keys by key id. Keys that
+// are not RSA or P-256 EC signing keys are skipped
+func ParseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+	keys := map[string]crypto.PublicKey{}
+	for _, key := range set.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		publicKey, err := key.publicKey()
+		if err != nil {
+			slog.Warn("skipping jwk", "kid", key.Kid, "err", err)
+			continue
+		}
+		keys[key.Kid] = publicKey
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("rsa exponent too large")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+		if !key.Curve.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on the curve")
+		}
+		return key, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %s", k.Kty)
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// KeySet returns the public key used to sign a token with the given key id
+type KeySet interface {
+	Key(kid string) (crypto.PublicKey, error)
+}
+
+// StaticKeySet is a key set that never changes, as loaded from a file
+type StaticKeySet map[string]crypto.PublicKey
+
+func (s StaticKeySet) Key(kid string) (crypto.PublicKey, error) {
+	key, ok := s[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	return key, nil
+}
+
+// LoadJWKSFile reads a key set from a local file
+func LoadJWKSFile(path string) (StaticKeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseJWKS(data)
+}
+
+// RemoteKeySet fetches the key set from a URL and fetches it again when a
+// token names an unknown key id, at most once per minRefresh so unknown ids
+// cannot be used to flood the identity provider. The fetch runs outside the
+// lock, known keys keep being served meanwhile and the requests missing a
+// key wait on the one fetch in flight
+type RemoteKeySet struct {
+	url        string
+	client     *http.Client
+	minRefresh time.Duration
+	mu         sync.Mutex
+	keys       map[string]crypto.PublicKey
+	fetchedAt  time.Time
+	// refreshing is closed once the fetch in flight ends, nil without one
+	refreshing chan struct{}
+	fetchErr   error
+}
+
+func NewRemoteKeySet(url string) *RemoteKeySet {
+	return &RemoteKeySet{url: url, client: &http.Client{Timeout: 10 * time.Second}, minRefresh: time.Minute}
+}
+
+func (s *RemoteKeySet) Key(kid string) (crypto.PublicKey, error) {
+	s.mu.Lock()
+	key, ok := s.keys[kid]
+	done, leader := s.refreshing, false
+	if !ok && done == nil && time.Since(s.fetchedAt) >= s.minRefresh {
+		done, leader = make(chan struct{}), true
+		s.refreshing, s.fetchedAt = done, time.Now()
+	}
+	s.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+	if done == nil {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if leader {
+		s.refresh(done)
+	}
+	<-done
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if key, ok := s.keys[kid]; ok {
+		return key, nil
+	}
+	if s.fetchErr != nil {
+		return nil, s.fetchErr
+	}
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+// refresh fetches the key set and hands the result over to the requests
+// waiting on done
+func (s *RemoteKeySet) refresh(done chan struct{}) {
+	keys, err := s.fetch()
+	s.mu.Lock()
+	if err == nil {
+		s.keys = keys
+	}
+	s.fetchErr = err
+	s.refreshing = nil
+	s.mu.Unlock()
+	close(done)
+}
+
+func (s *RemoteKeySet) fetch() (map[string]crypto.PublicKey, error) {
+	resp, err := s.client.Get(s.url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching jwks: %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	return ParseJWKS(data)
+}
+
+// LoadKeySet loads the key set from source, an http(s) URL or a file path
+func LoadKeySet(source string) (KeySet, error) {
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		return NewRemoteKeySet(source), nil
+	}
+	return LoadJWKSFile(source)
+}
@@ -0,0 +1,200 @@
+package auth
+
+import (
+	"crproductos/internal/models"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// JWTConfig describes which tokens the JWT authenticator accepts and how
+// their claims map to roles
+type JWTConfig struct {
+	Keys     KeySet
+	Issuer   string
+	Audience string
+	// RoleClaim names the claim holding the caller's groups or roles, either
+	// a string or a list of strings
+	RoleClaim string
+	// Roles maps values of RoleClaim to roles, the highest matching role wins
+	Roles map[string]models.Role
+	// Leeway tolerates clock skew when checking exp and nbf
+	Leeway time.Duration
+	now    func() time.Time
+}
+
+type jwtAuthenticator struct {
+	config JWTConfig
+}
+
+// JWT: Authenticates bearer tokens signed with RS256 or ES256 by one of the
+// keys of config.Keys. The issuer and audience must match and the token must
+// map to a role, otherwise it is rejected. Both are required, a key set may
+// sign tokens for other services too
+func JWT(config JWTConfig) (Authenticator, error) {
+	if config.Issuer == "" || config.Audience == "" {
+		return nil, errors.New("jwt issuer and audience are required")
+	}
+	if config.now == nil {
+		config.now = time.Now
+	}
+	if config.RoleClaim == "" {
+		config.RoleClaim = "roles"
+	}
+	return jwtAuthenticator{config: config}, nil
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+func (a jwtAuthenticator) Authenticate(r *http.Request) (Principal, error) {
+	token := BearerToken(r)
+	if token == "" || strings.HasPrefix(token, KeyPrefix) || strings.Count(token, ".") != 2 {
+		return Principal{}, ErrNoCredentials
+	}
+	claims, err := a.verify(token)
+	if err != nil {
+		return Principal{}, errors.Join(ErrInvalidCredentials, err)
+	}
+	if err := a.checkClaims(claims); err != nil {
+		return Principal{}, errors.Join(ErrInvalidCredentials, err)
+	}
+	role, ok := a.role(claims)
+	if !ok {
+		return Principal{}, errors.Join(ErrInvalidCredentials, errors.New("token maps to no role"))
+	}
+	subject, _ := claims["sub"].(string)
+	return Principal{Subject: "jwt:" + subject, Role: role}, nil
+}
+
+// verify checks the signature of token and returns its claims
+func (a jwtAuthenticator) verify(token string) (map[string]any, error) {
+	parts := strings.Split(token, ".")
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, err
+	}
+	key, err := a.config.Keys.Key(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	switch header.Alg {
+	case "RS256":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return nil, errors.New("key does not match RS256")
+		}
+		if err := rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, digest[:], signature); err != nil {
+			return nil, err
+		}
+	case "ES256":
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(signature) != 64 {
+			return nil, errors.New("key or signature does not match ES256")
+		}
+		rs, ss := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(ecKey, digest[:], rs, ss) {
+			return nil, errors.New("invalid signature")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", header.Alg)
+	}
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// checkClaims verifies exp, nbf, iss and aud
+func (a jwtAuthenticator) checkClaims(claims map[string]any) error {
+	now := a.config.now()
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return errors.New("token has no expiry")
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(a.config.Leeway)) {
+		return errors.New("token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(a.config.Leeway).Before(time.Unix(int64(nbf), 0)) {
+		return errors.New("token not valid yet")
+	}
+	if claims["iss"] != a.config.Issuer {
+		return errors.New("unexpected issuer")
+	}
+	if !slices.Contains(stringList(claims["aud"]), a.config.Audience) {
+		return errors.New("unexpected audience")
+	}
+	return nil
+}
+
+// role returns the highest role the values of the role claim map to
+func (a jwtAuthenticator) role(claims map[string]any) (models.Role, bool) {
+	var best models.Role
+	for _, value := range stringList(claims[a.config.RoleClaim]) {
+		role, ok := a.config.Roles[value]
+		if ok && role.Valid() && !best.Allows(role) {
+			best = role
+		}
+	}
+	return best, best != ""
+}
+
+// stringList reads a claim that is either a string or a list of strings
+func stringList(claim any) []string {
+	switch value := claim.(type) {
+	case string:
+		return []string{value}
+	case []any:
+		var list []string
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+// ParseRoleMap: Reads a role mapping written as "group=role,group=role", as
+// used in configuration files
+func ParseRoleMap(value string) (map[string]models.Role, error) {
+	roles := map[string]models.Role{}
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		claim, role, ok := strings.Cut(pair, "=")
+		if !ok || !models.Role(strings.TrimSpace(role)).Valid() {
+			return nil, fmt.Errorf("invalid role mapping %q", pair)
+		}
+		roles[strings.TrimSpace(claim)] = models.Role(strings.TrimSpace(role))
+	}
+	return roles, nil
+}
@@ -0,0 +1,246 @@
+package auth
+
+import (
+	"crproductos/internal/models"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var (
+	rsaKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _  = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+)
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// jwksJSON publishes the public halves of the test keys
+func jwksJSON(t *testing.T) []byte {
+	t.Helper()
+	data, err := json.Marshal(map[string]any{"keys": []map[string]string{
+		{"kty": "RSA", "kid": "rsa", "use": "sig", "n": encode(rsaKey.N.Bytes()), "e": encode([]byte{1, 0, 1})},
+		{"kty": "EC", "kid": "ec", "crv": "P-256", "x": encode(ecKey.X.FillBytes(make([]byte, 32))), "y": encode(ecKey.Y.FillBytes(make([]byte, 32)))},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func sign(t *testing.T, alg string, kid string, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := encode(header) + "." + encode(payload)
+	digest := sha256.Sum256([]byte(signed))
+	var signature []byte
+	var err error
+	switch alg {
+	case "RS256":
+		signature, err = rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+	case "ES256":
+		r, s, signErr := ecdsa.Sign(rand.Reader, ecKey, digest[:])
+		err = signErr
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + encode(signature)
+}
+
+func claims(overrides map[string]any) map[string]any {
+	c := map[string]any{
+		"sub":    "ana",
+		"iss":    "https://sso.example.cr",
+		"aud":    []string{"other", "crproductos"},
+		"exp":    time.Now().Add(time.Hour).Unix(),
+		"groups": []string{"staff", "pricing"},
+	}
+	for k, v := range overrides {
+		if v == nil {
+			delete(c, k)
+		} else {
+			c[k] = v
+		}
+	}
+	return c
+}
+
+func authenticateToken(a Authenticator, token string) (Principal, error) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	return a.Authenticate(req)
+}
+
+func TestJWT(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, jwksJSON(t), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := LoadKeySet(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roles, err := ParseRoleMap("staff=reader, pricing=price-collector,it=admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := JWT(JWTConfig{Keys: keys, Issuer: "https://sso.example.cr", Audience: "crproductos", RoleClaim: "groups", Roles: roles})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, alg := range []struct{ alg, kid string }{{"RS256", "rsa"}, {"ES256", "ec"}} {
+		principal, err := authenticateToken(a, sign(t, alg.alg, alg.kid, claims(nil)))
+		if err != nil {
+			t.Fatalf("%s: %v", alg.alg, err)
+		}
+		if principal.Role != models.RolePriceCollector || principal.Subject != "jwt:ana" {
+			t.Errorf("%s: got %+v", alg.alg, principal)
+		}
+	}
+
+	tampered := sign(t, "RS256", "rsa", claims(nil))
+	tampered = tampered[:len(tampered)-4] + "AAAA"
+	rejected := map[string]string{
+		"wrong issuer":   sign(t, "RS256", "rsa", claims(map[string]any{"iss": "https://evil.example"})),
+		"wrong audience": sign(t, "RS256", "rsa", claims(map[string]any{"aud": "other"})),
+		"no issuer":      sign(t, "RS256", "rsa", claims(map[string]any{"iss": nil})),
+		"no audience":    sign(t, "RS256", "rsa", claims(map[string]any{"aud": nil})),
+		"expired":        sign(t, "ES256", "ec", claims(map[string]any{"exp": time.Now().Add(-time.Minute).Unix()})),
+		"no expiry":      sign(t, "ES256", "ec", claims(map[string]any{"exp": nil})),
+		"not yet valid":  sign(t, "ES256", "ec", claims(map[string]any{"nbf": time.Now().Add(time.Hour).Unix()})),
+		"no role":        sign(t, "RS256", "rsa", claims(map[string]any{"groups": "visitors"})),
+		"unknown key":    sign(t, "RS256", "other", claims(nil)),
+		"key mismatch":   sign(t, "RS256", "ec", claims(nil)),
+		"bad signature":  tampered,
+		"alg none":       encode([]byte(`{"alg":"none","kid":"rsa"}`)) + "." + encode([]byte(`{"sub":"ana"}`)) + ".",
+	}
+	for name, token := range rejected {
+		if _, err := authenticateToken(a, token); !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("%s: got %v, want ErrInvalidCredentials", name, err)
+		}
+	}
+
+	for _, token := range []string{"", "crp_abc.def.ghi", "opaque"} {
+		if _, err := authenticateToken(a, token); !errors.Is(err, ErrNoCredentials) {
+			t.Errorf("%q: got %v, want ErrNoCredentials", token, err)
+		}
+	}
+
+	principal, err := authenticateToken(a, sign(t, "RS256", "rsa", claims(map[string]any{"groups": []string{"staff", "it"}})))
+	if err != nil || principal.Role != models.RoleAdmin {
+		t.Errorf("highest role: got %+v, %v", principal, err)
+	}
+}
+
+func TestRemoteKeySet(t *testing.T) {
+	fetches := 0
+	jwks := jwksJSON(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		w.Write(jwks)
+	}))
+	defer server.Close()
+
+	keys, err := LoadKeySet(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := JWT(JWTConfig{Keys: keys, Issuer: "https://sso.example.cr", Audience: "crproductos", Roles: map[string]models.Role{"staff": models.RoleReader}, RoleClaim: "groups"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := authenticateToken(a, sign(t, "ES256", "ec", claims(nil))); err != nil {
+		t.Fatal(err)
+	}
+	authenticateToken(a, sign(t, "RS256", "rsa", claims(nil)))
+	authenticateToken(a, sign(t, "RS256", "missing", claims(nil)))
+	if fetches != 1 {
+		t.Errorf("fetched the key set %d times, want 1", fetches)
+	}
+}
+
+func TestJWTRequiresIssuerAndAudience(t *testing.T) {
+	for _, config := range []JWTConfig{{Issuer: "https://sso.example.cr"}, {Audience: "crproductos"}} {
+		if _, err := JWT(config); err == nil {
+			t.Errorf("%+v: expected an error", config)
+		}
+	}
+}
+
+func TestRemoteKeySetServesKnownKeysWhileFetching(t *testing.T) {
+	jwks := jwksJSON(t)
+	release := make(chan struct{})
+	fetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fetches++; fetches > 1 {
+			<-release
+		}
+		w.Write(jwks)
+	}))
+	defer server.Close()
+	defer close(release)
+
+	keys := NewRemoteKeySet(server.URL)
+	keys.minRefresh = 0
+	if _, err := keys.Key("rsa"); err != nil {
+		t.Fatal(err)
+	}
+	missing := make(chan error)
+	go func() {
+		_, err := keys.Key("missing")
+		missing <- err
+	}()
+	for {
+		keys.mu.Lock()
+		fetching := keys.refreshing != nil
+		keys.mu.Unlock()
+		if fetching {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	known := make(chan error)
+	go func() {
+		_, err := keys.Key("ec")
+		known <- err
+	}()
+	select {
+	case err := <-known:
+		if err != nil {
+			t.Errorf("known key: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("a known key waited on the fetch in flight")
+	}
+	release <- struct{}{}
+	if err := <-missing; err == nil {
+		t.Error("expected the missing key to stay unknown")
+	}
+}
+
+func TestParseRoleMap(t *testing.T) {
+	if _, err := ParseRoleMap("staff=owner"); err == nil {
+		t.Error("unknown role accepted")
+	}
+	if _, err := ParseRoleMap("staff"); err == nil {
+		t.Error("mapping without role accepted")
+	}
+}
@@ -0,0 +1,142 @@
+// Package cache keeps serialized values for a while so repeated reads skip
+// the database. Stores are pluggable, LRU keeps them in process and a shared
+// store lets replicas see each other's invalidations
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// Store holds values by key until their ttl runs out or they are deleted. A
+// zero ttl keeps the value until it is deleted or evicted
+type Store interface {
+	Get(key string) ([]byte, bool, error)
+	Set(key string, value []byte, ttl time.Duration) error
+	Delete(keys ...string) error
+}
+
+// LRU is an in-process Store bounded to a number of entries, it evicts the
+// least recently used one when full
+type LRU struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+	now     func() time.Time
+}
+
+type lruEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// NewLRU creates a store holding up to size entries
+func NewLRU(size int) *LRU {
+	return &LRU{size: size, order: list.New(), entries: map[string]*list.Element{}, now: time.Now}
+}
+
+func (c *LRU) Get(key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := element.Value.(*lruEntry)
+	if !entry.expires.IsZero() && !c.now().Before(entry.expires) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return nil, false, nil
+	}
+	c.order.MoveToFront(element)
+	return entry.value, true, nil
+}
+
+func (c *LRU) Set(key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := &lruEntry{key: key, value: value}
+	if ttl > 0 {
+		entry.expires = c.now().Add(ttl)
+	}
+	if element, ok := c.entries[key]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return nil
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry).key)
+	}
+	return nil
+}
+
+func (c *LRU) Delete(keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		if element, ok := c.entries[key]; ok {
+			c.order.Remove(element)
+			delete(c.entries, key)
+		}
+	}
+	return nil
+}
+
+// Len returns the number of entries, expired ones included until read
+func (c *LRU) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// Status collects whether the cached reads made while serving a request
+// were hits, so the response can tell
+type Status struct {
+	mu     sync.Mutex
+	hits   int
+	misses int
+}
+
+type statusKey struct{}
+
+// WithStatus returns a context collecting the cache lookups made with it
+func WithStatus(ctx context.Context) (context.Context, *Status) {
+	status := &Status{}
+	return context.WithValue(ctx, statusKey{}, status), status
+}
+
+// Record counts a lookup on the Status of ctx, if there is one
+func Record(ctx context.Context, hit bool) {
+	status, ok := ctx.Value(statusKey{}).(*Status)
+	if !ok {
+		return
+	}
+	status.mu.Lock()
+	defer status.mu.Unlock()
+	if hit {
+		status.hits++
+	} else {
+		status.misses++
+	}
+}
+
+// String is "HIT" when every lookup hit, "MISS" when any missed and empty
+// without lookups
+func (s *Status) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case s.misses > 0:
+		return "MISS"
+	case s.hits > 0:
+		return "HIT"
+	}
+	return ""
+}
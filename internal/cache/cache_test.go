@@ -0,0 +1,49 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLRU(t *testing.T) {
+	now := time.Date(2026, time.October, 19, 8, 0, 0, 0, time.UTC)
+	c := NewLRU(2)
+	c.now = func() time.Time { return now }
+
+	c.Set("leche", []byte("990"), time.Minute)
+	c.Set("cafe", []byte("2150"), 0)
+	c.Get("leche")
+	c.Set("arroz", []byte("2290"), time.Minute)
+	if _, ok, _ := c.Get("cafe"); ok {
+		t.Error("the least recently used entry should have been evicted")
+	}
+	if value, ok, _ := c.Get("leche"); !ok || string(value) != "990" {
+		t.Errorf("got %q %v, want 990", value, ok)
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok, _ := c.Get("arroz"); ok || c.Len() != 1 {
+		t.Errorf("expired entry still served, %d entries", c.Len())
+	}
+	c.Delete("leche", "pepsi")
+	if c.Len() != 0 {
+		t.Errorf("got %d entries after delete", c.Len())
+	}
+}
+
+func TestStatus(t *testing.T) {
+	Record(context.Background(), true)
+	ctx, status := WithStatus(context.Background())
+	if status.String() != "" {
+		t.Errorf("got %q without lookups", status)
+	}
+	Record(ctx, true)
+	if status.String() != "HIT" {
+		t.Errorf("got %q, want HIT", status)
+	}
+	Record(ctx, false)
+	if status.String() != "MISS" {
+		t.Errorf("got %q, want MISS", status)
+	}
+}
@@ -9,27 +9,35 @@ import (
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 	"log"
+	"log/slog"
 	"os"
 	"strconv"
 )
 
-// ConnectToPostgres: Loads environment variables and then creates the connection string for postgres
-// Returns the sql DB object from database/Sql
-func ConnectToPostgres() *sql.DB {
+// LoadEnv: Loads the environment variables from config.development.env,
+// variables already set take precedence
+func LoadEnv() {
 	err := godotenv.Load("config.development.env")
 	if err != nil {
 		log.Fatalf("Error loading .env files: %v", err)
 	}
-	dbhost := os.Getenv("DB_HOST")
-	dbportStr := os.Getenv("DB_PORT")
-	dbuser := os.Getenv("DB_USER")
-	dbpassword := os.Getenv("DB_PASSWORD")
-	dbname := os.Getenv("DB_NAME")
-	dbport, err := strconv.Atoi(dbportStr)
+}
+
+// ConnInfo builds the lib/pq connection string from the DB_* variables
+func ConnInfo() string {
+	dbport, err := strconv.Atoi(os.Getenv("DB_PORT"))
 	if err != nil {
 		log.Fatalf("error converting: %v", err)
 	}
-	psqlInfo := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable", dbhost, dbport, dbuser, dbpassword, dbname)
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
+		os.Getenv("DB_HOST"), dbport, os.Getenv("DB_USER"), os.Getenv("DB_PASSWORD"), os.Getenv("DB_NAME"))
+}
+
+// ConnectToPostgres: Loads environment variables and then creates the connection string for postgres
+// Returns the sql DB object from database/Sql
+func ConnectToPostgres() *sql.DB {
+	LoadEnv()
+	psqlInfo := ConnInfo()
 
 	db, err := sql.Open("postgres", psqlInfo)
 	if err != nil {
@@ -40,6 +48,6 @@ func ConnectToPostgres() *sql.DB {
 	if err != nil {
 		log.Fatal("failed to ping postgres", err)
 	}
-	fmt.Println("connected to postgres")
+	slog.Info("connected to postgres", "host", os.Getenv("DB_HOST"), "port", os.Getenv("DB_PORT"), "dbname", os.Getenv("DB_NAME"))
 	return db
 }
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS public.product (
+	id serial PRIMARY KEY,
+	"name" text,
+	quantity double precision,
+	unit text,
+	stores jsonb NOT NULL DEFAULT '{}'::jsonb
+);
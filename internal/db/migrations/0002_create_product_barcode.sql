@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS public.product_barcode (
+	barcode text PRIMARY KEY,
+	product_id integer NOT NULL REFERENCES public.product (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS product_barcode_product_id_idx ON public.product_barcode (product_id);
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS public.category (
+	id serial PRIMARY KEY,
+	"name" text NOT NULL,
+	parent_id integer REFERENCES public.category (id)
+);
+
+CREATE INDEX IF NOT EXISTS category_parent_id_idx ON public.category (parent_id);
+
+CREATE TABLE IF NOT EXISTS public.product_category (
+	product_id integer NOT NULL REFERENCES public.product (id) ON DELETE CASCADE,
+	category_id integer NOT NULL REFERENCES public.category (id) ON DELETE CASCADE,
+	PRIMARY KEY (product_id, category_id)
+);
+
+CREATE TABLE IF NOT EXISTS public.tag (
+	id serial PRIMARY KEY,
+	"name" text NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS public.product_tag (
+	product_id integer NOT NULL REFERENCES public.product (id) ON DELETE CASCADE,
+	tag_id integer NOT NULL REFERENCES public.tag (id) ON DELETE CASCADE,
+	PRIMARY KEY (product_id, tag_id)
+);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS public.brand (
+	id serial PRIMARY KEY,
+	"name" text NOT NULL UNIQUE
+);
+
+ALTER TABLE public.product ADD COLUMN IF NOT EXISTS brand_id integer REFERENCES public.brand (id) ON DELETE SET NULL;
+ALTER TABLE public.product ADD COLUMN IF NOT EXISTS parent_id integer REFERENCES public.product (id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS product_brand_id_idx ON public.product (brand_id);
+CREATE INDEX IF NOT EXISTS product_parent_id_idx ON public.product (parent_id);
@@ -0,0 +1,13 @@
+-- currencies maps a store to the currency its price is listed in, stores
+-- missing from it are priced in colones (CRC)
+ALTER TABLE public.product ADD COLUMN IF NOT EXISTS currencies jsonb NOT NULL DEFAULT '{}'::jsonb;
+
+-- One unit of base is worth rate units of quote starting on effective_date
+CREATE TABLE IF NOT EXISTS public.exchange_rate (
+	id serial PRIMARY KEY,
+	base char(3) NOT NULL,
+	quote char(3) NOT NULL,
+	rate double precision NOT NULL CHECK (rate > 0),
+	effective_date date NOT NULL,
+	UNIQUE (base, quote, effective_date)
+);
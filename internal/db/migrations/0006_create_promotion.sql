@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS public.promotion (
+	id serial PRIMARY KEY,
+	product_id integer NOT NULL REFERENCES public.product (id) ON DELETE CASCADE,
+	store text NOT NULL,
+	"type" text NOT NULL CHECK ("type" IN ('percentage', 'fixed', 'multi_buy')),
+	value double precision NOT NULL DEFAULT 0,
+	buy integer NOT NULL DEFAULT 0,
+	pay integer NOT NULL DEFAULT 0,
+	starts_at timestamptz NOT NULL,
+	ends_at timestamptz,
+	conditions jsonb NOT NULL DEFAULT '{}'::jsonb,
+	description text NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS promotion_product_id_idx ON public.promotion (product_id, store);
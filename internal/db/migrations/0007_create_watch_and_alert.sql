@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS public.watch (
+	id serial PRIMARY KEY,
+	subscriber text NOT NULL,
+	product_id integer NOT NULL REFERENCES public.product (id) ON DELETE CASCADE,
+	-- a NULL store watches every store selling the product
+	store text,
+	target_price double precision NOT NULL CHECK (target_price > 0),
+	created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS watch_product_id_idx ON public.watch (product_id);
+CREATE INDEX IF NOT EXISTS watch_subscriber_idx ON public.watch (subscriber);
+
+CREATE TABLE IF NOT EXISTS public.alert (
+	id serial PRIMARY KEY,
+	watch_id integer NOT NULL REFERENCES public.watch (id) ON DELETE CASCADE,
+	subscriber text NOT NULL,
+	product_id integer NOT NULL REFERENCES public.product (id) ON DELETE CASCADE,
+	store text NOT NULL,
+	price double precision NOT NULL,
+	previous_price double precision,
+	target_price double precision NOT NULL,
+	created_at timestamptz NOT NULL DEFAULT now(),
+	read_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS alert_subscriber_idx ON public.alert (subscriber, created_at DESC);
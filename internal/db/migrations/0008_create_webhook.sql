@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS public.webhook (
+	id serial PRIMARY KEY,
+	url text NOT NULL,
+	secret text NOT NULL,
+	-- an empty list subscribes to every event type
+	events jsonb NOT NULL DEFAULT '[]'::jsonb,
+	active boolean NOT NULL DEFAULT true,
+	created_at timestamptz NOT NULL DEFAULT now()
+);
+
+-- One row per delivery attempt
+CREATE TABLE IF NOT EXISTS public.webhook_delivery (
+	id serial PRIMARY KEY,
+	webhook_id integer NOT NULL REFERENCES public.webhook (id) ON DELETE CASCADE,
+	event_id text NOT NULL,
+	event_type text NOT NULL,
+	payload jsonb NOT NULL,
+	attempt integer NOT NULL,
+	status_code integer,
+	error text NOT NULL DEFAULT '',
+	success boolean NOT NULL,
+	created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS webhook_delivery_webhook_id_idx ON public.webhook_delivery (webhook_id, created_at DESC);
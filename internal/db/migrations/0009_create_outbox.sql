@@ -0,0 +1,16 @@
+-- Events written in the same transaction as the product change they describe,
+-- the outbox dispatcher publishes them in id order and stamps published_at
+CREATE TABLE IF NOT EXISTS public.outbox (
+	id bigserial PRIMARY KEY,
+	event_id text NOT NULL UNIQUE,
+	event_type text NOT NULL,
+	product_id integer NOT NULL,
+	store text NOT NULL DEFAULT '',
+	data jsonb NOT NULL,
+	occurred_at timestamptz NOT NULL,
+	attempts integer NOT NULL DEFAULT 0,
+	last_error text NOT NULL DEFAULT '',
+	published_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS outbox_pending_idx ON public.outbox (id) WHERE published_at IS NULL;
@@ -0,0 +1,10 @@
+-- Only the sha256 hash of a key is stored, prefix identifies it in listings
+CREATE TABLE IF NOT EXISTS public.api_key (
+	id serial PRIMARY KEY,
+	"name" text NOT NULL,
+	prefix text NOT NULL UNIQUE,
+	hash text NOT NULL UNIQUE,
+	"role" text NOT NULL CHECK ("role" IN ('reader', 'price-collector', 'admin')),
+	created_at timestamptz NOT NULL DEFAULT now(),
+	revoked_at timestamptz
+);
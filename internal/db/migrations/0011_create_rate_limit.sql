@@ -0,0 +1,6 @@
+-- Token buckets shared by every replica, see ratelimit.Bucket
+CREATE UNLOGGED TABLE IF NOT EXISTS public.rate_limit_bucket (
+	"key" text PRIMARY KEY,
+	tokens double precision NOT NULL,
+	updated_at timestamptz NOT NULL
+);
@@ -0,0 +1,17 @@
+-- Every store price a product had, written along with the product change.
+-- A null price means the store stopped listing the product
+CREATE TABLE IF NOT EXISTS public.price_history (
+	id bigserial PRIMARY KEY,
+	product_id integer NOT NULL REFERENCES public.product (id) ON DELETE CASCADE,
+	store text NOT NULL,
+	price double precision,
+	recorded_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS price_history_product_idx ON public.price_history (product_id, recorded_at);
+
+-- Start the history with the prices known today
+INSERT INTO public.price_history (product_id, store, price)
+SELECT product.id, s.key, s.value::double precision
+FROM public.product, jsonb_each_text(product.stores) s
+WHERE jsonb_typeof(product.stores) = 'object';
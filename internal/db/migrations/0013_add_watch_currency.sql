@@ -0,0 +1,4 @@
+-- the currency target_price is given in, store prices are converted to it
+-- before comparing and alerts report the converted prices
+ALTER TABLE public.watch ADD COLUMN IF NOT EXISTS currency text NOT NULL DEFAULT 'CRC';
+ALTER TABLE public.alert ADD COLUMN IF NOT EXISTS currency text NOT NULL DEFAULT 'CRC';
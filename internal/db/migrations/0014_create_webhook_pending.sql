@@ -0,0 +1,15 @@
+-- Deliveries waiting to be sent or retried, a row is removed once delivered
+-- or out of attempts. Queuing an event twice for a webhook is a no-op, the
+-- outbox relay publishes at least once
+CREATE TABLE IF NOT EXISTS public.webhook_pending (
+	id bigserial PRIMARY KEY,
+	webhook_id integer NOT NULL REFERENCES public.webhook (id) ON DELETE CASCADE,
+	event_id text NOT NULL,
+	event_type text NOT NULL,
+	payload jsonb NOT NULL,
+	attempt integer NOT NULL DEFAULT 0,
+	next_attempt_at timestamptz NOT NULL DEFAULT now(),
+	UNIQUE (webhook_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS webhook_pending_next_attempt_at_idx ON public.webhook_pending (next_attempt_at);
@@ -0,0 +1,115 @@
+package events
+
+import (
+	"sync"
+)
+
+// Filter narrows the events a subscriber receives, zero values match everything
+type Filter struct {
+	ProductId int
+	Store     string
+}
+
+// Matches reports whether the event passes the filter
+func (f Filter) Matches(event Event) bool {
+	if f.ProductId != 0 && event.ProductId != f.ProductId {
+		return false
+	}
+	if f.Store != "" && event.Store != f.Store {
+		return false
+	}
+	return true
+}
+
+// Broker is an in-memory Publisher fanning events out to live subscribers.
+// It keeps the last events in a bounded buffer so reconnecting subscribers
+// can resume from the last event they saw
+type Broker struct {
+	mu          sync.Mutex
+	buffer      []Event
+	size        int
+	next        int
+	subscribers map[*subscription]struct{}
+}
+
+type subscription struct {
+	filter Filter
+	events chan Event
+}
+
+// NewBroker creates a broker remembering the last size events
+func NewBroker(size int) *Broker {
+	return &Broker{
+		buffer:      make([]Event, 0, size),
+		size:        size,
+		subscribers: map[*subscription]struct{}{},
+	}
+}
+
+// Publish: Buffers the event and hands it to every matching subscriber.
+// Subscribers too slow to keep up miss the event rather than block writes
+func (b *Broker) Publish(event Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.size > 0 {
+		if len(b.buffer) < b.size {
+			b.buffer = append(b.buffer, event)
+		} else {
+			b.buffer[b.next] = event
+		}
+		b.next = (b.next + 1) % b.size
+	}
+	for sub := range b.subscribers {
+		if !sub.filter.Matches(event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+		}
+	}
+	return nil
+}
+
+// buffered returns the buffered events from oldest to newest
+func (b *Broker) buffered() []Event {
+	if len(b.buffer) < b.size {
+		return append([]Event(nil), b.buffer...)
+	}
+	return append(append([]Event(nil), b.buffer[b.next:]...), b.buffer[:b.next]...)
+}
+
+// Subscribe: Registers a subscriber and returns the buffered events it missed
+// after lastEventId along with the channel of new events. An empty or
+// unknown lastEventId replays nothing or the whole buffer respectively. The
+// returned function unsubscribes and must be called once done
+func (b *Broker) Subscribe(filter Filter, lastEventId string) ([]Event, <-chan Event, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var missed []Event
+	if lastEventId != "" {
+		buffered := b.buffered()
+		start := 0
+		for i, event := range buffered {
+			if event.Id == lastEventId {
+				start = i + 1
+				break
+			}
+		}
+		for _, event := range buffered[start:] {
+			if filter.Matches(event) {
+				missed = append(missed, event)
+			}
+		}
+	}
+	sub := &subscription{filter: filter, events: make(chan Event, 64)}
+	b.subscribers[sub] = struct{}{}
+	var once sync.Once
+	return missed, sub.events, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			delete(b.subscribers, sub)
+		})
+	}
+}
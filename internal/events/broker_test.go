@@ -0,0 +1,69 @@
+package events
+
+import (
+	"testing"
+)
+
+func publish(t *testing.T, b *Broker, productId int, store string) Event {
+	t.Helper()
+	event, err := New(PriceChanged, productId, store, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Publish(event)
+	return event
+}
+
+func TestBrokerFiltersLiveEvents(t *testing.T) {
+	b := NewBroker(10)
+	_, live, cancel := b.Subscribe(Filter{ProductId: 2}, "")
+	defer cancel()
+	publish(t, b, 1, "walmart")
+	want := publish(t, b, 2, "walmart")
+	got := <-live
+	if got.Id != want.Id {
+		t.Errorf("got event of product %d, want product 2", got.ProductId)
+	}
+	select {
+	case extra := <-live:
+		t.Errorf("unexpected event %+v", extra)
+	default:
+	}
+}
+
+func TestBrokerResumesFromLastEventId(t *testing.T) {
+	b := NewBroker(3)
+	first := publish(t, b, 1, "walmart")
+	second := publish(t, b, 1, "automercado")
+	third := publish(t, b, 1, "walmart")
+
+	missed, _, cancel := b.Subscribe(Filter{}, first.Id)
+	cancel()
+	if len(missed) != 2 || missed[0].Id != second.Id || missed[1].Id != third.Id {
+		t.Errorf("missed = %+v", missed)
+	}
+
+	missed, _, cancel = b.Subscribe(Filter{Store: "walmart"}, first.Id)
+	cancel()
+	if len(missed) != 1 || missed[0].Id != third.Id {
+		t.Errorf("missed with store filter = %+v", missed)
+	}
+
+	// first falls out of the buffer, an unknown id replays everything kept
+	fourth := publish(t, b, 1, "walmart")
+	missed, _, cancel = b.Subscribe(Filter{}, first.Id)
+	cancel()
+	if len(missed) != 3 || missed[0].Id != second.Id || missed[2].Id != fourth.Id {
+		t.Errorf("missed after eviction = %+v", missed)
+	}
+}
+
+func TestBrokerUnsubscribe(t *testing.T) {
+	b := NewBroker(1)
+	_, _, cancel := b.Subscribe(Filter{}, "")
+	cancel()
+	cancel()
+	if len(b.subscribers) != 0 {
+		t.Errorf("%d subscribers left", len(b.subscribers))
+	}
+}
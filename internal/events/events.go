@@ -0,0 +1,85 @@
+// Package events describes the product and price events published by the
+// service layer and the Publisher interface their consumers implement
+package events
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"time"
+)
+
+const (
+	ProductCreated = "product.created"
+	ProductUpdated = "product.updated"
+	ProductDeleted = "product.deleted"
+	PriceChanged   = "price.changed"
+)
+
+// Types lists every event type that can be published
+var Types = []string{ProductCreated, ProductUpdated, ProductDeleted, PriceChanged}
+
+// Event is a change to the catalog. Id is unique per event so consumers can
+// tell redeliveries apart from new events
+type Event struct {
+	Id         string          `json:"id"`
+	Type       string          `json:"type"`
+	OccurredAt time.Time       `json:"occurredAt"`
+	ProductId  int             `json:"productId"`
+	Store      string          `json:"store,omitempty"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// PriceChange is the Data of a price.changed event, a nil price means the
+// store did not list the product
+type PriceChange struct {
+	Store         string   `json:"store"`
+	PreviousPrice *float64 `json:"previousPrice"`
+	Price         *float64 `json:"price"`
+}
+
+// Publisher hands events over to a consumer
+type Publisher interface {
+	Publish(event Event) error
+}
+
+// New: Builds an event of the given type with a fresh id, data is marshalled
+// into the Data field
+func New(eventType string, productId int, store string, data any) (Event, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, err
+	}
+	return Event{
+		Id:         NewId(),
+		Type:       eventType,
+		OccurredAt: time.Now().UTC(),
+		ProductId:  productId,
+		Store:      store,
+		Data:       payload,
+	}, nil
+}
+
+// NewId returns a random 128 bit hex identifier
+func NewId() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		slog.Error("failed to read random bytes", "err", err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// Multi publishes every event to several publishers, it keeps going when
+// one of them fails and returns the first error
+type Multi []Publisher
+
+func (m Multi) Publish(event Event) error {
+	var first error
+	for _, publisher := range m {
+		if err := publisher.Publish(event); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
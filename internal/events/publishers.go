@@ -0,0 +1,59 @@
+package events
+
+import (
+	"container/list"
+	"log/slog"
+	"sync"
+)
+
+// Channel publishes events to an in-process channel, blocking until the
+// receiver takes them
+type Channel chan Event
+
+func (c Channel) Publish(event Event) error {
+	c <- event
+	return nil
+}
+
+// LogPublisher writes every event to the standard logger
+type LogPublisher struct{}
+
+func (LogPublisher) Publish(event Event) error {
+	slog.Info("event", "event_id", event.Id, "type", event.Type, "product_id", event.ProductId, "store", event.Store, "data", event.Data)
+	return nil
+}
+
+// Dedup wraps a publisher and drops events whose id was already published,
+// remembering the last size ids. Delivery from the outbox is at least once,
+// Dedup turns the redeliveries into no-ops for in-process consumers
+type Dedup struct {
+	next  Publisher
+	size  int
+	mu    sync.Mutex
+	seen  map[string]*list.Element
+	order *list.List
+}
+
+func NewDedup(next Publisher, size int) *Dedup {
+	return &Dedup{next: next, size: size, seen: map[string]*list.Element{}, order: list.New()}
+}
+
+// Publish holds the lock from the lookup to the insert, so the same event
+// published twice at once reaches next only once
+func (d *Dedup) Publish(event Event) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.seen[event.Id]; ok {
+		return nil
+	}
+	if err := d.next.Publish(event); err != nil {
+		return err
+	}
+	d.seen[event.Id] = d.order.PushBack(event.Id)
+	for d.order.Len() > d.size {
+		oldest := d.order.Front()
+		d.order.Remove(oldest)
+		delete(d.seen, oldest.Value.(string))
+	}
+	return nil
+}
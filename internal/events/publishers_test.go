@@ -0,0 +1,69 @@
+package events
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+type countingPublisher struct {
+	published []string
+	fail      bool
+}
+
+func (c *countingPublisher) Publish(event Event) error {
+	if c.fail {
+		return errors.New("unavailable")
+	}
+	c.published = append(c.published, event.Id)
+	return nil
+}
+
+func TestDedup(t *testing.T) {
+	next := &countingPublisher{}
+	dedup := NewDedup(next, 2)
+	a, b, c := Event{Id: "a"}, Event{Id: "b"}, Event{Id: "c"}
+	for _, event := range []Event{a, a, b, a, c, a} {
+		dedup.Publish(event)
+	}
+	// a is forgotten once b and c are remembered, so its last redelivery passes
+	want := []string{"a", "b", "c", "a"}
+	if len(next.published) != len(want) {
+		t.Fatalf("published %v, want %v", next.published, want)
+	}
+	for i := range want {
+		if next.published[i] != want[i] {
+			t.Fatalf("published %v, want %v", next.published, want)
+		}
+	}
+}
+
+func TestDedupRetriesFailures(t *testing.T) {
+	next := &countingPublisher{fail: true}
+	dedup := NewDedup(next, 10)
+	if err := dedup.Publish(Event{Id: "a"}); err == nil {
+		t.Fatal("expected the failure to be returned")
+	}
+	next.fail = false
+	dedup.Publish(Event{Id: "a"})
+	if len(next.published) != 1 {
+		t.Errorf("failed event was remembered as published: %v", next.published)
+	}
+}
+
+func TestDedupConcurrentRedeliveries(t *testing.T) {
+	next := &countingPublisher{}
+	dedup := NewDedup(next, 10)
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			dedup.Publish(Event{Id: "a"})
+		}()
+	}
+	wg.Wait()
+	if len(next.published) != 1 {
+		t.Errorf("published %v, want a single a", next.published)
+	}
+}
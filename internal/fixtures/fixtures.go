@@ -0,0 +1,168 @@
+// Package fixtures loads sets of products described in JSON into the
+// catalog, for tests, demos and fresh installs
+package fixtures
+
+import (
+	"bytes"
+	"context"
+	"crproductos/internal/models"
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+//go:embed costa_rica.json
+var costaRica []byte
+
+var ErrInvalidFixture = errors.New("invalid fixture")
+
+// Product is a product of a fixture set. Key names it within the set so
+// variants can point at their Parent before either has an id
+type Product struct {
+	Key        string                 `json:"key"`
+	Name       string                 `json:"name"`
+	Quantity   *float64               `json:"quantity,omitempty"`
+	Unit       string                 `json:"unit,omitempty"`
+	Barcodes   models.Barcodes        `json:"barcodes,omitempty"`
+	Stores     models.Stores          `json:"stores,omitempty"`
+	Currencies models.StoreCurrencies `json:"currencies,omitempty"`
+	Parent     string                 `json:"parent,omitempty"`
+}
+
+// Set is the content of a fixture file, {"products": [...]}
+type Set struct {
+	Products []Product `json:"products"`
+}
+
+// Parse: Reads and validates a fixture set. Keys must be unique and parents
+// must come before their variants and not be variants themselves
+func Parse(r io.Reader) (Set, error) {
+	var set Set
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&set); err != nil {
+		return set, errors.Join(ErrInvalidFixture, err)
+	}
+	return set, set.Validate()
+}
+
+// Validate checks the rules Parse enforces on a set built in code
+func (s Set) Validate() error {
+	seen := map[string]Product{}
+	for i, product := range s.Products {
+		if product.Key == "" || product.Name == "" {
+			return fmt.Errorf("%w: product %d needs a key and a name", ErrInvalidFixture, i)
+		}
+		if _, ok := seen[product.Key]; ok {
+			return fmt.Errorf("%w: key %q is repeated", ErrInvalidFixture, product.Key)
+		}
+		if err := product.Barcodes.Validate(); err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrInvalidFixture, product.Key, err)
+		}
+		if product.Parent != "" {
+			parent, ok := seen[product.Parent]
+			if !ok {
+				return fmt.Errorf("%w: parent %q of %s must come before it", ErrInvalidFixture, product.Parent, product.Key)
+			}
+			if parent.Parent != "" {
+				return fmt.Errorf("%w: parent %q of %s is a variant itself", ErrInvalidFixture, product.Parent, product.Key)
+			}
+		}
+		seen[product.Key] = product
+	}
+	return nil
+}
+
+// CostaRica is the bundled dataset, the usual groceries of the Costa Rican
+// supermarkets with their sizes and shelf prices in colones (USD where the
+// store lists them so). The barcodes are made up but valid
+func CostaRica() Set {
+	set, err := Parse(bytes.NewReader(costaRica))
+	if err != nil {
+		panic(err)
+	}
+	return set
+}
+
+// Target is where Load writes, satisfied by ProductRepository and by
+// ProductService. Only the Id of what GetProductByBarcode returns is read
+type Target interface {
+	GetProductByBarcode(ctx context.Context, barcode string) (models.Product, error)
+	CreateProduct(ctx context.Context, product models.ProductResponse) (models.ProductResponse, error)
+}
+
+// Load: Creates the products of set in order and returns their ids by key.
+// A product whose barcode is already in the catalog is left as it is, so
+// loading the same set twice creates nothing the second time
+func Load(ctx context.Context, target Target, set Set) (map[string]int, error) {
+	ids := map[string]int{}
+	for _, product := range set.Products {
+		id, err := existing(ctx, target, product.Barcodes)
+		if err != nil {
+			return ids, fmt.Errorf("%s: %w", product.Key, err)
+		}
+		if id == 0 {
+			created, err := target.CreateProduct(ctx, product.toModel(ids))
+			if err != nil {
+				return ids, fmt.Errorf("%s: %w", product.Key, err)
+			}
+			id = created.Id
+		}
+		ids[product.Key] = id
+	}
+	return ids, nil
+}
+
+// existing returns the id of the product holding one of barcodes, 0 if none
+func existing(ctx context.Context, target Target, barcodes models.Barcodes) (int, error) {
+	for _, barcode := range barcodes {
+		product, err := target.GetProductByBarcode(ctx, barcode)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		return product.Id, nil
+	}
+	return 0, nil
+}
+
+// Products returns set as the catalog would list it after loading it into
+// an empty database, with ids counting from 1. Handy to back test doubles
+func Products(set Set) []models.ProductResponse {
+	ids := map[string]int{}
+	products := make([]models.ProductResponse, 0, len(set.Products))
+	for i, product := range set.Products {
+		ids[product.Key] = i + 1
+		model := product.toModel(ids)
+		model.Id = i + 1
+		products = append(products, model)
+	}
+	return products
+}
+
+func (p Product) toModel(ids map[string]int) models.ProductResponse {
+	product := models.ProductResponse{
+		Name:     &p.Name,
+		Quantity: p.Quantity,
+		Barcodes: p.Barcodes,
+	}
+	if p.Unit != "" {
+		product.Unit = &p.Unit
+	}
+	if p.Stores != nil {
+		product.Stores = &p.Stores
+	}
+	if p.Currencies != nil {
+		product.Currencies = &p.Currencies
+	}
+	if p.Parent != "" {
+		parentId := ids[p.Parent]
+		product.ParentId = &parentId
+	}
+	return product
+}
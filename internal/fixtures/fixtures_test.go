@@ -0,0 +1,79 @@
+package fixtures
+
+import (
+	"context"
+	"crproductos/internal/models"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// memoryTarget stores the created products in memory
+type memoryTarget struct {
+	products []models.ProductResponse
+}
+
+func (m *memoryTarget) GetProductByBarcode(ctx context.Context, barcode string) (models.Product, error) {
+	for _, product := range m.products {
+		for _, code := range product.Barcodes {
+			if code == barcode {
+				return models.Product{Id: product.Id}, nil
+			}
+		}
+	}
+	return models.Product{}, sql.ErrNoRows
+}
+
+func (m *memoryTarget) CreateProduct(ctx context.Context, product models.ProductResponse) (models.ProductResponse, error) {
+	product.Id = len(m.products) + 1
+	m.products = append(m.products, product)
+	return product, nil
+}
+
+func TestLoadCostaRica(t *testing.T) {
+	set := CostaRica()
+	if len(set.Products) < 20 {
+		t.Fatalf("expected a full dataset, got %d products", len(set.Products))
+	}
+	target := &memoryTarget{}
+	ids, err := Load(context.Background(), target, set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(target.products) != len(set.Products) || len(ids) != len(set.Products) {
+		t.Fatalf("created %d products for %d fixtures", len(target.products), len(set.Products))
+	}
+	bigMilk := target.products[ids["leche-entera-1-8l"]-1]
+	if bigMilk.ParentId == nil || *bigMilk.ParentId != ids["leche-entera-1l"] {
+		t.Errorf("variant not linked to its parent: %+v", bigMilk)
+	}
+	if coffee := target.products[ids["cafe-britt-340g"]-1]; coffee.Currencies.Of("pricesmart") != "USD" {
+		t.Errorf("unexpected currencies %+v", coffee.Currencies)
+	}
+
+	// a second load finds every product by barcode
+	again, err := Load(context.Background(), target, set)
+	if err != nil || len(target.products) != len(set.Products) || again["pepsi-3l"] != ids["pepsi-3l"] {
+		t.Errorf("second load created products: %d %v", len(target.products), err)
+	}
+
+	if products := Products(set); len(products) != len(set.Products) || *products[1].ParentId != 1 || products[1].Id != 2 {
+		t.Errorf("unexpected Products %+v", products[1])
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, fixture := range []string{
+		`{"products": [{"key": "a"}]}`,
+		`{"products": [{"key": "a", "name": "A"}, {"key": "a", "name": "B"}]}`,
+		`{"products": [{"key": "b", "name": "B", "parent": "a"}, {"key": "a", "name": "A"}]}`,
+		`{"products": [{"key": "a", "name": "A"}, {"key": "b", "name": "B", "parent": "a"}, {"key": "c", "name": "C", "parent": "b"}]}`,
+		`{"products": [{"key": "a", "name": "A", "barcodes": ["7441000010071"]}]}`,
+		`{"products": [{"key": "a", "name": "A", "price": 100}]}`,
+	} {
+		if _, err := Parse(strings.NewReader(fixture)); !errors.Is(err, ErrInvalidFixture) {
+			t.Errorf("%s: got %v, want ErrInvalidFixture", fixture, err)
+		}
+	}
+}
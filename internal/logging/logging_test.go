@@ -0,0 +1,49 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRedactDSN(t *testing.T) {
+	cases := map[string]string{
+		"host=db port=5432 user=app password=hunter2 dbname=crproductos sslmode=disable": "host=db port=5432 user=app password=[REDACTED] dbname=crproductos sslmode=disable",
+		"host=db password='with space' dbname=x":                                         "host=db password=[REDACTED] dbname=x",
+		"postgres://app:hunter2@db:5432/crproductos?sslmode=disable":                     "postgres://app:[REDACTED]@db:5432/crproductos?sslmode=disable",
+		"postgres://app@db/crproductos":                                                  "postgres://app@db/crproductos",
+	}
+	for dsn, want := range cases {
+		if got := RedactDSN(dsn); got != want {
+			t.Errorf("RedactDSN(%q) = %q, want %q", dsn, got, want)
+		}
+	}
+}
+
+func TestSetup(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+	var out bytes.Buffer
+	logger := Setup(&out, "WARN")
+
+	ctx := WithRequestID(context.Background(), "req-1")
+	logger.InfoContext(ctx, "dropped")
+	logger.WarnContext(ctx, "connecting", "dsn", "host=db password=hunter2", "secret", "s3cr3t")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("got %d lines, want 1:\n%s", len(lines), out.String())
+	}
+	var record map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record["request_id"] != "req-1" || record["dsn"] != "host=db password=[REDACTED]" || record["secret"] != "[REDACTED]" {
+		t.Errorf("unexpected record %v", record)
+	}
+	if ParseLevel("nonsense") != slog.LevelInfo || ParseLevel("debug") != slog.LevelDebug {
+		t.Error("unexpected level parsing")
+	}
+}
@@ -0,0 +1,30 @@
+package metrics
+
+import (
+	"database/sql"
+)
+
+// RegisterDBStats exposes the connection pool statistics of db
+func RegisterDBStats(r *Registry, db *sql.DB) {
+	stat := func(read func(sql.DBStats) float64) func() (float64, error) {
+		return func() (float64, error) {
+			return read(db.Stats()), nil
+		}
+	}
+	r.NewGaugeFunc("crproductos_db_max_open_connections", "Maximum number of open connections to the database.",
+		stat(func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }))
+	r.NewGaugeFunc("crproductos_db_open_connections", "Number of established connections, in use and idle.",
+		stat(func(s sql.DBStats) float64 { return float64(s.OpenConnections) }))
+	r.NewGaugeFunc("crproductos_db_in_use_connections", "Number of connections currently in use.",
+		stat(func(s sql.DBStats) float64 { return float64(s.InUse) }))
+	r.NewGaugeFunc("crproductos_db_idle_connections", "Number of idle connections.",
+		stat(func(s sql.DBStats) float64 { return float64(s.Idle) }))
+	r.NewCounterFunc("crproductos_db_wait_count_total", "Number of connections waited for.",
+		stat(func(s sql.DBStats) float64 { return float64(s.WaitCount) }))
+	r.NewCounterFunc("crproductos_db_wait_duration_seconds_total", "Time spent waiting for a connection.",
+		stat(func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }))
+	r.NewCounterFunc("crproductos_db_max_idle_closed_total", "Connections closed because of the idle limit.",
+		stat(func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) }))
+	r.NewCounterFunc("crproductos_db_max_lifetime_closed_total", "Connections closed because of their maximum lifetime.",
+		stat(func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) }))
+}
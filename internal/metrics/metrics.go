@@ -0,0 +1,277 @@
+// Package metrics is a small Prometheus client. It keeps counters,
+// histograms and gauges read at scrape time, and writes them in the
+// Prometheus text exposition format
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBuckets are the histogram buckets in seconds, from 1ms to 10s
+var DefaultBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Registry holds the metrics of the service
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+}
+
+type metric interface {
+	name() string
+	write(w io.Writer)
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.metrics {
+		if existing.name() == m.name() {
+			panic("metrics: " + m.name() + " registered twice")
+		}
+	}
+	r.metrics = append(r.metrics, m)
+}
+
+// Write writes every metric, sorted by name, in the text exposition format
+func (r *Registry) Write(w io.Writer) {
+	r.mu.Lock()
+	metrics := append([]metric(nil), r.metrics...)
+	r.mu.Unlock()
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].name() < metrics[j].name() })
+	for _, m := range metrics {
+		m.write(w)
+	}
+}
+
+// Handler serves the metrics to Prometheus
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.Write(w)
+	})
+}
+
+// labelSet renders label names and values as {a="x",b="y"}
+func labelSet(names []string, values []string, extra ...string) string {
+	var pairs []string
+	for i, name := range names {
+		pairs = append(pairs, name+`="`+escape(values[i])+`"`)
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, extra[i]+`="`+extra[i+1]+`"`)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func escape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func writeHeader(w io.Writer, name string, help string, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// vec is the part shared by labelled metrics, a series per label values
+type vec[T any] struct {
+	metricName string
+	help       string
+	labels     []string
+	mu         sync.Mutex
+	series     map[string]*T
+	values     map[string][]string
+	create     func() *T
+}
+
+func (v *vec[T]) name() string {
+	return v.metricName
+}
+
+func (v *vec[T]) with(values []string) *T {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", v.metricName, len(v.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	s, ok := v.series[key]
+	if !ok {
+		s = v.create()
+		v.series[key] = s
+		v.values[key] = append([]string(nil), values...)
+	}
+	return s
+}
+
+// each calls fn for every series sorted by label values
+func (v *vec[T]) each(fn func(values []string, s *T)) {
+	v.mu.Lock()
+	keys := make([]string, 0, len(v.series))
+	for key := range v.series {
+		keys = append(keys, key)
+	}
+	v.mu.Unlock()
+	sort.Strings(keys)
+	for _, key := range keys {
+		v.mu.Lock()
+		s, values := v.series[key], v.values[key]
+		v.mu.Unlock()
+		fn(values, s)
+	}
+}
+
+func newVec[T any](name string, help string, labels []string, create func() *T) vec[T] {
+	return vec[T]{metricName: name, help: help, labels: labels, series: map[string]*T{}, values: map[string][]string{}, create: create}
+}
+
+// CounterVec is a counter per combination of label values
+type CounterVec struct {
+	vec[counter]
+}
+
+type counter struct {
+	mu    sync.Mutex
+	value float64
+}
+
+// NewCounter registers a counter with the given label names
+func (r *Registry) NewCounter(name string, help string, labels ...string) *CounterVec {
+	c := &CounterVec{newVec(name, help, labels, func() *counter { return &counter{} })}
+	r.register(c)
+	return c
+}
+
+// Add adds delta to the series of the given label values
+func (c *CounterVec) Add(delta float64, values ...string) {
+	s := c.with(values)
+	s.mu.Lock()
+	s.value += delta
+	s.mu.Unlock()
+}
+
+// Inc adds one to the series of the given label values
+func (c *CounterVec) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+func (c *CounterVec) write(w io.Writer) {
+	writeHeader(w, c.metricName, c.help, "counter")
+	c.each(func(values []string, s *counter) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		fmt.Fprintf(w, "%s%s %s\n", c.metricName, labelSet(c.labels, values), formatFloat(s.value))
+	})
+}
+
+// HistogramVec is a histogram per combination of label values
+type HistogramVec struct {
+	vec[histogram]
+	buckets []float64
+}
+
+type histogram struct {
+	mu     sync.Mutex
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// NewHistogram registers a histogram with the given upper bounds and label
+// names, nil buckets means DefaultBuckets
+func (r *Registry) NewHistogram(name string, help string, buckets []float64, labels ...string) *HistogramVec {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	h := &HistogramVec{buckets: buckets}
+	h.vec = newVec(name, help, labels, func() *histogram { return &histogram{counts: make([]uint64, len(buckets))} })
+	r.register(h)
+	return h
+}
+
+// Observe records value in the series of the given label values
+func (h *HistogramVec) Observe(value float64, values ...string) {
+	s := h.with(values)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, bound := range h.buckets {
+		if value <= bound {
+			s.counts[i]++
+		}
+	}
+	s.count++
+	s.sum += value
+}
+
+// ObserveDuration records the time elapsed since start in seconds
+func (h *HistogramVec) ObserveDuration(start time.Time, values ...string) {
+	h.Observe(time.Since(start).Seconds(), values...)
+}
+
+func (h *HistogramVec) write(w io.Writer) {
+	writeHeader(w, h.metricName, h.help, "histogram")
+	h.each(func(values []string, s *histogram) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for i, bound := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.metricName, labelSet(h.labels, values, "le", formatFloat(bound)), s.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.metricName, labelSet(h.labels, values, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.metricName, labelSet(h.labels, values), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.metricName, labelSet(h.labels, values), s.count)
+	})
+}
+
+// funcMetric is a metric whose value is read when scraped
+type funcMetric struct {
+	metricName string
+	help       string
+	kind       string
+	fn         func() (float64, error)
+}
+
+func (f *funcMetric) name() string {
+	return f.metricName
+}
+
+func (f *funcMetric) write(w io.Writer) {
+	value, err := f.fn()
+	if err != nil {
+		slog.Error("failed to read metric", "metric", f.metricName, "err", err)
+		return
+	}
+	writeHeader(w, f.metricName, f.help, f.kind)
+	fmt.Fprintf(w, "%s %s\n", f.metricName, formatFloat(value))
+}
+
+// NewGaugeFunc registers a gauge read from fn on every scrape, the gauge is
+// left out of the scrape when fn fails
+func (r *Registry) NewGaugeFunc(name string, help string, fn func() (float64, error)) {
+	r.register(&funcMetric{metricName: name, help: help, kind: "gauge", fn: fn})
+}
+
+// NewCounterFunc registers a counter read from fn on every scrape
+func (r *Registry) NewCounterFunc(name string, help string, fn func() (float64, error)) {
+	r.register(&funcMetric{metricName: name, help: help, kind: "counter", fn: fn})
+}
@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestExposition(t *testing.T) {
+	r := NewRegistry()
+	requests := r.NewCounter("test_requests_total", "Requests.", "route", "status")
+	latency := r.NewHistogram("test_latency_seconds", "Latency.", []float64{0.1, 1}, "route")
+	r.NewGaugeFunc("test_products", "Products.", func() (float64, error) { return 42, nil })
+	r.NewGaugeFunc("test_broken", "Broken.", func() (float64, error) { return 0, errors.New("down") })
+
+	requests.Inc("/products/{id}", "200")
+	requests.Inc("/products/{id}", "200")
+	requests.Inc(`/a"b`, "500")
+	latency.Observe(0.05, "/products/")
+	latency.Observe(0.5, "/products/")
+	latency.Observe(3, "/products/")
+
+	var out strings.Builder
+	r.Write(&out)
+	want := `# HELP test_latency_seconds Latency.
+# TYPE test_latency_seconds histogram
+test_latency_seconds_bucket{route="/products/",le="0.1"} 1
+test_latency_seconds_bucket{route="/products/",le="1"} 2
+test_latency_seconds_bucket{route="/products/",le="+Inf"} 3
+test_latency_seconds_sum{route="/products/"} 3.55
+test_latency_seconds_count{route="/products/"} 3
+# HELP test_products Products.
+# TYPE test_products gauge
+test_products 42
+# HELP test_requests_total Requests.
+# TYPE test_requests_total counter
+test_requests_total{route="/a\"b",status="500"} 1
+test_requests_total{route="/products/{id}",status="200"} 2
+`
+	if out.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestRegisterTwicePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic")
+		}
+	}()
+	r := NewRegistry()
+	r.NewCounter("dup", "Dup.")
+	r.NewCounter("dup", "Dup.")
+}
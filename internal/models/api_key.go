@@ -0,0 +1,55 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+var ErrInvalidAPIKey = errors.New("invalid api key")
+
+// Role is what a caller is allowed to do, every role includes the ones
+// below it: readers read, price collectors also update store prices and
+// admins manage everything
+type Role string
+
+const (
+	RoleReader         Role = "reader"
+	RolePriceCollector Role = "price-collector"
+	RoleAdmin          Role = "admin"
+)
+
+var roleRank = map[Role]int{RoleReader: 1, RolePriceCollector: 2, RoleAdmin: 3}
+
+// Valid reports whether r is one of the known roles
+func (r Role) Valid() bool {
+	return roleRank[r] > 0
+}
+
+// Allows reports whether r grants what required does
+func (r Role) Allows(required Role) bool {
+	return r.Valid() && roleRank[r] >= roleRank[required]
+}
+
+// APIKey is an issued key. The key itself is only known when it is issued,
+// Prefix is kept to tell keys apart
+type APIKey struct {
+	Id        int        `json:"id"`
+	Name      string     `json:"name"`
+	Prefix    string     `json:"prefix"`
+	Role      Role       `json:"role"`
+	CreatedAt time.Time  `json:"createdAt"`
+	RevokedAt *time.Time `json:"revokedAt"`
+	// Key is the plain key, only set in the response to issuing it
+	Key string `json:"key,omitempty"`
+}
+
+func (k APIKey) Validate() error {
+	if strings.TrimSpace(k.Name) == "" {
+		return errors.Join(ErrInvalidAPIKey, errors.New("name is required"))
+	}
+	if !k.Role.Valid() {
+		return errors.Join(ErrInvalidAPIKey, errors.New("role must be reader, price-collector or admin"))
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrInvalidBarcode   = errors.New("invalid barcode")
+	ErrDuplicateBarcode = errors.New("barcode already assigned to another product")
+)
+
+// Barcodes holds every EAN-13/UPC-A code printed on a product. A product can
+// carry several of them since package redesigns usually come with a new code.
+type Barcodes []string
+
+func (b Barcodes) Value() (driver.Value, error) {
+	return json.Marshal(b)
+}
+
+func (b *Barcodes) Scan(value interface{}) error {
+	return scanJSON(value, b)
+}
+
+// Validate: Checks every barcode with ValidateBarcode and rejects codes that
+// are repeated within the same list.
+func (b Barcodes) Validate() error {
+	seen := make(map[string]bool, len(b))
+	for _, code := range b {
+		if err := ValidateBarcode(code); err != nil {
+			return err
+		}
+		if seen[code] {
+			return fmt.Errorf("%w: %s is repeated", ErrInvalidBarcode, code)
+		}
+		seen[code] = true
+	}
+	return nil
+}
+
+// ValidateBarcode: Accepts 13 digit EAN-13 and 12 digit UPC-A codes whose last
+// digit matches the GS1 check digit of the preceding ones.
+func ValidateBarcode(code string) error {
+	if len(code) != 12 && len(code) != 13 {
+		return fmt.Errorf("%w: %q must have 12 (UPC-A) or 13 (EAN-13) digits", ErrInvalidBarcode, code)
+	}
+	sum := 0
+	for i := len(code) - 2; i >= 0; i-- {
+		digit := code[i]
+		if digit < '0' || digit > '9' {
+			return fmt.Errorf("%w: %q must only contain digits", ErrInvalidBarcode, code)
+		}
+		// Weights alternate 3,1,3,... starting from the digit next to the check digit
+		weight := 1
+		if (len(code)-2-i)%2 == 0 {
+			weight = 3
+		}
+		sum += int(digit-'0') * weight
+	}
+	last := code[len(code)-1]
+	if last < '0' || last > '9' {
+		return fmt.Errorf("%w: %q must only contain digits", ErrInvalidBarcode, code)
+	}
+	if check := (10 - sum%10) % 10; int(last-'0') != check {
+		return fmt.Errorf("%w: %q has check digit %c, expected %d", ErrInvalidBarcode, code, last, check)
+	}
+	return nil
+}
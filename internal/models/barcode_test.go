@@ -0,0 +1,27 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateBarcode(t *testing.T) {
+	valid := []string{"7441029512342", "4006381333931", "036000291452", "012345678905"}
+	for _, code := range valid {
+		if err := ValidateBarcode(code); err != nil {
+			t.Errorf("Expected %s to be valid, got %v", code, err)
+		}
+	}
+	invalid := []string{"", "4006381333932", "03600029145", "03600029145a", "40063813339311"}
+	for _, code := range invalid {
+		if err := ValidateBarcode(code); !errors.Is(err, ErrInvalidBarcode) {
+			t.Errorf("Expected %q to be rejected, got %v", code, err)
+		}
+	}
+}
+
+func TestBarcodesValidateRejectsRepeated(t *testing.T) {
+	if err := (Barcodes{"4006381333931", "4006381333931"}).Validate(); !errors.Is(err, ErrInvalidBarcode) {
+		t.Errorf("Expected repeated barcode to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,126 @@
+package models
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrInvalidBrand   = errors.New("invalid brand")
+	ErrDuplicateBrand = errors.New("brand already exists")
+	ErrBrandNotFound  = errors.New("brand not found")
+	ErrInvalidVariant = errors.New("invalid variant")
+)
+
+type Brand struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func (b Brand) Validate() error {
+	if strings.TrimSpace(b.Name) == "" {
+		return errors.Join(ErrInvalidBrand, errors.New("name is required"))
+	}
+	return nil
+}
+
+// VariantComparison is a single size of a product along with what each
+// store charges per base unit (litre, kilogram...) for it
+type VariantComparison struct {
+	ProductResponse
+	BaseUnit   string `json:"baseUnit"`
+	UnitPrices Stores `json:"unitPrices"`
+	// EffectiveUnitPrices is UnitPrices once promotions apply
+	EffectiveUnitPrices Stores `json:"effectiveUnitPrices,omitempty"`
+
+	baseQuantity float64
+}
+
+// baseUnits maps the units we get from stores to a base unit and the factor
+// that converts a quantity into it
+var baseUnits = map[string]struct {
+	unit   string
+	factor float64
+}{
+	"l":          {"l", 1},
+	"lt":         {"l", 1},
+	"litro":      {"l", 1},
+	"litros":     {"l", 1},
+	"ml":         {"l", 0.001},
+	"mililitro":  {"l", 0.001},
+	"mililitros": {"l", 0.001},
+	"kg":         {"kg", 1},
+	"kilo":       {"kg", 1},
+	"kilos":      {"kg", 1},
+	"kilogramo":  {"kg", 1},
+	"kilogramos": {"kg", 1},
+	"g":          {"kg", 0.001},
+	"gr":         {"kg", 0.001},
+	"gramo":      {"kg", 0.001},
+	"gramos":     {"kg", 0.001},
+	"u":          {"unidad", 1},
+	"unidad":     {"unidad", 1},
+	"unidades":   {"unidad", 1},
+}
+
+// BaseQuantity: Converts quantity expressed in unit to the matching base unit,
+// e.g. 500 "ml" is 0.5 "l". Unknown units are returned untouched
+func BaseQuantity(quantity float64, unit string) (float64, string) {
+	normalized := strings.ToLower(strings.TrimSpace(unit))
+	if base, ok := baseUnits[normalized]; ok {
+		return quantity * base.factor, base.unit
+	}
+	return quantity, normalized
+}
+
+// CompareVariants: Computes the price per base unit of every product that has
+// a quantity, sorted by base unit and then by size so all sizes of an item
+// show up side by side. Unit prices stay in the currency of each store, see
+// MixedCurrencies
+func CompareVariants(products []ProductResponse) []VariantComparison {
+	comparisons := []VariantComparison{}
+	for _, product := range products {
+		if product.Quantity == nil || *product.Quantity <= 0 {
+			continue
+		}
+		unit := ""
+		if product.Unit != nil {
+			unit = *product.Unit
+		}
+		quantity, baseUnit := BaseQuantity(*product.Quantity, unit)
+		unitPrices := Stores{}
+		if product.Stores != nil {
+			for store, price := range *product.Stores {
+				if price > 0 {
+					unitPrices[store] = price / quantity
+				}
+			}
+		}
+		comparisons = append(comparisons, VariantComparison{ProductResponse: product, BaseUnit: baseUnit, UnitPrices: unitPrices, baseQuantity: quantity})
+	}
+	sort.SliceStable(comparisons, func(i, j int) bool {
+		if comparisons[i].BaseUnit != comparisons[j].BaseUnit {
+			return comparisons[i].BaseUnit < comparisons[j].BaseUnit
+		}
+		return comparisons[i].baseQuantity < comparisons[j].baseQuantity
+	})
+	return comparisons
+}
+
+// MixedCurrencies tells whether any store of the comparisons is priced in a
+// currency other than colones, their unit prices can only be ranked against
+// each other once converted to a single currency
+func MixedCurrencies(comparisons []VariantComparison) bool {
+	for _, comparison := range comparisons {
+		if comparison.Stores == nil {
+			continue
+		}
+		for store := range *comparison.Stores {
+			if comparison.Currencies.Of(store) != DefaultCurrency {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,37 @@
+package models
+
+import "testing"
+
+func TestCompareVariants(t *testing.T) {
+	name, liters, milliliters := "coca", "litros", "ml"
+	big, small := 2.5, 600.0
+	products := []ProductResponse{
+		{Id: 1, Name: &name, Quantity: &big, Unit: &liters, Stores: &Stores{"pali": 2000, "walmart": 0}},
+		{Id: 2, Name: &name, Quantity: &small, Unit: &milliliters, Stores: &Stores{"pali": 900}},
+		{Id: 3, Name: &name},
+	}
+	comparison := CompareVariants(products)
+	if len(comparison) != 2 {
+		t.Fatalf("Expected 2 variants, got %d", len(comparison))
+	}
+	if comparison[0].Id != 2 || comparison[1].Id != 1 {
+		t.Errorf("Expected variants sorted by size, got %d, %d", comparison[0].Id, comparison[1].Id)
+	}
+	if comparison[0].BaseUnit != "l" || comparison[0].UnitPrices["pali"] != 1500 {
+		t.Errorf("Expected 1500 per l, got %v per %s", comparison[0].UnitPrices["pali"], comparison[0].BaseUnit)
+	}
+	if _, ok := comparison[1].UnitPrices["walmart"]; ok {
+		t.Errorf("Expected stores without price to be skipped, got %+v", comparison[1].UnitPrices)
+	}
+}
+
+func TestMixedCurrencies(t *testing.T) {
+	colones := []VariantComparison{{ProductResponse: ProductResponse{Stores: &Stores{"pali": 1000}, Currencies: &StoreCurrencies{"pali": "CRC"}}}}
+	if MixedCurrencies(colones) {
+		t.Errorf("Expected colones only not to be mixed")
+	}
+	dollars := append(colones, VariantComparison{ProductResponse: ProductResponse{Stores: &Stores{"amazon": 3}, Currencies: &StoreCurrencies{"amazon": "USD"}}})
+	if !MixedCurrencies(dollars) {
+		t.Errorf("Expected a dollar price to need converting")
+	}
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	ErrInvalidCategory  = errors.New("invalid category")
+	ErrCategoryCycle    = errors.New("category cannot be nested under itself or one of its subcategories")
+	ErrCategoryInUse    = errors.New("category still has subcategories")
+	ErrCategoryNotFound = errors.New("category not found")
+	ErrInvalidTag       = errors.New("invalid tag")
+	ErrDuplicateTag     = errors.New("tag already exists")
+)
+
+// Category is a node of the product classification tree, root categories
+// have a nil ParentId
+type Category struct {
+	Id       int    `json:"id"`
+	Name     string `json:"name"`
+	ParentId *int   `json:"parentId"`
+}
+
+func (c Category) Validate() error {
+	if strings.TrimSpace(c.Name) == "" {
+		return errors.Join(ErrInvalidCategory, errors.New("name is required"))
+	}
+	if c.ParentId != nil && *c.ParentId == c.Id && c.Id != 0 {
+		return ErrCategoryCycle
+	}
+	return nil
+}
+
+type Categories []Category
+
+func (c *Categories) Scan(value interface{}) error {
+	return scanJSON(value, c)
+}
+
+type Tag struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// Tags holds free-form tag names, they are stored trimmed and lower cased
+// so "Lácteos" and "lácteos " end up being the same tag
+type Tags []string
+
+func (t *Tags) Scan(value interface{}) error {
+	return scanJSON(value, t)
+}
+
+func NormalizeTag(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return "", errors.Join(ErrInvalidTag, errors.New("name is required"))
+	}
+	return name, nil
+}
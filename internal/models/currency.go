@@ -0,0 +1,153 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultCurrency is the currency of every store price that does not say otherwise
+const DefaultCurrency = "CRC"
+
+var (
+	ErrInvalidCurrency       = errors.New("invalid currency")
+	ErrInvalidExchangeRate   = errors.New("invalid exchange rate")
+	ErrDuplicateExchangeRate = errors.New("exchange rate already exists for that date")
+	ErrExchangeRateNotFound  = errors.New("exchange rate not found")
+)
+
+// NormalizeCurrency upper cases an ISO 4217 code such as "usd"
+func NormalizeCurrency(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != 3 {
+		return "", fmt.Errorf("%w: %q", ErrInvalidCurrency, code)
+	}
+	for _, c := range code {
+		if c < 'A' || c > 'Z' {
+			return "", fmt.Errorf("%w: %q", ErrInvalidCurrency, code)
+		}
+	}
+	return code, nil
+}
+
+// StoreCurrencies maps a store to the currency its price is listed in
+type StoreCurrencies map[string]string
+
+func (c StoreCurrencies) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+func (c *StoreCurrencies) Scan(value interface{}) error {
+	return scanJSON(value, c)
+}
+
+// Of returns the currency of the store price, DefaultCurrency when unset
+func (c *StoreCurrencies) Of(store string) string {
+	if c != nil {
+		if currency, ok := (*c)[store]; ok && currency != "" {
+			return currency
+		}
+	}
+	return DefaultCurrency
+}
+
+// Normalize upper cases every currency code, failing on the first invalid one
+func (c StoreCurrencies) Normalize() error {
+	for store, currency := range c {
+		normalized, err := NormalizeCurrency(currency)
+		if err != nil {
+			return fmt.Errorf("%w for store %s", err, store)
+		}
+		c[store] = normalized
+	}
+	return nil
+}
+
+// Date is a calendar day, it travels as "2006-01-02" in JSON
+type Date struct {
+	time.Time
+}
+
+const dateLayout = "2006-01-02"
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.Format(dateLayout))
+}
+
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	parsed, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return err
+	}
+	d.Time = parsed
+	return nil
+}
+
+func (d Date) Value() (driver.Value, error) {
+	return d.Format(dateLayout), nil
+}
+
+func (d *Date) Scan(value interface{}) error {
+	t, ok := value.(time.Time)
+	if !ok {
+		return errors.New("type assertion to time failed")
+	}
+	d.Time = t
+	return nil
+}
+
+// ExchangeRate says that one unit of Base is worth Rate units of Quote
+// starting on EffectiveDate, until a newer rate for the pair takes over
+type ExchangeRate struct {
+	Id            int     `json:"id"`
+	Base          string  `json:"base"`
+	Quote         string  `json:"quote"`
+	Rate          float64 `json:"rate"`
+	EffectiveDate Date    `json:"effectiveDate"`
+}
+
+func (r *ExchangeRate) Validate() error {
+	base, err := NormalizeCurrency(r.Base)
+	if err != nil {
+		return err
+	}
+	quote, err := NormalizeCurrency(r.Quote)
+	if err != nil {
+		return err
+	}
+	if base == quote {
+		return errors.Join(ErrInvalidExchangeRate, errors.New("base and quote must differ"))
+	}
+	if r.Rate <= 0 {
+		return errors.Join(ErrInvalidExchangeRate, errors.New("rate must be positive"))
+	}
+	if r.EffectiveDate.IsZero() {
+		return errors.Join(ErrInvalidExchangeRate, errors.New("effectiveDate is required"))
+	}
+	r.Base, r.Quote = base, quote
+	return nil
+}
+
+// Conversion is attached to responses whose prices were converted through
+// ?currency=, it lists every rate that was applied
+type Conversion struct {
+	Currency string         `json:"currency"`
+	Rates    []ExchangeRate `json:"rates"`
+}
+
+// Add records rate unless a rate for the same pair was already recorded
+func (c *Conversion) Add(rate ExchangeRate) {
+	for _, existing := range c.Rates {
+		if existing.Base == rate.Base && existing.Quote == rate.Quote {
+			return
+		}
+	}
+	c.Rates = append(c.Rates, rate)
+}
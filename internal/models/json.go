@@ -0,0 +1,17 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// scanJSON decodes a json/jsonb column into dest, used by the sql.Scanner
+// implementations of the types stored or aggregated as JSON
+func scanJSON(value interface{}, dest interface{}) error {
+	b, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to byte failed")
+	}
+
+	return json.Unmarshal(b, dest)
+}
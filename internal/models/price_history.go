@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// PricePoint is the price a store charged for a product from RecordedAt
+// until the next point of the same store. A nil Price means the store
+// stopped listing the product
+type PricePoint struct {
+	ProductId  int       `json:"productId"`
+	Store      string    `json:"store"`
+	Price      *float64  `json:"price"`
+	RecordedAt time.Time `json:"recordedAt"`
+}
+
+// PriceHistoryFilter narrows GetPriceHistory, zero values mean no filtering
+type PriceHistoryFilter struct {
+	ProductIds []int
+	Store      string
+	Since      *time.Time
+}
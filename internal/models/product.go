@@ -9,11 +9,17 @@ import (
 )
 
 type Product struct {
-	Id       int
-	Name     sql.NullString
-	Quantity sql.NullFloat64
-	Unit     sql.NullString
-	Stores   *Stores
+	Id         int
+	Name       sql.NullString
+	Quantity   sql.NullFloat64
+	Unit       sql.NullString
+	BrandId    sql.NullInt64
+	ParentId   sql.NullInt64
+	Stores     *Stores
+	Currencies *StoreCurrencies
+	Barcodes   Barcodes
+	Categories Categories
+	Tags       Tags
 }
 
 type Stores map[string]float64
@@ -36,7 +42,38 @@ type ProductResponse struct {
 	Name     *string  `json:"name"`
 	Quantity *float64 `json:"quantity"`
 	Unit     *string  `json:"unit"`
-	Stores   *Stores  `json:"stores"`
+	BrandId  *int     `json:"brandId,omitempty" db:"brand_id"`
+	// ParentId links a size variant to the product it is a variant of
+	ParentId *int    `json:"parentId,omitempty" db:"parent_id"`
+	Stores   *Stores `json:"stores"`
+	// Currencies lists the stores whose price is not in colones
+	Currencies *StoreCurrencies `json:"currencies,omitempty"`
+	Barcodes   Barcodes         `json:"barcodes,omitempty" db:"-"`
+	// Categories and Tags are read only here, they are assigned through
+	// their own /products/{id}/categories and /products/{id}/tags routes
+	Categories Categories `json:"categories,omitempty" db:"-"`
+	Tags       Tags       `json:"tags,omitempty" db:"-"`
+	// EffectivePrices and Promotions are filled in on reads, they hold the
+	// store prices once the promotions active at the requested time apply
+	EffectivePrices *Stores     `json:"effectivePrices,omitempty" db:"-"`
+	Promotions      []Promotion `json:"promotions,omitempty" db:"-"`
+	Conversion      *Conversion `json:"conversion,omitempty" db:"-"`
+}
+
+// ProductFilter narrows GetAllProducts, zero values mean no filtering.
+// CategoryId matches the category and all of its subcategories, every tag
+// in Tags must be present on the product, BrandId also matches variants of
+// products of that brand and MaxPrice matches products sold at or below
+// that price in colones in at least one store, prices in other currencies
+// are converted with the latest exchange rate and never match without one.
+// Ids only keeps the listed products
+type ProductFilter struct {
+	Ids        []int
+	CategoryId *int
+	Tags       []string
+	BrandId    *int
+	ParentId   *int
+	MaxPrice   *float64
 }
 
 func (p *Product) ToJSON() ProductResponse {
@@ -52,13 +89,33 @@ func (p *Product) ToJSON() ProductResponse {
 	if p.Unit.Valid {
 		unit = &p.Unit.String
 	}
+	currencies := p.Currencies
+	if currencies != nil && len(*currencies) == 0 {
+		currencies = nil
+	}
+	var brandId *int
+	if p.BrandId.Valid {
+		id := int(p.BrandId.Int64)
+		brandId = &id
+	}
+	var parentId *int
+	if p.ParentId.Valid {
+		id := int(p.ParentId.Int64)
+		parentId = &id
+	}
 
 	return ProductResponse{
-		Id:       p.Id,
-		Name:     name,
-		Quantity: quantity,
-		Unit:     unit,
-		Stores:   p.Stores,
+		Id:         p.Id,
+		Name:       name,
+		Quantity:   quantity,
+		Unit:       unit,
+		BrandId:    brandId,
+		ParentId:   parentId,
+		Stores:     p.Stores,
+		Currencies: currencies,
+		Barcodes:   p.Barcodes,
+		Categories: p.Categories,
+		Tags:       p.Tags,
 	}
 }
 
@@ -0,0 +1,213 @@
+package models
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ProductCSVHeader are the columns of the product CSV format. Barcodes are
+// separated by ";", stores and currencies are "store=value" pairs separated
+// by ";"
+var ProductCSVHeader = []string{"id", "name", "quantity", "unit", "brandId", "parentId", "barcodes", "stores", "currencies"}
+
+// ProductCSVWriter writes products as CSV rows, the header goes before the
+// first one
+type ProductCSVWriter struct {
+	w           *csv.Writer
+	wroteHeader bool
+}
+
+func NewProductCSVWriter(w io.Writer) *ProductCSVWriter {
+	return &ProductCSVWriter{w: csv.NewWriter(w)}
+}
+
+func (c *ProductCSVWriter) writeHeader() error {
+	if c.wroteHeader {
+		return nil
+	}
+	c.wroteHeader = true
+	return c.w.Write(ProductCSVHeader)
+}
+
+func (c *ProductCSVWriter) Write(product ProductResponse) error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	currencies := map[string]string{}
+	if product.Currencies != nil {
+		currencies = *product.Currencies
+	}
+	return c.w.Write([]string{
+		strconv.Itoa(product.Id),
+		csvString(product.Name),
+		csvFloat(product.Quantity),
+		csvString(product.Unit),
+		csvInt(product.BrandId),
+		csvInt(product.ParentId),
+		strings.Join(product.Barcodes, ";"),
+		FormatStorePrices(product.Stores),
+		formatPairs(currencies),
+	})
+}
+
+// Flush writes the buffered rows, the header alone when there were none
+func (c *ProductCSVWriter) Flush() error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// WriteProductsCSV writes products in the product CSV format
+func WriteProductsCSV(w io.Writer, products []ProductResponse) error {
+	c := NewProductCSVWriter(w)
+	for _, product := range products {
+		if err := c.Write(product); err != nil {
+			return err
+		}
+	}
+	return c.Flush()
+}
+
+// ReadProductsCSV: Reads products in the product CSV format. The header
+// picks the columns so they can come in any order and unknown ones are
+// ignored. Products without an id are new
+func ReadProductsCSV(r io.Reader) ([]ProductResponse, error) {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err != nil {
+		return nil, err
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	var products []ProductResponse
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return products, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		product, err := parseProductRecord(field)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		products = append(products, product)
+	}
+}
+
+func parseProductRecord(field func(name string) string) (ProductResponse, error) {
+	var product ProductResponse
+	var err error
+	if id := field("id"); id != "" {
+		if product.Id, err = strconv.Atoi(id); err != nil {
+			return product, fmt.Errorf("invalid id %q", id)
+		}
+	}
+	if name := field("name"); name != "" {
+		product.Name = &name
+	}
+	if unit := field("unit"); unit != "" {
+		product.Unit = &unit
+	}
+	if quantity := field("quantity"); quantity != "" {
+		value, err := strconv.ParseFloat(quantity, 64)
+		if err != nil {
+			return product, fmt.Errorf("invalid quantity %q", quantity)
+		}
+		product.Quantity = &value
+	}
+	for column, target := range map[string]**int{"brandId": &product.BrandId, "parentId": &product.ParentId} {
+		if value := field(column); value != "" {
+			id, err := strconv.Atoi(value)
+			if err != nil {
+				return product, fmt.Errorf("invalid %s %q", column, value)
+			}
+			*target = &id
+		}
+	}
+	if barcodes := field("barcodes"); barcodes != "" {
+		product.Barcodes = strings.Split(barcodes, ";")
+	}
+	if stores := field("stores"); stores != "" {
+		prices := Stores{}
+		for store, value := range parsePairs(stores) {
+			if prices[store], err = strconv.ParseFloat(value, 64); err != nil {
+				return product, fmt.Errorf("invalid price %q for %s", value, store)
+			}
+		}
+		product.Stores = &prices
+	}
+	if currencies := field("currencies"); currencies != "" {
+		storeCurrencies := StoreCurrencies(parsePairs(currencies))
+		product.Currencies = &storeCurrencies
+	}
+	return product, nil
+}
+
+// FormatStorePrices writes stores as "store=price" pairs sorted by store and
+// separated by ";"
+func FormatStorePrices(stores *Stores) string {
+	pairs := map[string]string{}
+	if stores != nil {
+		for store, price := range *stores {
+			pairs[store] = strconv.FormatFloat(price, 'f', -1, 64)
+		}
+	}
+	return formatPairs(pairs)
+}
+
+func formatPairs(pairs map[string]string) string {
+	joined := make([]string, 0, len(pairs))
+	for key, value := range pairs {
+		joined = append(joined, key+"="+value)
+	}
+	sort.Strings(joined)
+	return strings.Join(joined, ";")
+}
+
+func parsePairs(value string) map[string]string {
+	pairs := map[string]string{}
+	for _, pair := range strings.Split(value, ";") {
+		key, value, _ := strings.Cut(pair, "=")
+		if key = strings.TrimSpace(key); key != "" {
+			pairs[key] = strings.TrimSpace(value)
+		}
+	}
+	return pairs
+}
+
+func csvString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+func csvInt(value *int) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.Itoa(*value)
+}
+
+func csvFloat(value *float64) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*value, 'f', -1, 64)
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func ptr[T any](value T) *T {
+	return &value
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	products := []ProductResponse{
+		{Id: 1, Name: ptr("Leche entera, 1 L"), Quantity: ptr(1.0), Unit: ptr("litros"), BrandId: ptr(1),
+			Barcodes: Barcodes{"7441001603217"}, Stores: &Stores{"walmart": 990, "pali": 950}},
+		{Id: 2, Name: ptr("Cafe molido"), ParentId: ptr(1),
+			Stores: &Stores{"masxmenos": 7.5}, Currencies: &StoreCurrencies{"masxmenos": "USD"}},
+	}
+	var buf bytes.Buffer
+	if err := WriteProductsCSV(&buf, products); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `1,"Leche entera, 1 L",1,litros,1,,7441001603217,pali=950;walmart=990,`) {
+		t.Errorf("unexpected csv\n%s", buf.String())
+	}
+	read, err := ReadProductsCSV(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read, products) {
+		t.Errorf("got %+v, want %+v", read, products)
+	}
+
+	if _, err := ReadProductsCSV(strings.NewReader("name,stores\nArroz,pali=abc\n")); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected an error on line 2, got %v", err)
+	}
+}
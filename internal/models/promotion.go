@@ -0,0 +1,131 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var ErrInvalidPromotion = errors.New("invalid promotion")
+
+type PromotionType string
+
+const (
+	// PromotionPercentage takes Value percent off the price, "20% off"
+	PromotionPercentage PromotionType = "percentage"
+	// PromotionFixed takes a fixed amount (Value) off the price, "₡500 off"
+	PromotionFixed PromotionType = "fixed"
+	// PromotionMultiBuy charges Pay units out of every Buy units, "2x1" is Buy 2 Pay 1
+	PromotionMultiBuy PromotionType = "multi_buy"
+)
+
+// PromotionConditions are the fine print of a promotion, they are shown to
+// clients but the effective price assumes they are met
+type PromotionConditions struct {
+	MinQuantity    int  `json:"minQuantity,omitempty"`
+	MaxPerCustomer int  `json:"maxPerCustomer,omitempty"`
+	MembersOnly    bool `json:"membersOnly,omitempty"`
+}
+
+func (c PromotionConditions) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+func (c *PromotionConditions) Scan(value interface{}) error {
+	return scanJSON(value, c)
+}
+
+// Promotion is a time bound sale of a product at a single store. A nil EndsAt
+// means the promotion runs until it is deleted
+type Promotion struct {
+	Id          int                 `json:"id"`
+	ProductId   int                 `json:"productId"`
+	Store       string              `json:"store"`
+	Type        PromotionType       `json:"type"`
+	Value       float64             `json:"value,omitempty"`
+	Buy         int                 `json:"buy,omitempty"`
+	Pay         int                 `json:"pay,omitempty"`
+	StartsAt    time.Time           `json:"startsAt"`
+	EndsAt      *time.Time          `json:"endsAt"`
+	Conditions  PromotionConditions `json:"conditions"`
+	Description string              `json:"description,omitempty"`
+}
+
+func (p *Promotion) Validate() error {
+	p.Store = strings.TrimSpace(p.Store)
+	if p.ProductId <= 0 {
+		return errors.Join(ErrInvalidPromotion, errors.New("productId is required"))
+	}
+	if p.Store == "" {
+		return errors.Join(ErrInvalidPromotion, errors.New("store is required"))
+	}
+	switch p.Type {
+	case PromotionPercentage:
+		if p.Value <= 0 || p.Value > 100 {
+			return errors.Join(ErrInvalidPromotion, errors.New("percentage value must be between 0 and 100"))
+		}
+	case PromotionFixed:
+		if p.Value <= 0 {
+			return errors.Join(ErrInvalidPromotion, errors.New("fixed value must be positive"))
+		}
+	case PromotionMultiBuy:
+		if p.Pay < 1 || p.Buy <= p.Pay {
+			return errors.Join(ErrInvalidPromotion, errors.New("multi_buy needs buy greater than pay and pay of at least 1"))
+		}
+	default:
+		return errors.Join(ErrInvalidPromotion, errors.New("type must be percentage, fixed or multi_buy"))
+	}
+	if p.StartsAt.IsZero() {
+		return errors.Join(ErrInvalidPromotion, errors.New("startsAt is required"))
+	}
+	if p.EndsAt != nil && !p.EndsAt.After(p.StartsAt) {
+		return errors.Join(ErrInvalidPromotion, errors.New("endsAt must be after startsAt"))
+	}
+	return nil
+}
+
+// ActiveAt reports whether the promotion runs at the given time
+func (p Promotion) ActiveAt(at time.Time) bool {
+	return !at.Before(p.StartsAt) && (p.EndsAt == nil || at.Before(*p.EndsAt))
+}
+
+// Apply returns the price of a single unit once the promotion is applied
+func (p Promotion) Apply(price float64) float64 {
+	switch p.Type {
+	case PromotionPercentage:
+		return price * (1 - p.Value/100)
+	case PromotionFixed:
+		return max(price-p.Value, 0)
+	case PromotionMultiBuy:
+		return price * float64(p.Pay) / float64(p.Buy)
+	}
+	return price
+}
+
+// EffectivePrices: Returns the unit price of every store once the best
+// promotion active at the given time is applied. Stores without promotions
+// keep their regular price
+func EffectivePrices(stores Stores, promotions []Promotion, at time.Time) Stores {
+	effective := make(Stores, len(stores))
+	for store, price := range stores {
+		effective[store] = price
+		for _, promotion := range promotions {
+			if promotion.Store != store || !promotion.ActiveAt(at) {
+				continue
+			}
+			if promoted := promotion.Apply(price); promoted < effective[store] {
+				effective[store] = promoted
+			}
+		}
+	}
+	return effective
+}
+
+// PromotionFilter narrows GetPromotions, zero values mean no filtering
+type PromotionFilter struct {
+	ProductIds []int
+	Store      string
+	ActiveAt   *time.Time
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestEffectivePrices(t *testing.T) {
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	sunday := time.Date(2026, 10, 18, 23, 59, 0, 0, time.UTC)
+	expired := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	promotions := []Promotion{
+		{Store: "pali", Type: PromotionPercentage, Value: 20, StartsAt: now.AddDate(0, 0, -1), EndsAt: &sunday},
+		{Store: "pali", Type: PromotionMultiBuy, Buy: 2, Pay: 1, StartsAt: now.AddDate(0, 0, 1)},
+		{Store: "walmart", Type: PromotionMultiBuy, Buy: 2, Pay: 1, StartsAt: now.AddDate(0, -1, 0)},
+		{Store: "maxipali", Type: PromotionFixed, Value: 5000, StartsAt: now.AddDate(0, -1, 0)},
+		{Store: "masxmenos", Type: PromotionPercentage, Value: 50, StartsAt: now.AddDate(0, -1, 0), EndsAt: &expired},
+	}
+	effective := EffectivePrices(Stores{"pali": 1000, "walmart": 1000, "maxipali": 1000, "masxmenos": 1000}, promotions, now)
+	expected := Stores{"pali": 800, "walmart": 500, "maxipali": 0, "masxmenos": 1000}
+	for store, price := range expected {
+		if effective[store] != price {
+			t.Errorf("Expected %s to cost %v, got %v", store, price, effective[store])
+		}
+	}
+}
+
+func TestPromotionValidate(t *testing.T) {
+	starts := time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)
+	invalid := []Promotion{
+		{ProductId: 1, Store: "pali", Type: PromotionPercentage, Value: 120, StartsAt: starts},
+		{ProductId: 1, Store: "pali", Type: PromotionMultiBuy, Buy: 1, Pay: 1, StartsAt: starts},
+		{ProductId: 1, Store: " ", Type: PromotionFixed, Value: 100, StartsAt: starts},
+		{ProductId: 1, Store: "pali", Type: "bogo", StartsAt: starts},
+		{ProductId: 1, Store: "pali", Type: PromotionFixed, Value: 100, StartsAt: starts, EndsAt: &starts},
+	}
+	for _, promotion := range invalid {
+		if err := promotion.Validate(); !errors.Is(err, ErrInvalidPromotion) {
+			t.Errorf("Expected %+v to be rejected, got %v", promotion, err)
+		}
+	}
+}
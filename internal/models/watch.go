@@ -0,0 +1,126 @@
+package models
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrInvalidWatch = errors.New("invalid watch")
+
+// Watch subscribes someone to the price of a product at a store, or at any
+// store when Store is nil. An alert is raised whenever a price drops to or
+// below TargetPrice, which is given in Currency, colones unless set
+type Watch struct {
+	Id          int       `json:"id"`
+	Subscriber  string    `json:"subscriber"`
+	ProductId   int       `json:"productId"`
+	Store       *string   `json:"store"`
+	TargetPrice float64   `json:"targetPrice"`
+	Currency    string    `json:"currency"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+func (w *Watch) Validate() error {
+	w.Subscriber = strings.TrimSpace(w.Subscriber)
+	if w.Subscriber == "" {
+		return errors.Join(ErrInvalidWatch, errors.New("subscriber is required"))
+	}
+	if w.ProductId <= 0 {
+		return errors.Join(ErrInvalidWatch, errors.New("productId is required"))
+	}
+	if w.Store != nil && strings.TrimSpace(*w.Store) == "" {
+		w.Store = nil
+	}
+	if w.TargetPrice <= 0 {
+		return errors.Join(ErrInvalidWatch, errors.New("targetPrice must be positive"))
+	}
+	if w.Currency == "" {
+		w.Currency = DefaultCurrency
+	}
+	currency, err := NormalizeCurrency(w.Currency)
+	if err != nil {
+		return errors.Join(ErrInvalidWatch, err)
+	}
+	w.Currency = currency
+	return nil
+}
+
+// Matches reports whether the watch covers the given store
+func (w Watch) Matches(store string) bool {
+	return w.Store == nil || *w.Store == store
+}
+
+// Alert is raised when the price of a watched product crosses the target,
+// the prices are in the currency of the watch
+type Alert struct {
+	Id            int        `json:"id"`
+	WatchId       int        `json:"watchId"`
+	Subscriber    string     `json:"subscriber"`
+	ProductId     int        `json:"productId"`
+	Store         string     `json:"store"`
+	Price         float64    `json:"price"`
+	PreviousPrice *float64   `json:"previousPrice"`
+	TargetPrice   float64    `json:"targetPrice"`
+	Currency      string     `json:"currency"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	ReadAt        *time.Time `json:"readAt"`
+}
+
+// WatchFilter narrows GetWatches, zero values mean no filtering
+type WatchFilter struct {
+	Subscriber string
+	ProductId  *int
+}
+
+// AlertFilter narrows GetAlerts, zero values mean no filtering
+type AlertFilter struct {
+	Subscriber string
+	UnreadOnly bool
+}
+
+// PriceChange is a single store price that changed through a write, the
+// currencies are the ones each price is listed in, colones when empty
+type PriceChange struct {
+	ProductId        int
+	Store            string
+	PreviousPrice    *float64
+	Price            *float64
+	PreviousCurrency string
+	Currency         string
+}
+
+// DiffStores: Lists every store whose price differs between before and after,
+// a nil price means the store was not listed
+func DiffStores(productId int, before *Stores, after *Stores) []PriceChange {
+	var changes []PriceChange
+	lookup := func(stores *Stores, store string) *float64 {
+		if stores == nil {
+			return nil
+		}
+		if price, ok := (*stores)[store]; ok {
+			return &price
+		}
+		return nil
+	}
+	seen := map[string]bool{}
+	for _, stores := range []*Stores{after, before} {
+		if stores == nil {
+			continue
+		}
+		for store := range *stores {
+			if seen[store] {
+				continue
+			}
+			seen[store] = true
+			previous, current := lookup(before, store), lookup(after, store)
+			if previous != nil && current != nil && *previous == *current {
+				continue
+			}
+			changes = append(changes, PriceChange{ProductId: productId, Store: store, PreviousPrice: previous, Price: current})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Store < changes[j].Store })
+	return changes
+}
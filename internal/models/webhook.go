@@ -0,0 +1,85 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"slices"
+	"time"
+)
+
+var ErrInvalidWebhook = errors.New("invalid webhook")
+
+// Webhook is an endpoint receiving signed catalog events. Secret is only
+// returned when the webhook is created. Active is left nil by requests that
+// don't mention it, new webhooks then start active and updates keep the
+// current value
+type Webhook struct {
+	Id        int       `json:"id"`
+	Url       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"`
+	Events    EventList `json:"events"`
+	Active    *bool     `json:"active"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// EventList holds the event types a webhook subscribes to
+type EventList []string
+
+func (e EventList) Value() (driver.Value, error) {
+	if e == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(e)
+}
+
+func (e *EventList) Scan(value interface{}) error {
+	return scanJSON(value, e)
+}
+
+// Validate checks the url and that every event is one of knownEvents
+func (w Webhook) Validate(knownEvents []string) error {
+	parsed, err := url.Parse(w.Url)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return errors.Join(ErrInvalidWebhook, errors.New("url must be an absolute http(s) url"))
+	}
+	for _, event := range w.Events {
+		if !slices.Contains(knownEvents, event) {
+			return errors.Join(ErrInvalidWebhook, errors.New("unknown event "+event))
+		}
+	}
+	return nil
+}
+
+// Subscribes reports whether the webhook wants events of the given type
+func (w Webhook) Subscribes(eventType string) bool {
+	return w.Active != nil && *w.Active && (len(w.Events) == 0 || slices.Contains(w.Events, eventType))
+}
+
+// WebhookDelivery is a single attempt at delivering an event to a webhook
+type WebhookDelivery struct {
+	Id         int             `json:"id"`
+	WebhookId  int             `json:"webhookId"`
+	EventId    string          `json:"eventId"`
+	EventType  string          `json:"eventType"`
+	Payload    json.RawMessage `json:"payload"`
+	Attempt    int             `json:"attempt"`
+	StatusCode *int            `json:"statusCode"`
+	Error      string          `json:"error,omitempty"`
+	Success    bool            `json:"success"`
+	CreatedAt  time.Time       `json:"createdAt"`
+}
+
+// PendingDelivery is an event waiting to be delivered to a webhook, it stays
+// queued across restarts until delivered or out of attempts. Attempt counts
+// the attempts already made
+type PendingDelivery struct {
+	Id            int64
+	WebhookId     int
+	EventId       string
+	EventType     string
+	Payload       json.RawMessage
+	Attempt       int
+	NextAttemptAt time.Time
+}
@@ -0,0 +1,34 @@
+// Package notify delivers price alerts to their subscribers
+package notify
+
+import (
+	"crproductos/internal/models"
+	"log/slog"
+)
+
+// Notifier delivers an alert that was just raised, implementations may
+// email, push or forward it elsewhere
+type Notifier interface {
+	Notify(alert models.Alert) error
+}
+
+// LogNotifier writes alerts to the standard logger, it is the default notifier
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(alert models.Alert) error {
+	slog.Info("price alert", "subscriber", alert.Subscriber, "product_id", alert.ProductId, "store", alert.Store, "price", alert.Price, "target_price", alert.TargetPrice)
+	return nil
+}
+
+// Multi fans an alert out to several notifiers, returning the first error
+type Multi []Notifier
+
+func (m Multi) Notify(alert models.Alert) error {
+	var first error
+	for _, notifier := range m {
+		if err := notifier.Notify(alert); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
@@ -0,0 +1,94 @@
+package outbox
+
+import (
+	"context"
+	"crproductos/internal/events"
+	"crproductos/internal/repository"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Listener hands every event published by the relay, whichever process runs
+// it, to a local publisher. The relay claims each event once, so processes
+// feed their brokers from the listener rather than from a relay of their own
+type Listener struct {
+	connInfo  string
+	repo      repository.OutboxRepository
+	publisher events.Publisher
+}
+
+func NewListener(connInfo string, repo repository.OutboxRepository, publisher events.Publisher) *Listener {
+	return &Listener{connInfo: connInfo, repo: repo, publisher: publisher}
+}
+
+// Invalidator is implemented by publishers caching what the events describe,
+// InvalidateAll drops everything once events may have been missed
+type Invalidator interface {
+	InvalidateAll(ctx context.Context)
+}
+
+// Run: Listens on repository.OutboxChannel until ctx is cancelled. The
+// connection is reopened when it drops, events published meanwhile are
+// missed, like the events a slow broker subscriber misses, and publishers
+// implementing Invalidator are told to drop their caches
+func (l *Listener) Run(ctx context.Context) error {
+	listener := pq.NewListener(l.connInfo, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			slog.Error("outbox listener connection", "event", event, "err", err)
+		}
+	})
+	defer listener.Close()
+	if err := listener.Listen(repository.OutboxChannel); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case notification := <-listener.Notify:
+			// a nil notification tells the connection was reestablished
+			if notification == nil {
+				slog.Warn("outbox listener reconnected, events published meanwhile were missed")
+				l.missed(ctx)
+				continue
+			}
+			l.deliver(notification.Extra)
+		case <-time.After(90 * time.Second):
+			go listener.Ping()
+		}
+	}
+}
+
+// missed invalidates the publisher, or each publisher of an events.Multi,
+// that implements Invalidator
+func (l *Listener) missed(ctx context.Context) {
+	publishers := events.Multi{l.publisher}
+	if multi, ok := l.publisher.(events.Multi); ok {
+		publishers = multi
+	}
+	for _, publisher := range publishers {
+		if invalidator, ok := publisher.(Invalidator); ok {
+			invalidator.InvalidateAll(ctx)
+		}
+	}
+}
+
+// deliver loads the event notified by its outbox id and publishes it
+func (l *Listener) deliver(payload string) {
+	id, err := strconv.ParseInt(payload, 10, 64)
+	if err != nil {
+		slog.Error("invalid outbox notification", "payload", payload)
+		return
+	}
+	event, err := l.repo.GetEvent(id)
+	if err != nil {
+		slog.Error("failed to load outbox event", "id", id, "err", err)
+		return
+	}
+	if err := l.publisher.Publish(event); err != nil {
+		slog.Error("failed to publish outbox event", "eventId", event.Id, "err", err)
+	}
+}
@@ -0,0 +1,96 @@
+// Package outbox relays the events the repositories write to the outbox table
+// to a Publisher. Events are written in the same transaction as the change
+// they describe, so none is lost or published for a rolled back change
+package outbox
+
+import (
+	"context"
+	"crproductos/internal/events"
+	"crproductos/internal/repository"
+	"log/slog"
+	"time"
+)
+
+// Relay polls the outbox and publishes the pending events in order. Delivery
+// is at least once, consumers use the event id to drop duplicates
+type Relay struct {
+	repo      repository.OutboxRepository
+	publisher events.Publisher
+	interval  time.Duration
+	batchSize int
+	retention time.Duration
+}
+
+type Option func(*Relay)
+
+// WithInterval sets how long the relay waits when the outbox is drained
+func WithInterval(interval time.Duration) Option {
+	return func(r *Relay) {
+		r.interval = interval
+	}
+}
+
+// WithBatchSize sets how many events are published per transaction
+func WithBatchSize(size int) Option {
+	return func(r *Relay) {
+		r.batchSize = size
+	}
+}
+
+// WithRetention sets how long published events are kept, zero keeps them forever
+func WithRetention(retention time.Duration) Option {
+	return func(r *Relay) {
+		r.retention = retention
+	}
+}
+
+func NewRelay(repo repository.OutboxRepository, publisher events.Publisher, opts ...Option) *Relay {
+	r := &Relay{
+		repo:      repo,
+		publisher: publisher,
+		interval:  time.Second,
+		batchSize: 100,
+		retention: 7 * 24 * time.Hour,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Flush: Publishes pending events until the outbox is drained or publishing
+// fails, returns how many events were published
+func (r *Relay) Flush() (int, error) {
+	total := 0
+	for {
+		published, err := r.repo.PublishPending(r.batchSize, r.publisher.Publish)
+		total += published
+		if err != nil || published < r.batchSize {
+			return total, err
+		}
+	}
+}
+
+// Run flushes the outbox every interval until ctx is cancelled, pruning the
+// events published longer than the retention ago
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	lastPrune := time.Time{}
+	for {
+		if _, err := r.Flush(); err != nil {
+			slog.Error("failed to publish outbox events", "err", err)
+		}
+		if r.retention > 0 && time.Since(lastPrune) > time.Hour {
+			if _, err := r.repo.DeletePublished(time.Now().Add(-r.retention)); err != nil {
+				slog.Error("failed to prune outbox", "err", err)
+			}
+			lastPrune = time.Now()
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package outbox
+
+import (
+	"context"
+	"crproductos/internal/events"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeOutboxRepository mimics the ordering and retry semantics of the
+// outbox table
+type fakeOutboxRepository struct {
+	pending []events.Event
+}
+
+func (f *fakeOutboxRepository) PublishPending(limit int, publish func(events.Event) error) (int, error) {
+	published := 0
+	for published < limit && published < len(f.pending) {
+		if err := publish(f.pending[published]); err != nil {
+			f.pending = f.pending[published:]
+			return published, err
+		}
+		published++
+	}
+	f.pending = f.pending[published:]
+	return published, nil
+}
+func (f *fakeOutboxRepository) GetEvent(id int64) (events.Event, error) {
+	if id < 1 || int(id) > len(f.pending) {
+		return events.Event{}, sql.ErrNoRows
+	}
+	return f.pending[id-1], nil
+}
+
+func (f *fakeOutboxRepository) DeletePublished(before time.Time) (int64, error) {
+	return 0, nil
+}
+
+type flakyPublisher struct {
+	failOn    string
+	published []string
+}
+
+func (p *flakyPublisher) Publish(event events.Event) error {
+	if event.Id == p.failOn {
+		p.failOn = ""
+		return errors.New("unavailable")
+	}
+	p.published = append(p.published, event.Id)
+	return nil
+}
+
+func TestFlushKeepsOrderAcrossFailures(t *testing.T) {
+	repo := &fakeOutboxRepository{}
+	for _, id := range []string{"1", "2", "3", "4", "5"} {
+		repo.pending = append(repo.pending, events.Event{Id: id})
+	}
+	publisher := &flakyPublisher{failOn: "4"}
+	relay := NewRelay(repo, publisher, WithBatchSize(2))
+
+	published, err := relay.Flush()
+	if err == nil || published != 3 {
+		t.Fatalf("first flush published %d, err %v", published, err)
+	}
+	published, err = relay.Flush()
+	if err != nil || published != 2 {
+		t.Fatalf("second flush published %d, err %v", published, err)
+	}
+	want := "12345"
+	got := ""
+	for _, id := range publisher.published {
+		got += id
+	}
+	if got != want {
+		t.Errorf("published %s, want %s", got, want)
+	}
+}
+
+func TestListenerDeliversNotifiedEvents(t *testing.T) {
+	repo := &fakeOutboxRepository{pending: []events.Event{{Id: "a"}, {Id: "b"}}}
+	publisher := &flakyPublisher{}
+	listener := NewListener("", repo, publisher)
+	for _, payload := range []string{"2", "x", "7", "1"} {
+		listener.deliver(payload)
+	}
+	if len(publisher.published) != 2 || publisher.published[0] != "b" || publisher.published[1] != "a" {
+		t.Errorf("published %v, want [b a]", publisher.published)
+	}
+}
+
+type cachingPublisher struct {
+	flakyPublisher
+	invalidated int
+}
+
+func (p *cachingPublisher) InvalidateAll(ctx context.Context) {
+	p.invalidated++
+}
+
+func TestListenerInvalidatesCachesAfterMissedEvents(t *testing.T) {
+	cache := &cachingPublisher{}
+	listener := NewListener("", &fakeOutboxRepository{}, events.Multi{&flakyPublisher{}, cache})
+	listener.missed(context.Background())
+	if cache.invalidated != 1 {
+		t.Errorf("cache invalidated %d times within a Multi, want 1", cache.invalidated)
+	}
+	NewListener("", &fakeOutboxRepository{}, cache).missed(context.Background())
+	if cache.invalidated != 2 {
+		t.Errorf("cache invalidated %d times, want 2", cache.invalidated)
+	}
+}
@@ -0,0 +1,119 @@
+// Package ratelimit implements token buckets. Each key owns a bucket holding
+// up to Burst tokens that refills at Rate tokens per second, every request
+// takes one token and is refused when the bucket is empty
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limit is the size and refill rate of a bucket
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// ParseLimit: Reads a limit written as "<count>/<unit>" with unit s, m or h,
+// e.g. "300/m". The bucket holds count tokens and refills over one unit
+func ParseLimit(value string) (Limit, error) {
+	countStr, unit, ok := strings.Cut(value, "/")
+	count, err := strconv.Atoi(strings.TrimSpace(countStr))
+	if !ok || err != nil || count <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q", value)
+	}
+	periods := map[string]time.Duration{"s": time.Second, "m": time.Minute, "h": time.Hour}
+	period, ok := periods[strings.TrimSpace(unit)]
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid rate limit unit in %q", value)
+	}
+	return Limit{Rate: float64(count) / period.Seconds(), Burst: count}, nil
+}
+
+// Result is the outcome of taking a token
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is how long until a token is available, zero when allowed
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+}
+
+// Store keeps the buckets and takes tokens from them
+type Store interface {
+	Take(key string, limit Limit, now time.Time) (Result, error)
+}
+
+// Bucket is the state of a single bucket
+type Bucket struct {
+	Tokens  float64
+	Updated time.Time
+}
+
+// Take refills the bucket up to now and takes a token if there is one. A zero
+// bucket starts full
+func (b *Bucket) Take(limit Limit, now time.Time) Result {
+	if b.Updated.IsZero() {
+		b.Tokens = float64(limit.Burst)
+	} else if elapsed := now.Sub(b.Updated).Seconds(); elapsed > 0 {
+		b.Tokens = math.Min(float64(limit.Burst), b.Tokens+elapsed*limit.Rate)
+	}
+	b.Updated = now
+	result := Result{Limit: limit.Burst}
+	if b.Tokens >= 1 {
+		b.Tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - b.Tokens) / limit.Rate)
+	}
+	result.Remaining = int(b.Tokens)
+	result.Reset = seconds((float64(limit.Burst) - b.Tokens) / limit.Rate)
+	return result
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// MemoryStore keeps the buckets in process, each replica limits on its own
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*Bucket
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]*Bucket{}}
+}
+
+func (s *MemoryStore) Take(key string, limit Limit, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &Bucket{}
+		s.buckets[key] = bucket
+	}
+	return bucket.Take(limit, now), nil
+}
+
+// sweep drops the buckets untouched for an hour, a full bucket holds no
+// information so forgetting it is harmless for any limit refilling within it
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for key, bucket := range s.buckets {
+		if now.Sub(bucket.Updated) > time.Hour {
+			delete(s.buckets, key)
+		}
+	}
+}
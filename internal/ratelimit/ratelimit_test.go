@@ -0,0 +1,47 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{Rate: 1, Burst: 2}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	for i, want := range []int{1, 0} {
+		result, _ := store.Take("a", limit, now)
+		if !result.Allowed || result.Remaining != want {
+			t.Fatalf("take %d: %+v", i, result)
+		}
+	}
+	result, _ := store.Take("a", limit, now)
+	if result.Allowed || result.RetryAfter != time.Second || result.Reset != 2*time.Second {
+		t.Fatalf("empty bucket: %+v", result)
+	}
+	if other, _ := store.Take("b", limit, now); !other.Allowed {
+		t.Error("keys share a bucket")
+	}
+
+	result, _ = store.Take("a", limit, now.Add(1500*time.Millisecond))
+	if !result.Allowed || result.Remaining != 0 {
+		t.Errorf("after refill: %+v", result)
+	}
+	result, _ = store.Take("a", limit, now.Add(time.Hour))
+	if !result.Allowed || result.Remaining != 1 {
+		t.Errorf("refill is capped at the burst: %+v", result)
+	}
+}
+
+func TestParseLimit(t *testing.T) {
+	limit, err := ParseLimit("120/m")
+	if err != nil || limit.Burst != 120 || limit.Rate != 2 {
+		t.Errorf("got %+v, %v", limit, err)
+	}
+	for _, value := range []string{"", "10", "0/s", "10/d", "x/s"} {
+		if _, err := ParseLimit(value); err == nil {
+			t.Errorf("%q accepted", value)
+		}
+	}
+}
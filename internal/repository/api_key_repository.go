@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"crproductos/internal/models"
+	"database/sql"
+	"log/slog"
+)
+
+const apiKeyColumns = `id, "name", prefix, "role", created_at, revoked_at`
+
+type apiKeyRepository struct {
+	db *sql.DB
+}
+
+func NewAPIKeyRepository(db *sql.DB) APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+func scanAPIKey(row rowScanner, key *models.APIKey) error {
+	return row.Scan(&key.Id, &key.Name, &key.Prefix, &key.Role, &key.CreatedAt, &key.RevokedAt)
+}
+
+func (r *apiKeyRepository) GetAllKeys() ([]models.APIKey, error) {
+	rows, err := r.db.Query("select " + apiKeyColumns + " from api_key order by id")
+	if err != nil {
+		slog.Error("failed to query api_key", "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+	keys := []models.APIKey{}
+	for rows.Next() {
+		var key models.APIKey
+		if err := scanAPIKey(rows, &key); err != nil {
+			slog.Error("failed to scan", "err", err)
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// GetKeyByHash returns the key with the given hash, revoked keys included
+func (r *apiKeyRepository) GetKeyByHash(hash string) (models.APIKey, error) {
+	var key models.APIKey
+	err := scanAPIKey(r.db.QueryRow("select "+apiKeyColumns+" from api_key where hash = $1", hash), &key)
+	return key, err
+}
+
+func (r *apiKeyRepository) CreateKey(key models.APIKey, hash string) (models.APIKey, error) {
+	err := scanAPIKey(r.db.QueryRow(`insert into api_key ("name", prefix, hash, "role") values ($1, $2, $3, $4) returning `+apiKeyColumns,
+		key.Name, key.Prefix, hash, key.Role), &key)
+	if err != nil {
+		slog.Error("error during insert", "err", err)
+	}
+	return key, err
+}
+
+// RevokeKey stamps revoked_at, revoking an already revoked key keeps the
+// original time
+func (r *apiKeyRepository) RevokeKey(id string) (models.APIKey, error) {
+	var key models.APIKey
+	err := scanAPIKey(r.db.QueryRow("update api_key set revoked_at = coalesce(revoked_at, now()) where id = $1 returning "+apiKeyColumns, id), &key)
+	return key, err
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"crproductos/internal/models"
+	"database/sql"
+	"log/slog"
+)
+
+type brandRepository struct {
+	db *sql.DB
+}
+
+func NewBrandRepository(db *sql.DB) BrandRepository {
+	return &brandRepository{db: db}
+}
+
+func (r *brandRepository) GetAllBrands() ([]models.Brand, error) {
+	rows, err := r.db.Query("select id, \"name\" from brand order by \"name\"")
+	if err != nil {
+		slog.Error("failed to query brand", "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+	brands := []models.Brand{}
+	for rows.Next() {
+		var brand models.Brand
+		if err := rows.Scan(&brand.Id, &brand.Name); err != nil {
+			slog.Error("failed to scan", "err", err)
+			return nil, err
+		}
+		brands = append(brands, brand)
+	}
+	return brands, rows.Err()
+}
+
+func (r *brandRepository) GetBrandById(id string) (models.Brand, error) {
+	var brand models.Brand
+	err := r.db.QueryRow("select id, \"name\" from brand where id = $1", id).Scan(&brand.Id, &brand.Name)
+	if err != nil {
+		slog.Error("failed to scan", "err", err)
+	}
+	return brand, err
+}
+
+func (r *brandRepository) CreateBrand(brand models.Brand) (models.Brand, error) {
+	err := r.db.QueryRow("INSERT INTO public.brand (\"name\") VALUES($1) returning id;", brand.Name).Scan(&brand.Id)
+	if isUniqueViolation(err) {
+		return brand, models.ErrDuplicateBrand
+	}
+	return brand, err
+}
+
+func (r *brandRepository) UpdateBrand(id string, brand models.Brand) (models.Brand, error) {
+	err := r.db.QueryRow("UPDATE public.brand SET \"name\"=$1 WHERE id=$2 returning id;", brand.Name, id).Scan(&brand.Id)
+	if isUniqueViolation(err) {
+		return brand, models.ErrDuplicateBrand
+	}
+	return brand, err
+}
+
+func (r *brandRepository) DeleteBrand(id string) error {
+	result, err := r.db.Exec("DELETE FROM public.brand WHERE id=$1;", id)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"crproductos/internal/models"
+	"database/sql"
+	"log/slog"
+)
+
+type categoryRepository struct {
+	db *sql.DB
+}
+
+func NewCategoryRepository(db *sql.DB) CategoryRepository {
+	return &categoryRepository{db: db}
+}
+
+func (r *categoryRepository) GetAllCategories() ([]models.Category, error) {
+	rows, err := r.db.Query("select id, \"name\", parent_id from category order by id")
+	if err != nil {
+		slog.Error("failed to query category", "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+	categories := []models.Category{}
+	for rows.Next() {
+		var category models.Category
+		if err := rows.Scan(&category.Id, &category.Name, &category.ParentId); err != nil {
+			slog.Error("failed to scan", "err", err)
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+	return categories, rows.Err()
+}
+
+func (r *categoryRepository) GetCategoryById(id string) (models.Category, error) {
+	var category models.Category
+	err := r.db.QueryRow("select id, \"name\", parent_id from category where id = $1", id).Scan(&category.Id, &category.Name, &category.ParentId)
+	if err != nil {
+		slog.Error("failed to scan", "err", err)
+	}
+	return category, err
+}
+
+func (r *categoryRepository) CreateCategory(category models.Category) (models.Category, error) {
+	err := r.db.QueryRow("INSERT INTO public.category (\"name\", parent_id) VALUES($1, $2) returning id;", category.Name, category.ParentId).Scan(&category.Id)
+	if isForeignKeyViolation(err) {
+		return category, models.ErrCategoryNotFound
+	}
+	return category, err
+}
+
+// UpdateCategory: Renames and/or moves the category. Moving a category below
+// itself or one of its own subcategories is rejected with models.ErrCategoryCycle
+func (r *categoryRepository) UpdateCategory(id string, category models.Category) (models.Category, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return category, err
+	}
+	defer tx.Rollback()
+	if category.ParentId != nil {
+		var cycle bool
+		err = tx.QueryRow(`with recursive category_tree as (
+	select id from category where id = $1
+	union all
+	select category.id from category join category_tree on category.parent_id = category_tree.id
+) select exists (select 1 from category_tree where id = $2)`, id, *category.ParentId).Scan(&cycle)
+		if err != nil {
+			return category, err
+		}
+		if cycle {
+			return category, models.ErrCategoryCycle
+		}
+	}
+	err = tx.QueryRow("UPDATE public.category SET \"name\"=$1, parent_id=$2 WHERE id=$3 returning id;", category.Name, category.ParentId, id).Scan(&category.Id)
+	if isForeignKeyViolation(err) {
+		return category, models.ErrCategoryNotFound
+	}
+	if err != nil {
+		return category, err
+	}
+	return category, tx.Commit()
+}
+
+func (r *categoryRepository) DeleteCategory(id string) error {
+	result, err := r.db.Exec("DELETE FROM public.category WHERE id=$1;", id)
+	if isForeignKeyViolation(err) {
+		return models.ErrCategoryInUse
+	}
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// SetProductCategories: Replaces every category assigned to the product
+func (r *categoryRepository) SetProductCategories(productId string, categoryIds []int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err = lockProduct(tx, productId); err != nil {
+		return err
+	}
+	if _, err = tx.Exec("DELETE FROM public.product_category WHERE product_id=$1;", productId); err != nil {
+		return err
+	}
+	for _, categoryId := range categoryIds {
+		_, err = tx.Exec("INSERT INTO public.product_category (product_id, category_id) VALUES($1, $2) ON CONFLICT DO NOTHING;", productId, categoryId)
+		if isForeignKeyViolation(err) {
+			return models.ErrCategoryNotFound
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
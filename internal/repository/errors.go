@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"crproductos/internal/models"
+	"database/sql"
+	"errors"
+	"github.com/lib/pq"
+)
+
+// isUniqueViolation reports whether err is postgres' unique_violation
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// isForeignKeyViolation reports whether err is postgres' foreign_key_violation
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23503"
+}
+
+// lockProduct: Locks the product row for the rest of tx, returns sql.ErrNoRows
+// when the product does not exist
+func lockProduct(tx *sql.Tx, id string) error {
+	var productId int
+	return tx.QueryRow("select id from product where id = $1 for update", id).Scan(&productId)
+}
+
+// productReferenceError translates foreign key violations on the product table
+// into the matching domain error, other errors are returned untouched
+func productReferenceError(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != "23503" {
+		return err
+	}
+	switch pqErr.Constraint {
+	case "product_brand_id_fkey":
+		return models.ErrBrandNotFound
+	case "product_parent_id_fkey":
+		return errors.Join(models.ErrInvalidVariant, errors.New("parent product not found"))
+	}
+	return err
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"crproductos/internal/models"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+type exchangeRateRepository struct {
+	db *sql.DB
+}
+
+func NewExchangeRateRepository(db *sql.DB) ExchangeRateRepository {
+	return &exchangeRateRepository{db: db}
+}
+
+func (r *exchangeRateRepository) GetAllExchangeRates() ([]models.ExchangeRate, error) {
+	rows, err := r.db.Query("select id, base, quote, rate, effective_date from exchange_rate order by base, quote, effective_date desc")
+	if err != nil {
+		slog.Error("failed to query exchange_rate", "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+	rates := []models.ExchangeRate{}
+	for rows.Next() {
+		var rate models.ExchangeRate
+		if err := rows.Scan(&rate.Id, &rate.Base, &rate.Quote, &rate.Rate, &rate.EffectiveDate); err != nil {
+			slog.Error("failed to scan", "err", err)
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+	return rates, rows.Err()
+}
+
+func (r *exchangeRateRepository) CreateExchangeRate(rate models.ExchangeRate) (models.ExchangeRate, error) {
+	err := r.db.QueryRow("INSERT INTO public.exchange_rate (base, quote, rate, effective_date) VALUES($1, $2, $3, $4) returning id;", rate.Base, rate.Quote, rate.Rate, rate.EffectiveDate).Scan(&rate.Id)
+	if isUniqueViolation(err) {
+		return rate, models.ErrDuplicateExchangeRate
+	}
+	return rate, err
+}
+
+func (r *exchangeRateRepository) DeleteExchangeRate(id string) error {
+	result, err := r.db.Exec("DELETE FROM public.exchange_rate WHERE id=$1;", id)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// FindExchangeRate: Returns the newest base/quote rate already in effect at the
+// given time, models.ErrExchangeRateNotFound when there is none
+func (r *exchangeRateRepository) FindExchangeRate(base string, quote string, at time.Time) (models.ExchangeRate, error) {
+	var rate models.ExchangeRate
+	err := r.db.QueryRow("select id, base, quote, rate, effective_date from exchange_rate where base = $1 and quote = $2 and effective_date <= $3::date order by effective_date desc limit 1", base, quote, at).Scan(&rate.Id, &rate.Base, &rate.Quote, &rate.Rate, &rate.EffectiveDate)
+	if errors.Is(err, sql.ErrNoRows) {
+		return rate, models.ErrExchangeRateNotFound
+	}
+	return rate, err
+}
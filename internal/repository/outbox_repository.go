@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"crproductos/internal/events"
+	"crproductos/internal/models"
+	"database/sql"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+// OutboxChannel is the channel PublishPending notifies with the outbox id of
+// every event it publishes
+const OutboxChannel = "outbox_published"
+
+type outboxRepository struct {
+	db *sql.DB
+}
+
+func NewOutboxRepository(db *sql.DB) OutboxRepository {
+	return &outboxRepository{db: db}
+}
+
+// writeEvents inserts the events into the outbox as part of tx, so they are
+// only published when the change they describe is committed
+func writeEvents(ctx context.Context, tx *sql.Tx, pending ...events.Event) error {
+	for _, event := range pending {
+		_, err := execContext(ctx, tx, "insert into outbox (event_id, event_type, product_id, store, data, occurred_at) values ($1, $2, $3, $4, $5, $6)",
+			event.Id, event.Type, event.ProductId, event.Store, []byte(event.Data), event.OccurredAt)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeProductEvents: Writes an event of eventType carrying data, followed by
+// one price.changed event per store price that differs between before and
+// after. The changed prices are appended to the price history as well
+func writeProductEvents(ctx context.Context, tx *sql.Tx, eventType string, productId int, data any, before *models.Stores, after *models.Stores) error {
+	event, err := events.New(eventType, productId, "", data)
+	if err != nil {
+		return err
+	}
+	pending := []events.Event{event}
+	for _, change := range models.DiffStores(productId, before, after) {
+		if _, err := execContext(ctx, tx, "INSERT INTO public.price_history (product_id, store, price) VALUES($1, $2, $3);", productId, change.Store, change.Price); err != nil {
+			return err
+		}
+		priceEvent, err := events.New(events.PriceChanged, productId, change.Store, events.PriceChange{
+			Store:         change.Store,
+			PreviousPrice: change.PreviousPrice,
+			Price:         change.Price,
+		})
+		if err != nil {
+			return err
+		}
+		pending = append(pending, priceEvent)
+	}
+	return writeEvents(ctx, tx, pending...)
+}
+
+// lockStores: Locks the product row for the rest of tx and returns its store
+// prices, returns sql.ErrNoRows when the product does not exist
+func lockStores(ctx context.Context, tx *sql.Tx, id string) (*models.Stores, error) {
+	var stores *models.Stores
+	err := queryRowContext(ctx, tx, "select stores from product where id = $1 for update", id).Scan(&stores)
+	return stores, err
+}
+
+// PublishPending: Hands up to limit unpublished events to publish in the order
+// they were written and marks them published. Rows are locked with skip locked
+// so several dispatchers can run side by side. The first failure stops the
+// batch to keep the order, the event is retried on the next call. An event
+// published right before a crash is published again, consumers tell the
+// duplicates apart by the event id. Every published event is announced on
+// OutboxChannel, postgres only delivers the notifications once the batch
+// commits
+func (r *outboxRepository) PublishPending(limit int, publish func(events.Event) error) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	rows, err := tx.Query("select id, event_id, event_type, product_id, store, data, occurred_at from outbox where published_at is null order by id limit $1 for update skip locked", limit)
+	if err != nil {
+		slog.Error("failed to query outbox", "err", err)
+		return 0, err
+	}
+	var ids []int64
+	var pending []events.Event
+	for rows.Next() {
+		var id int64
+		var event events.Event
+		var data []byte
+		if err := rows.Scan(&id, &event.Id, &event.Type, &event.ProductId, &event.Store, &data, &event.OccurredAt); err != nil {
+			rows.Close()
+			slog.Error("failed to scan", "err", err)
+			return 0, err
+		}
+		event.Data = data
+		ids = append(ids, id)
+		pending = append(pending, event)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	published := 0
+	var publishErr error
+	for i, event := range pending {
+		if publishErr = publish(event); publishErr != nil {
+			if _, err := tx.Exec("update outbox set attempts = attempts + 1, last_error = $1 where id = $2", publishErr.Error(), ids[i]); err != nil {
+				return 0, err
+			}
+			break
+		}
+		if _, err := tx.Exec("update outbox set attempts = attempts + 1, last_error = '', published_at = now() where id = $1", ids[i]); err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec("select pg_notify($1, $2)", OutboxChannel, strconv.FormatInt(ids[i], 10)); err != nil {
+			return 0, err
+		}
+		published++
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return published, publishErr
+}
+
+// GetEvent returns the event stored under the outbox id
+func (r *outboxRepository) GetEvent(id int64) (events.Event, error) {
+	var event events.Event
+	var data []byte
+	err := r.db.QueryRow("select event_id, event_type, product_id, store, data, occurred_at from outbox where id = $1", id).
+		Scan(&event.Id, &event.Type, &event.ProductId, &event.Store, &data, &event.OccurredAt)
+	event.Data = data
+	return event, err
+}
+
+// DeletePublished removes the events published before the given time
+func (r *outboxRepository) DeletePublished(before time.Time) (int64, error) {
+	result, err := r.db.Exec("delete from outbox where published_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
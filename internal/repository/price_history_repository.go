@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"crproductos/internal/models"
+	"database/sql"
+	"fmt"
+	"github.com/lib/pq"
+	"log/slog"
+	"strings"
+)
+
+type priceHistoryRepository struct {
+	db *sql.DB
+}
+
+func NewPriceHistoryRepository(db *sql.DB) PriceHistoryRepository {
+	return &priceHistoryRepository{db: db}
+}
+
+// GetPriceHistory: Lists the recorded prices matching filter, oldest first
+// per product
+func (r *priceHistoryRepository) GetPriceHistory(filter models.PriceHistoryFilter) ([]models.PricePoint, error) {
+	var conditions []string
+	var args []interface{}
+	if len(filter.ProductIds) > 0 {
+		args = append(args, pq.Array(filter.ProductIds))
+		conditions = append(conditions, fmt.Sprintf("product_id = any($%d)", len(args)))
+	}
+	if filter.Store != "" {
+		args = append(args, filter.Store)
+		conditions = append(conditions, fmt.Sprintf("store = $%d", len(args)))
+	}
+	if filter.Since != nil {
+		args = append(args, *filter.Since)
+		conditions = append(conditions, fmt.Sprintf("recorded_at >= $%d", len(args)))
+	}
+	query := "select product_id, store, price, recorded_at from price_history"
+	if len(conditions) > 0 {
+		query += " where " + strings.Join(conditions, " and ")
+	}
+	rows, err := r.db.Query(query+" order by product_id, recorded_at, id", args...)
+	if err != nil {
+		slog.Error("failed to query price_history", "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+	points := []models.PricePoint{}
+	for rows.Next() {
+		var point models.PricePoint
+		if err := rows.Scan(&point.ProductId, &point.Store, &point.Price, &point.RecordedAt); err != nil {
+			slog.Error("failed to scan", "err", err)
+			return nil, err
+		}
+		points = append(points, point)
+	}
+	return points, rows.Err()
+}
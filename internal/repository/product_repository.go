@@ -1,120 +1,305 @@
 package repository
 
 import (
+	"context"
+	"crproductos/internal/events"
 	"crproductos/internal/models"
 	"crproductos/internal/utils"
 	"database/sql"
 	"errors"
 	"fmt"
-	_ "github.com/lib/pq"
-	"log"
+	"github.com/lib/pq"
+	"log/slog"
 	"reflect"
+	"strconv"
 	"strings"
 )
 
+// productColumns lists the columns scanned by scanProduct, barcodes, categories
+// and tags are aggregated from their own tables into JSON arrays
+const productColumns = `product.id, product."name", product.quantity, product.unit, product.brand_id, product.parent_id, product.stores, product.currencies,
+	coalesce((select json_agg(b.barcode order by b.barcode) from product_barcode b where b.product_id = product.id), '[]'),
+	coalesce((select json_agg(json_build_object('id', c.id, 'name', c."name", 'parentId', c.parent_id) order by c.id)
+		from product_category pc join category c on c.id = pc.category_id where pc.product_id = product.id), '[]'),
+	coalesce((select json_agg(t."name" order by t."name") from product_tag pt join tag t on t.id = pt.tag_id where pt.product_id = product.id), '[]')`
+
+// storePriceInColones is a store price of the product from jsonb_each_text,
+// aliased s, converted to colones with the latest rate for its currency or
+// the inverse of the latest colones rate. It is null when there is neither
+const storePriceInColones = `(case coalesce(nullif(product.currencies->>s.key, ''), 'CRC')
+	when 'CRC' then s.value::double precision
+	else s.value::double precision * coalesce(
+		(select r.rate from exchange_rate r where r.base = product.currencies->>s.key and r.quote = 'CRC'
+			and r.effective_date <= current_date order by r.effective_date desc limit 1),
+		(select 1 / r.rate from exchange_rate r where r.base = 'CRC' and r.quote = product.currencies->>s.key
+			and r.effective_date <= current_date order by r.effective_date desc limit 1))
+	end)`
+
 type userRepository struct {
 	db *sql.DB
 }
 
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanProduct(row rowScanner, product *models.Product) error {
+	return row.Scan(&product.Id, &product.Name, &product.Quantity, &product.Unit, &product.BrandId, &product.ParentId, &product.Stores, &product.Currencies, &product.Barcodes, &product.Categories, &product.Tags)
+}
+
+// scanPage is how many products ScanProducts reads per query
+const scanPage = 500
+
+// productFilterQuery: Builds the select of the page of up to limit products
+// matching filter with an id above after, in id order. The category filter
+// walks the category tree so subcategories are matched too
+func productFilterQuery(filter models.ProductFilter, after int, limit int) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	query := ""
+	if len(filter.Ids) > 0 {
+		args = append(args, pq.Array(filter.Ids))
+		conditions = append(conditions, fmt.Sprintf("product.id = any($%d)", len(args)))
+	}
+	if filter.CategoryId != nil {
+		args = append(args, *filter.CategoryId)
+		query = fmt.Sprintf(`with recursive category_tree as (
+	select id from category where id = $%d
+	union all
+	select category.id from category join category_tree on category.parent_id = category_tree.id
+) `, len(args))
+		conditions = append(conditions, "exists (select 1 from product_category pc where pc.product_id = product.id and pc.category_id in (select id from category_tree))")
+	}
+	for _, tag := range filter.Tags {
+		args = append(args, tag)
+		conditions = append(conditions, fmt.Sprintf("exists (select 1 from product_tag pt join tag t on t.id = pt.tag_id where pt.product_id = product.id and t.\"name\" = $%d)", len(args)))
+	}
+	if filter.BrandId != nil {
+		args = append(args, *filter.BrandId)
+		conditions = append(conditions, fmt.Sprintf("(product.brand_id = $%d or exists (select 1 from product parent where parent.id = product.parent_id and parent.brand_id = $%d))", len(args), len(args)))
+	}
+	if filter.ParentId != nil {
+		args = append(args, *filter.ParentId)
+		conditions = append(conditions, fmt.Sprintf("product.parent_id = $%d", len(args)))
+	}
+	if filter.MaxPrice != nil {
+		args = append(args, *filter.MaxPrice)
+		conditions = append(conditions, fmt.Sprintf("exists (select 1 from jsonb_each_text(product.stores) s where %s <= $%d)", storePriceInColones, len(args)))
+	}
+	args = append(args, after)
+	conditions = append(conditions, fmt.Sprintf("product.id > $%d", len(args)))
+	args = append(args, limit)
+	query += "select " + productColumns + " from product where " + strings.Join(conditions, " and ")
+	return query + fmt.Sprintf(" order by product.id limit $%d", len(args)), args
+}
+
+// replaceBarcodes: Swaps the barcodes of the product for the given ones within tx.
+// A barcode already owned by a different product is reported as models.ErrDuplicateBarcode
+func replaceBarcodes(ctx context.Context, tx *sql.Tx, id any, barcodes models.Barcodes) error {
+	if _, err := execContext(ctx, tx, "DELETE FROM public.product_barcode WHERE product_id=$1;", id); err != nil {
+		return err
+	}
+	for _, barcode := range barcodes {
+		if _, err := execContext(ctx, tx, "INSERT INTO public.product_barcode (barcode, product_id) VALUES($1, $2);", barcode, id); err != nil {
+			if isUniqueViolation(err) {
+				return fmt.Errorf("%w: %s", models.ErrDuplicateBarcode, barcode)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
 func NewProductRepository(db *sql.DB) ProductRepository {
 	return &userRepository{db: db}
 }
 
 // GetAllProducts: Receives the r.db struct instance and returns
-// either a list of all products found, or the corresponding error.
+// either a list of all products matching filter, or the corresponding error.
 // A successful GetAllProducts call will return err == nil
-func (r *userRepository) GetAllProducts() ([]models.ProductResponse, error) {
-	rows, err := r.db.Query("select id,\"name\",quantity,unit,stores from product")
+func (r *userRepository) GetAllProducts(ctx context.Context, filter models.ProductFilter) ([]models.ProductResponse, error) {
+	var products []models.ProductResponse
+	err := r.ScanProducts(ctx, filter, func(product models.ProductResponse) error {
+		products = append(products, product)
+		return nil
+	})
 	if err != nil {
-		log.Println("Failed to query product: ", err)
+		return nil, err
+	}
+	slog.DebugContext(ctx, "products found", "count", len(products))
+	return products, nil
+}
+
+// ScanProducts: Pages through the products by id, scanPage at a time. The
+// rows of a page are released before fn sees its products, so a slow fn,
+// writing to a client say, never holds a connection or a cursor open.
+// Products written during the scan show up in their latest state, or not
+// at all when they were deleted before their page was read
+func (r *userRepository) ScanProducts(ctx context.Context, filter models.ProductFilter, fn func(models.ProductResponse) error) error {
+	after := 0
+	for {
+		page, err := r.productPage(ctx, filter, after)
+		if err != nil {
+			return err
+		}
+		for _, product := range page {
+			if err := fn(product); err != nil {
+				return err
+			}
+		}
+		if len(page) < scanPage {
+			return nil
+		}
+		after = page[len(page)-1].Id
+	}
+}
+
+// productPage reads the page of the products matching filter after the id
+func (r *userRepository) productPage(ctx context.Context, filter models.ProductFilter, after int) ([]models.ProductResponse, error) {
+	query, args := productFilterQuery(filter, after, scanPage)
+	rows, err := queryContext(ctx, r.db, query, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to query product", "err", err)
 		return nil, err
 	}
 	defer rows.Close()
-	var products []models.ProductResponse
+	page := make([]models.ProductResponse, 0, scanPage)
 	for rows.Next() {
 		var product models.Product
-		if err := rows.Scan(&product.Id, &product.Name, &product.Quantity, &product.Unit, &product.Stores); err != nil {
-			log.Println("failed to scan: ", err)
+		if err := scanProduct(rows, &product); err != nil {
+			slog.ErrorContext(ctx, "failed to scan", "err", err)
 			return nil, err
 		}
-		log.Printf("Item found: %+v\n", product.ToJSON())
-		products = append(products, product.ToJSON())
+		page = append(page, product.ToJSON())
 	}
 	if err = rows.Err(); err != nil {
-		log.Println("row iteration error:", err)
+		slog.ErrorContext(ctx, "row iteration error", "err", err)
 		return nil, err
 	}
-	return products, nil
+	return page, nil
+}
+
+func (r *userRepository) GetProductById(ctx context.Context, id string) (models.Product, error) {
+	var product models.Product
+	if err := scanProduct(queryRowContext(ctx, r.db, "select "+productColumns+" from product where product.id = $1", id), &product); err != nil {
+		slog.ErrorContext(ctx, "failed to scan", "err", err)
+		return product, err
+	}
+	slog.DebugContext(ctx, "product found", "id", product.Id)
+	return product, nil
 }
 
-func (r *userRepository) GetProductById(id string) (models.Product, error) {
+func (r *userRepository) GetProductByBarcode(ctx context.Context, barcode string) (models.Product, error) {
 	var product models.Product
-	if err := r.db.QueryRow("select * from product where product.id = $1", id).Scan(&product.Id, &product.Name, &product.Quantity, &product.Unit, &product.Stores); err != nil {
-		log.Println("failed to scan: ", err)
+	query := "select " + productColumns + " from product join product_barcode on product_barcode.product_id = product.id where product_barcode.barcode = $1"
+	if err := scanProduct(queryRowContext(ctx, r.db, query, barcode), &product); err != nil {
+		slog.ErrorContext(ctx, "failed to scan", "err", err)
 		return product, err
 	}
-	log.Printf("Item found: %+v\n", product.ToJSON())
 	return product, nil
 }
 
-func (r *userRepository) CreateProduct(product models.ProductResponse) (models.ProductResponse, error) {
-	tx, err := r.db.Begin()
+func (r *userRepository) CreateProduct(ctx context.Context, product models.ProductResponse) (models.ProductResponse, error) {
+	tx, err := beginTx(ctx, r.db)
 	if err != nil {
-		log.Fatal(err)
+		slog.ErrorContext(ctx, "failed to begin transaction", "err", err)
+		return product, err
 	}
-	err = tx.QueryRow("INSERT INTO public.product (\"name\", quantity, unit, stores) VALUES($1, $2, $3, $4) returning id;", product.Name, product.Quantity, product.Unit, product.Stores).Scan(&product.Id)
+	err = queryRowContext(ctx, tx, "INSERT INTO public.product (\"name\", quantity, unit, brand_id, parent_id, stores, currencies) VALUES($1, $2, $3, $4, $5, $6, coalesce($7, '{}'::jsonb)) returning id;", product.Name, product.Quantity, product.Unit, product.BrandId, product.ParentId, product.Stores, product.Currencies).Scan(&product.Id)
 	if err != nil {
 		tx.Rollback()
-		log.Fatal("Error during insert: ", err)
+		slog.ErrorContext(ctx, "error during insert", "err", err)
+		return product, productReferenceError(err)
+	}
+	if err = replaceBarcodes(ctx, tx, product.Id, product.Barcodes); err != nil {
+		tx.Rollback()
+		slog.ErrorContext(ctx, "error inserting barcodes", "err", err)
 		return product, err
 	}
-	err = tx.Commit()
+	if err = writeProductEvents(ctx, tx, events.ProductCreated, product.Id, product, nil, product.Stores); err != nil {
+		tx.Rollback()
+		slog.ErrorContext(ctx, "error writing events", "err", err)
+		return product, err
+	}
+	err = commitTx(ctx, tx)
 	if err != nil {
-		log.Fatal("Error commiting: ", err)
+		slog.ErrorContext(ctx, "error committing", "err", err)
+		return product, err
 	}
 	return product, nil
 }
 
-func (r *userRepository) DeleteProduct(id string) error {
-	tx, err := r.db.Begin()
+func (r *userRepository) DeleteProduct(ctx context.Context, id string) error {
+	tx, err := beginTx(ctx, r.db)
 	if err != nil {
-		log.Fatal(err)
+		slog.ErrorContext(ctx, "failed to begin transaction", "err", err)
+		return err
 	}
-	_, err = tx.Exec("DELETE FROM public.product WHERE id=$1;", id)
+	var productId int
+	err = queryRowContext(ctx, tx, "DELETE FROM public.product WHERE id=$1 returning id;", id).Scan(&productId)
 	if err != nil {
 		tx.Rollback()
-		log.Fatal("Error during delete: ", err)
+		slog.ErrorContext(ctx, "error during delete", "err", err)
 		return err
 	}
-	err = tx.Commit()
+	if err = writeProductEvents(ctx, tx, events.ProductDeleted, productId, map[string]int{"id": productId}, nil, nil); err != nil {
+		tx.Rollback()
+		slog.ErrorContext(ctx, "error writing events", "err", err)
+		return err
+	}
+	err = commitTx(ctx, tx)
 	if err != nil {
-		log.Fatal("Error commiting: ", err)
+		slog.ErrorContext(ctx, "error committing", "err", err)
+		return err
 	}
 	return nil
 }
 
-func (r *userRepository) UpdateProduct(id string, product models.ProductResponse) (models.ProductResponse, error) {
-	tx, err := r.db.Begin()
+func (r *userRepository) UpdateProduct(ctx context.Context, id string, product models.ProductResponse) (models.ProductResponse, error) {
+	tx, err := beginTx(ctx, r.db)
 	if err != nil {
-		log.Fatal(err)
+		slog.ErrorContext(ctx, "failed to begin transaction", "err", err)
+		return product, err
 	}
-	_, err = tx.Exec("UPDATE public.product SET \"name\"=$1, quantity=$2, unit=$3, stores=$4 WHERE id=$5;", product.Name, product.Quantity, product.Unit, product.Stores, id)
+	before, err := lockStores(ctx, tx, id)
 	if err != nil {
 		tx.Rollback()
-		log.Fatal("Error during update: ", err)
 		return product, err
 	}
-	err = tx.Commit()
+	_, err = execContext(ctx, tx, "UPDATE public.product SET \"name\"=$1, quantity=$2, unit=$3, brand_id=$4, parent_id=$5, stores=$6, currencies=coalesce($7, '{}'::jsonb) WHERE id=$8;", product.Name, product.Quantity, product.Unit, product.BrandId, product.ParentId, product.Stores, product.Currencies, id)
 	if err != nil {
-		log.Fatal("Error commiting: ", err)
+		tx.Rollback()
+		slog.ErrorContext(ctx, "error during update", "err", err)
+		return product, productReferenceError(err)
+	}
+	if product.Barcodes != nil {
+		if err = replaceBarcodes(ctx, tx, id, product.Barcodes); err != nil {
+			tx.Rollback()
+			slog.ErrorContext(ctx, "error updating barcodes", "err", err)
+			return product, err
+		}
+	}
+	if productId, err := strconv.Atoi(id); err == nil {
+		product.Id = productId
+	}
+	if err = writeProductEvents(ctx, tx, events.ProductUpdated, product.Id, product, before, product.Stores); err != nil {
+		tx.Rollback()
+		slog.ErrorContext(ctx, "error writing events", "err", err)
+		return product, err
+	}
+	err = commitTx(ctx, tx)
+	if err != nil {
+		slog.ErrorContext(ctx, "error committing", "err", err)
+		return product, err
 	}
 	return product, nil
 }
 
-func (r *userRepository) PatchProduct(id string, product models.ProductResponse) (models.Product, error) {
-	tx, err := r.db.Begin()
+func (r *userRepository) PatchProduct(ctx context.Context, id string, product models.ProductResponse) (models.Product, error) {
+	tx, err := beginTx(ctx, r.db)
 	if err != nil {
-		log.Fatal(err)
+		slog.ErrorContext(ctx, "failed to begin transaction", "err", err)
+		return models.Product{}, err
 	}
 	var updateClauses []string
 	var args []interface{}
@@ -125,6 +310,9 @@ func (r *userRepository) PatchProduct(id string, product models.ProductResponse)
 	var updatedProduct models.Product
 	for i := 0; i < v.NumField(); i++ {
 		field := v.Field(i)
+		if !utils.IsColumn(t.Field(i)) {
+			continue
+		}
 
 		// Check if the field is a pointer
 		if field.Kind() == reflect.Ptr {
@@ -152,47 +340,78 @@ func (r *userRepository) PatchProduct(id string, product models.ProductResponse)
 		}
 
 	}
-	if len(updateClauses) == 0 {
-		fmt.Println("No fields to update")
+	if len(updateClauses) == 0 && product.Barcodes == nil {
+		slog.DebugContext(ctx, "no fields to update", "id", id)
+		tx.Rollback()
 		return updatedProduct, errors.New("No fields for update")
 	}
-	query := fmt.Sprintf("Update product set %s where id=$%d", strings.Join(updateClauses, ", "), argIndex)
-	args = append(args, id)
-	_, err = tx.Exec(query, args...)
+	before, err := lockStores(ctx, tx, id)
 	if err != nil {
 		tx.Rollback()
-		fmt.Println(err)
 		return updatedProduct, err
 	}
-	tx.QueryRow("select * from product where product.id = $1", id).Scan(&updatedProduct.Id, &updatedProduct.Name, &updatedProduct.Quantity, &updatedProduct.Unit, &updatedProduct.Stores)
-	err = tx.Commit()
+	if len(updateClauses) > 0 {
+		query := fmt.Sprintf("Update product set %s where id=$%d", strings.Join(updateClauses, ", "), argIndex)
+		args = append(args, id)
+		_, err = execContext(ctx, tx, query, args...)
+		if err != nil {
+			tx.Rollback()
+			slog.ErrorContext(ctx, "failed to patch product", "err", err)
+			return updatedProduct, productReferenceError(err)
+		}
+	}
+	if product.Barcodes != nil {
+		if err = replaceBarcodes(ctx, tx, id, product.Barcodes); err != nil {
+			tx.Rollback()
+			slog.ErrorContext(ctx, "failed to patch product", "err", err)
+			return updatedProduct, err
+		}
+	}
+	scanProduct(queryRowContext(ctx, tx, "select "+productColumns+" from product where product.id = $1", id), &updatedProduct)
+	if err = writeProductEvents(ctx, tx, events.ProductUpdated, updatedProduct.Id, updatedProduct.ToJSON(), before, updatedProduct.Stores); err != nil {
+		tx.Rollback()
+		slog.ErrorContext(ctx, "error writing events", "err", err)
+		return updatedProduct, err
+	}
+	err = commitTx(ctx, tx)
 	if err != nil {
-		fmt.Printf("Error commiting: %v", err)
+		slog.ErrorContext(ctx, "error committing", "err", err)
 		return updatedProduct, err
 	}
 	return updatedProduct, nil
 }
-func (r *userRepository) PatchStore(id string, jsonStore []byte) (models.Product, error) {
+func (r *userRepository) PatchStore(ctx context.Context, id string, jsonStore []byte) (models.Product, error) {
 
 	var updatedProduct models.Product
-	tx, err := r.db.Begin()
+	tx, err := beginTx(ctx, r.db)
 	if err != nil {
-		log.Fatal(err)
+		slog.ErrorContext(ctx, "failed to begin transaction", "err", err)
+		return updatedProduct, err
 	}
 
+	before, err := lockStores(ctx, tx, id)
+	if err != nil {
+		tx.Rollback()
+		return updatedProduct, err
+	}
 	query := "Update product set stores = stores || $1::jsonb where id=$2"
 
-	_, err = tx.Exec(query, string(jsonStore), id)
+	_, err = execContext(ctx, tx, query, string(jsonStore), id)
 	if err != nil {
 		tx.Rollback()
-		fmt.Printf("Failed to Patch: %v\n", err)
+		slog.ErrorContext(ctx, "failed to patch store", "err", err)
+		return updatedProduct, err
+	}
+	scanProduct(queryRowContext(ctx, tx, "select "+productColumns+" from product where product.id = $1", id), &updatedProduct)
+	if err = writeProductEvents(ctx, tx, events.ProductUpdated, updatedProduct.Id, updatedProduct.ToJSON(), before, updatedProduct.Stores); err != nil {
+		tx.Rollback()
+		slog.ErrorContext(ctx, "error writing events", "err", err)
 		return updatedProduct, err
 	}
-	tx.QueryRow("select * from product where product.id = $1", id).Scan(&updatedProduct.Id, &updatedProduct.Name, &updatedProduct.Quantity, &updatedProduct.Unit, &updatedProduct.Stores)
-	err = tx.Commit()
+	err = commitTx(ctx, tx)
 	if err != nil {
 		tx.Rollback()
-		fmt.Printf("Failed to Patch: %v\n", err)
+		slog.ErrorContext(ctx, "failed to patch store", "err", err)
 		return updatedProduct, err
 	}
 	return updatedProduct, nil
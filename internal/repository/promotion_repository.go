@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"crproductos/internal/models"
+	"database/sql"
+	"fmt"
+	"github.com/lib/pq"
+	"log/slog"
+	"strings"
+)
+
+const promotionColumns = `id, product_id, store, "type", value, buy, pay, starts_at, ends_at, conditions, description`
+
+type promotionRepository struct {
+	db *sql.DB
+}
+
+func NewPromotionRepository(db *sql.DB) PromotionRepository {
+	return &promotionRepository{db: db}
+}
+
+func scanPromotion(row rowScanner, promotion *models.Promotion) error {
+	return row.Scan(&promotion.Id, &promotion.ProductId, &promotion.Store, &promotion.Type, &promotion.Value, &promotion.Buy, &promotion.Pay,
+		&promotion.StartsAt, &promotion.EndsAt, &promotion.Conditions, &promotion.Description)
+}
+
+func (r *promotionRepository) GetPromotions(filter models.PromotionFilter) ([]models.Promotion, error) {
+	var conditions []string
+	var args []interface{}
+	if len(filter.ProductIds) > 0 {
+		args = append(args, pq.Array(filter.ProductIds))
+		conditions = append(conditions, fmt.Sprintf("product_id = any($%d)", len(args)))
+	}
+	if filter.Store != "" {
+		args = append(args, filter.Store)
+		conditions = append(conditions, fmt.Sprintf("store = $%d", len(args)))
+	}
+	if filter.ActiveAt != nil {
+		args = append(args, *filter.ActiveAt)
+		conditions = append(conditions, fmt.Sprintf("starts_at <= $%d and (ends_at is null or ends_at > $%d)", len(args), len(args)))
+	}
+	query := "select " + promotionColumns + " from promotion"
+	if len(conditions) > 0 {
+		query += " where " + strings.Join(conditions, " and ")
+	}
+	rows, err := r.db.Query(query+" order by product_id, store, starts_at", args...)
+	if err != nil {
+		slog.Error("failed to query promotion", "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+	promotions := []models.Promotion{}
+	for rows.Next() {
+		var promotion models.Promotion
+		if err := scanPromotion(rows, &promotion); err != nil {
+			slog.Error("failed to scan", "err", err)
+			return nil, err
+		}
+		promotions = append(promotions, promotion)
+	}
+	return promotions, rows.Err()
+}
+
+func (r *promotionRepository) GetPromotionById(id string) (models.Promotion, error) {
+	var promotion models.Promotion
+	err := scanPromotion(r.db.QueryRow("select "+promotionColumns+" from promotion where id = $1", id), &promotion)
+	return promotion, err
+}
+
+func (r *promotionRepository) CreatePromotion(promotion models.Promotion) (models.Promotion, error) {
+	err := r.db.QueryRow(`INSERT INTO public.promotion (product_id, store, "type", value, buy, pay, starts_at, ends_at, conditions, description)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) returning id;`,
+		promotion.ProductId, promotion.Store, promotion.Type, promotion.Value, promotion.Buy, promotion.Pay,
+		promotion.StartsAt, promotion.EndsAt, promotion.Conditions, promotion.Description).Scan(&promotion.Id)
+	if isForeignKeyViolation(err) {
+		return promotion, sql.ErrNoRows
+	}
+	return promotion, err
+}
+
+func (r *promotionRepository) UpdatePromotion(id string, promotion models.Promotion) (models.Promotion, error) {
+	err := r.db.QueryRow(`UPDATE public.promotion SET product_id=$1, store=$2, "type"=$3, value=$4, buy=$5, pay=$6, starts_at=$7, ends_at=$8, conditions=$9, description=$10
+		WHERE id=$11 returning id;`,
+		promotion.ProductId, promotion.Store, promotion.Type, promotion.Value, promotion.Buy, promotion.Pay,
+		promotion.StartsAt, promotion.EndsAt, promotion.Conditions, promotion.Description, id).Scan(&promotion.Id)
+	if isForeignKeyViolation(err) {
+		return promotion, sql.ErrNoRows
+	}
+	return promotion, err
+}
+
+func (r *promotionRepository) DeletePromotion(id string) error {
+	result, err := r.db.Exec("DELETE FROM public.promotion WHERE id=$1;", id)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
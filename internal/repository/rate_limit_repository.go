@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"crproductos/internal/ratelimit"
+	"database/sql"
+	"time"
+)
+
+type rateLimitRepository struct {
+	db *sql.DB
+}
+
+// NewRateLimitRepository returns a ratelimit.Store keeping the buckets in
+// postgres, so every replica draws from the same buckets
+func NewRateLimitRepository(db *sql.DB) RateLimitRepository {
+	return &rateLimitRepository{db: db}
+}
+
+// Take: Locks the bucket row, refills it and takes a token. A new key starts
+// with a full bucket
+func (r *rateLimitRepository) Take(key string, limit ratelimit.Limit, now time.Time) (ratelimit.Result, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return ratelimit.Result{}, err
+	}
+	defer tx.Rollback()
+	var bucket ratelimit.Bucket
+	err = tx.QueryRow(`insert into rate_limit_bucket ("key", tokens, updated_at) values ($1, $2, $3)
+		on conflict ("key") do update set "key" = excluded."key"
+		returning tokens, updated_at`, key, float64(limit.Burst), now).Scan(&bucket.Tokens, &bucket.Updated)
+	if err != nil {
+		return ratelimit.Result{}, err
+	}
+	result := bucket.Take(limit, now)
+	if _, err := tx.Exec(`update rate_limit_bucket set tokens = $1, updated_at = $2 where "key" = $3`, bucket.Tokens, bucket.Updated, key); err != nil {
+		return ratelimit.Result{}, err
+	}
+	return result, tx.Commit()
+}
+
+// DeleteIdle removes the buckets untouched since before, a full bucket needs
+// no row
+func (r *rateLimitRepository) DeleteIdle(before time.Time) (int64, error) {
+	result, err := r.db.Exec("delete from rate_limit_bucket where updated_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
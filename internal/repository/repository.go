@@ -1,13 +1,120 @@
 package repository
 
-import "crproductos/internal/models"
+import (
+	"context"
+	"crproductos/internal/events"
+	"crproductos/internal/models"
+	"crproductos/internal/ratelimit"
+	"time"
+)
 
 type ProductRepository interface {
-	GetAllProducts() ([]models.ProductResponse, error)
-	GetProductById(id string) (models.Product, error)
-	CreateProduct(product models.ProductResponse) (models.ProductResponse, error)
-	DeleteProduct(id string) error
-	UpdateProduct(id string, product models.ProductResponse) (models.ProductResponse, error)
-	PatchProduct(id string, product models.ProductResponse) (models.Product, error)
-	PatchStore(id string, jsonStore []byte) (models.Product, error)
+	GetAllProducts(ctx context.Context, filter models.ProductFilter) ([]models.ProductResponse, error)
+	// ScanProducts calls fn with every product GetAllProducts would return,
+	// a page at a time with no rows held open while fn runs, stopping at the
+	// first error fn returns
+	ScanProducts(ctx context.Context, filter models.ProductFilter, fn func(models.ProductResponse) error) error
+	GetProductById(ctx context.Context, id string) (models.Product, error)
+	GetProductByBarcode(ctx context.Context, barcode string) (models.Product, error)
+	CreateProduct(ctx context.Context, product models.ProductResponse) (models.ProductResponse, error)
+	DeleteProduct(ctx context.Context, id string) error
+	UpdateProduct(ctx context.Context, id string, product models.ProductResponse) (models.ProductResponse, error)
+	PatchProduct(ctx context.Context, id string, product models.ProductResponse) (models.Product, error)
+	PatchStore(ctx context.Context, id string, jsonStore []byte) (models.Product, error)
+}
+
+type CategoryRepository interface {
+	GetAllCategories() ([]models.Category, error)
+	GetCategoryById(id string) (models.Category, error)
+	CreateCategory(category models.Category) (models.Category, error)
+	UpdateCategory(id string, category models.Category) (models.Category, error)
+	DeleteCategory(id string) error
+	SetProductCategories(productId string, categoryIds []int) error
+}
+
+type TagRepository interface {
+	GetAllTags() ([]models.Tag, error)
+	CreateTag(tag models.Tag) (models.Tag, error)
+	UpdateTag(id string, tag models.Tag) (models.Tag, error)
+	DeleteTag(id string) error
+	SetProductTags(productId string, tags []string) error
+}
+
+type BrandRepository interface {
+	GetAllBrands() ([]models.Brand, error)
+	GetBrandById(id string) (models.Brand, error)
+	CreateBrand(brand models.Brand) (models.Brand, error)
+	UpdateBrand(id string, brand models.Brand) (models.Brand, error)
+	DeleteBrand(id string) error
+}
+
+type ExchangeRateRepository interface {
+	GetAllExchangeRates() ([]models.ExchangeRate, error)
+	CreateExchangeRate(rate models.ExchangeRate) (models.ExchangeRate, error)
+	DeleteExchangeRate(id string) error
+	FindExchangeRate(base string, quote string, at time.Time) (models.ExchangeRate, error)
+}
+
+type PromotionRepository interface {
+	GetPromotions(filter models.PromotionFilter) ([]models.Promotion, error)
+	GetPromotionById(id string) (models.Promotion, error)
+	CreatePromotion(promotion models.Promotion) (models.Promotion, error)
+	UpdatePromotion(id string, promotion models.Promotion) (models.Promotion, error)
+	DeletePromotion(id string) error
+}
+
+// WatchlistRepository: The methods taking a subscriber only see the watches
+// and alerts of that subscriber, an empty one sees all of them
+type WatchlistRepository interface {
+	GetWatches(filter models.WatchFilter) ([]models.Watch, error)
+	GetWatchById(id string, subscriber string) (models.Watch, error)
+	CreateWatch(watch models.Watch) (models.Watch, error)
+	UpdateWatch(id string, subscriber string, watch models.Watch) (models.Watch, error)
+	DeleteWatch(id string, subscriber string) error
+	GetAlerts(filter models.AlertFilter) ([]models.Alert, error)
+	CreateAlert(alert models.Alert) (models.Alert, error)
+	MarkAlertRead(id string, subscriber string) (models.Alert, error)
+}
+
+type WebhookRepository interface {
+	GetAllWebhooks() ([]models.Webhook, error)
+	GetWebhookById(id string) (models.Webhook, error)
+	CreateWebhook(webhook models.Webhook) (models.Webhook, error)
+	UpdateWebhook(id string, webhook models.Webhook) (models.Webhook, error)
+	DeleteWebhook(id string) error
+	GetActiveWebhooks() ([]models.Webhook, error)
+	CreateDelivery(delivery models.WebhookDelivery) (models.WebhookDelivery, error)
+	GetDeliveries(webhookId string) ([]models.WebhookDelivery, error)
+	GetDeliveryById(id string) (models.WebhookDelivery, error)
+	QueueDelivery(pending models.PendingDelivery) error
+	ClaimDueDeliveries(limit int, lease time.Duration) ([]models.PendingDelivery, error)
+	RescheduleDelivery(id int64, attempt int, at time.Time) error
+	DeletePendingDelivery(id int64) error
+}
+
+type PriceHistoryRepository interface {
+	GetPriceHistory(filter models.PriceHistoryFilter) ([]models.PricePoint, error)
+}
+
+type OutboxRepository interface {
+	PublishPending(limit int, publish func(events.Event) error) (int, error)
+	GetEvent(id int64) (events.Event, error)
+	DeletePublished(before time.Time) (int64, error)
+}
+
+type APIKeyRepository interface {
+	GetAllKeys() ([]models.APIKey, error)
+	GetKeyByHash(hash string) (models.APIKey, error)
+	CreateKey(key models.APIKey, hash string) (models.APIKey, error)
+	RevokeKey(id string) (models.APIKey, error)
+}
+
+type RateLimitRepository interface {
+	ratelimit.Store
+	DeleteIdle(before time.Time) (int64, error)
+}
+
+type StatsRepository interface {
+	CountProducts() (int, error)
+	CountStores() (int, error)
 }
@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"database/sql"
+)
+
+type statsRepository struct {
+	db *sql.DB
+}
+
+func NewStatsRepository(db *sql.DB) StatsRepository {
+	return &statsRepository{db: db}
+}
+
+func (r *statsRepository) CountProducts() (int, error) {
+	var count int
+	err := r.db.QueryRow("select count(*) from product").Scan(&count)
+	return count, err
+}
+
+// CountStores counts the distinct stores listing at least one product
+func (r *statsRepository) CountStores() (int, error) {
+	var count int
+	err := r.db.QueryRow(`select count(distinct store) from product,
+		jsonb_object_keys(case when jsonb_typeof(stores) = 'object' then stores else '{}'::jsonb end) as store`).Scan(&count)
+	return count, err
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"crproductos/internal/models"
+	"database/sql"
+	"log/slog"
+)
+
+type tagRepository struct {
+	db *sql.DB
+}
+
+func NewTagRepository(db *sql.DB) TagRepository {
+	return &tagRepository{db: db}
+}
+
+func (r *tagRepository) GetAllTags() ([]models.Tag, error) {
+	rows, err := r.db.Query("select id, \"name\" from tag order by \"name\"")
+	if err != nil {
+		slog.Error("failed to query tag", "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+	tags := []models.Tag{}
+	for rows.Next() {
+		var tag models.Tag
+		if err := rows.Scan(&tag.Id, &tag.Name); err != nil {
+			slog.Error("failed to scan", "err", err)
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+func (r *tagRepository) CreateTag(tag models.Tag) (models.Tag, error) {
+	err := r.db.QueryRow("INSERT INTO public.tag (\"name\") VALUES($1) returning id;", tag.Name).Scan(&tag.Id)
+	if isUniqueViolation(err) {
+		return tag, models.ErrDuplicateTag
+	}
+	return tag, err
+}
+
+func (r *tagRepository) UpdateTag(id string, tag models.Tag) (models.Tag, error) {
+	err := r.db.QueryRow("UPDATE public.tag SET \"name\"=$1 WHERE id=$2 returning id;", tag.Name, id).Scan(&tag.Id)
+	if isUniqueViolation(err) {
+		return tag, models.ErrDuplicateTag
+	}
+	return tag, err
+}
+
+func (r *tagRepository) DeleteTag(id string) error {
+	result, err := r.db.Exec("DELETE FROM public.tag WHERE id=$1;", id)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// SetProductTags: Replaces every tag of the product, tags that do not exist
+// yet are created on the fly
+func (r *tagRepository) SetProductTags(productId string, tags []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err = lockProduct(tx, productId); err != nil {
+		return err
+	}
+	if _, err = tx.Exec("DELETE FROM public.product_tag WHERE product_id=$1;", productId); err != nil {
+		return err
+	}
+	for _, name := range tags {
+		var tagId int
+		err = tx.QueryRow("INSERT INTO public.tag (\"name\") VALUES($1) ON CONFLICT (\"name\") DO UPDATE SET \"name\" = excluded.\"name\" returning id;", name).Scan(&tagId)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("INSERT INTO public.product_tag (product_id, tag_id) VALUES($1, $2) ON CONFLICT DO NOTHING;", productId, tagId)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"crproductos/internal/models"
+	"time"
+)
+
+// ObserveFunc records how long a repository method took, start is when the
+// method was called
+type ObserveFunc func(method string, start time.Time)
+
+type timedProductRepository struct {
+	repo    ProductRepository
+	observe ObserveFunc
+}
+
+// NewTimedProductRepository wraps repo and reports the duration of every
+// call to observe
+func NewTimedProductRepository(repo ProductRepository, observe ObserveFunc) ProductRepository {
+	return &timedProductRepository{repo: repo, observe: observe}
+}
+
+func (r *timedProductRepository) GetAllProducts(ctx context.Context, filter models.ProductFilter) ([]models.ProductResponse, error) {
+	defer r.observe("GetAllProducts", time.Now())
+	return r.repo.GetAllProducts(ctx, filter)
+}
+
+// ScanProducts is timed until the scan ends, the time fn takes included
+func (r *timedProductRepository) ScanProducts(ctx context.Context, filter models.ProductFilter, fn func(models.ProductResponse) error) error {
+	defer r.observe("ScanProducts", time.Now())
+	return r.repo.ScanProducts(ctx, filter, fn)
+}
+func (r *timedProductRepository) GetProductById(ctx context.Context, id string) (models.Product, error) {
+	defer r.observe("GetProductById", time.Now())
+	return r.repo.GetProductById(ctx, id)
+}
+func (r *timedProductRepository) GetProductByBarcode(ctx context.Context, barcode string) (models.Product, error) {
+	defer r.observe("GetProductByBarcode", time.Now())
+	return r.repo.GetProductByBarcode(ctx, barcode)
+}
+func (r *timedProductRepository) CreateProduct(ctx context.Context, product models.ProductResponse) (models.ProductResponse, error) {
+	defer r.observe("CreateProduct", time.Now())
+	return r.repo.CreateProduct(ctx, product)
+}
+func (r *timedProductRepository) DeleteProduct(ctx context.Context, id string) error {
+	defer r.observe("DeleteProduct", time.Now())
+	return r.repo.DeleteProduct(ctx, id)
+}
+func (r *timedProductRepository) UpdateProduct(ctx context.Context, id string, product models.ProductResponse) (models.ProductResponse, error) {
+	defer r.observe("UpdateProduct", time.Now())
+	return r.repo.UpdateProduct(ctx, id, product)
+}
+func (r *timedProductRepository) PatchProduct(ctx context.Context, id string, product models.ProductResponse) (models.Product, error) {
+	defer r.observe("PatchProduct", time.Now())
+	return r.repo.PatchProduct(ctx, id, product)
+}
+func (r *timedProductRepository) PatchStore(ctx context.Context, id string, jsonStore []byte) (models.Product, error) {
+	defer r.observe("PatchStore", time.Now())
+	return r.repo.PatchStore(ctx, id, jsonStore)
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"crproductos/internal/trace"
+	"database/sql"
+	"strings"
+)
+
+// sqlConn is what *sql.DB and *sql.Tx have in common
+type sqlConn interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// maxStatementLength caps the statement recorded on spans
+const maxStatementLength = 2000
+
+// startSQL starts a client span for a statement, named after its operation
+func startSQL(ctx context.Context, statement string) *trace.Span {
+	statement = strings.Join(strings.Fields(statement), " ")
+	operation, _, _ := strings.Cut(statement, " ")
+	operation = strings.ToLower(operation)
+	if operation == "with" {
+		operation = "select"
+	}
+	_, span := trace.Start(ctx, "sql "+operation, trace.KindClient)
+	if span != nil {
+		if len(statement) > maxStatementLength {
+			statement = statement[:maxStatementLength]
+		}
+		span.SetAttribute("db.system", "postgresql")
+		span.SetAttribute("db.operation", operation)
+		span.SetAttribute("db.statement", statement)
+	}
+	return span
+}
+
+func execContext(ctx context.Context, conn sqlConn, query string, args ...any) (sql.Result, error) {
+	span := startSQL(ctx, query)
+	defer span.End()
+	result, err := conn.ExecContext(ctx, query, args...)
+	span.RecordError(err)
+	return result, err
+}
+
+func queryContext(ctx context.Context, conn sqlConn, query string, args ...any) (*sql.Rows, error) {
+	span := startSQL(ctx, query)
+	defer span.End()
+	rows, err := conn.QueryContext(ctx, query, args...)
+	span.RecordError(err)
+	return rows, err
+}
+
+// queryRowContext times the statement itself, scanning the row is left out
+func queryRowContext(ctx context.Context, conn sqlConn, query string, args ...any) *sql.Row {
+	span := startSQL(ctx, query)
+	defer span.End()
+	row := conn.QueryRowContext(ctx, query, args...)
+	span.RecordError(row.Err())
+	return row
+}
+
+func beginTx(ctx context.Context, db *sql.DB) (*sql.Tx, error) {
+	span := startSQL(ctx, "begin")
+	defer span.End()
+	tx, err := db.BeginTx(ctx, nil)
+	span.RecordError(err)
+	return tx, err
+}
+
+func commitTx(ctx context.Context, tx *sql.Tx) error {
+	span := startSQL(ctx, "commit")
+	defer span.End()
+	err := tx.Commit()
+	span.RecordError(err)
+	return err
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"crproductos/internal/models"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+const (
+	watchColumns = "id, subscriber, product_id, store, target_price, currency, created_at"
+	alertColumns = "id, watch_id, subscriber, product_id, store, price, previous_price, target_price, currency, created_at, read_at"
+)
+
+type watchlistRepository struct {
+	db *sql.DB
+}
+
+func NewWatchlistRepository(db *sql.DB) WatchlistRepository {
+	return &watchlistRepository{db: db}
+}
+
+func scanWatch(row rowScanner, watch *models.Watch) error {
+	return row.Scan(&watch.Id, &watch.Subscriber, &watch.ProductId, &watch.Store, &watch.TargetPrice, &watch.Currency, &watch.CreatedAt)
+}
+
+func scanAlert(row rowScanner, alert *models.Alert) error {
+	return row.Scan(&alert.Id, &alert.WatchId, &alert.Subscriber, &alert.ProductId, &alert.Store, &alert.Price, &alert.PreviousPrice,
+		&alert.TargetPrice, &alert.Currency, &alert.CreatedAt, &alert.ReadAt)
+}
+
+func (r *watchlistRepository) GetWatches(filter models.WatchFilter) ([]models.Watch, error) {
+	var conditions []string
+	var args []interface{}
+	if filter.Subscriber != "" {
+		args = append(args, filter.Subscriber)
+		conditions = append(conditions, fmt.Sprintf("subscriber = $%d", len(args)))
+	}
+	if filter.ProductId != nil {
+		args = append(args, *filter.ProductId)
+		conditions = append(conditions, fmt.Sprintf("product_id = $%d", len(args)))
+	}
+	query := "select " + watchColumns + " from watch"
+	if len(conditions) > 0 {
+		query += " where " + strings.Join(conditions, " and ")
+	}
+	rows, err := r.db.Query(query+" order by id", args...)
+	if err != nil {
+		slog.Error("failed to query watch", "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+	watches := []models.Watch{}
+	for rows.Next() {
+		var watch models.Watch
+		if err := scanWatch(rows, &watch); err != nil {
+			slog.Error("failed to scan", "err", err)
+			return nil, err
+		}
+		watches = append(watches, watch)
+	}
+	return watches, rows.Err()
+}
+
+func (r *watchlistRepository) GetWatchById(id string, subscriber string) (models.Watch, error) {
+	var watch models.Watch
+	err := scanWatch(r.db.QueryRow("select "+watchColumns+" from watch where id = $1 and ($2 = '' or subscriber = $2)", id, subscriber), &watch)
+	return watch, err
+}
+
+func (r *watchlistRepository) CreateWatch(watch models.Watch) (models.Watch, error) {
+	err := r.db.QueryRow("INSERT INTO public.watch (subscriber, product_id, store, target_price, currency) VALUES($1, $2, $3, $4, $5) returning id, created_at;",
+		watch.Subscriber, watch.ProductId, watch.Store, watch.TargetPrice, watch.Currency).Scan(&watch.Id, &watch.CreatedAt)
+	if isForeignKeyViolation(err) {
+		return watch, sql.ErrNoRows
+	}
+	return watch, err
+}
+
+func (r *watchlistRepository) UpdateWatch(id string, subscriber string, watch models.Watch) (models.Watch, error) {
+	err := r.db.QueryRow("UPDATE public.watch SET subscriber=$1, product_id=$2, store=$3, target_price=$4, currency=$5 WHERE id=$6 and ($7 = '' or subscriber = $7) returning id, created_at;",
+		watch.Subscriber, watch.ProductId, watch.Store, watch.TargetPrice, watch.Currency, id, subscriber).Scan(&watch.Id, &watch.CreatedAt)
+	if isForeignKeyViolation(err) {
+		return watch, sql.ErrNoRows
+	}
+	return watch, err
+}
+
+func (r *watchlistRepository) DeleteWatch(id string, subscriber string) error {
+	result, err := r.db.Exec("DELETE FROM public.watch WHERE id=$1 and ($2 = '' or subscriber = $2);", id, subscriber)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *watchlistRepository) GetAlerts(filter models.AlertFilter) ([]models.Alert, error) {
+	var conditions []string
+	var args []interface{}
+	if filter.Subscriber != "" {
+		args = append(args, filter.Subscriber)
+		conditions = append(conditions, fmt.Sprintf("subscriber = $%d", len(args)))
+	}
+	if filter.UnreadOnly {
+		conditions = append(conditions, "read_at is null")
+	}
+	query := "select " + alertColumns + " from alert"
+	if len(conditions) > 0 {
+		query += " where " + strings.Join(conditions, " and ")
+	}
+	rows, err := r.db.Query(query+" order by created_at desc, id desc", args...)
+	if err != nil {
+		slog.Error("failed to query alert", "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+	alerts := []models.Alert{}
+	for rows.Next() {
+		var alert models.Alert
+		if err := scanAlert(rows, &alert); err != nil {
+			slog.Error("failed to scan", "err", err)
+			return nil, err
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts, rows.Err()
+}
+
+func (r *watchlistRepository) CreateAlert(alert models.Alert) (models.Alert, error) {
+	err := r.db.QueryRow(`INSERT INTO public.alert (watch_id, subscriber, product_id, store, price, previous_price, target_price, currency)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8) returning id, created_at;`,
+		alert.WatchId, alert.Subscriber, alert.ProductId, alert.Store, alert.Price, alert.PreviousPrice, alert.TargetPrice, alert.Currency).Scan(&alert.Id, &alert.CreatedAt)
+	return alert, err
+}
+
+func (r *watchlistRepository) MarkAlertRead(id string, subscriber string) (models.Alert, error) {
+	var alert models.Alert
+	err := scanAlert(r.db.QueryRow("UPDATE public.alert SET read_at = coalesce(read_at, now()) WHERE id=$1 and ($2 = '' or subscriber = $2) returning "+alertColumns, id, subscriber), &alert)
+	return alert, err
+}
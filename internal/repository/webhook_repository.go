@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"crproductos/internal/models"
+	"database/sql"
+	"log/slog"
+	"time"
+)
+
+const (
+	webhookColumns  = "id, url, secret, events, active, created_at"
+	deliveryColumns = "id, webhook_id, event_id, event_type, payload, attempt, status_code, error, success, created_at"
+	pendingColumns  = "id, webhook_id, event_id, event_type, payload, attempt, next_attempt_at"
+)
+
+type webhookRepository struct {
+	db *sql.DB
+}
+
+func NewWebhookRepository(db *sql.DB) WebhookRepository {
+	return &webhookRepository{db: db}
+}
+
+func scanWebhook(row rowScanner, webhook *models.Webhook) error {
+	return row.Scan(&webhook.Id, &webhook.Url, &webhook.Secret, &webhook.Events, &webhook.Active, &webhook.CreatedAt)
+}
+
+func scanDelivery(row rowScanner, delivery *models.WebhookDelivery) error {
+	var payload []byte
+	err := row.Scan(&delivery.Id, &delivery.WebhookId, &delivery.EventId, &delivery.EventType, &payload, &delivery.Attempt,
+		&delivery.StatusCode, &delivery.Error, &delivery.Success, &delivery.CreatedAt)
+	delivery.Payload = payload
+	return err
+}
+
+func (r *webhookRepository) queryWebhooks(query string, args ...interface{}) ([]models.Webhook, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		slog.Error("failed to query webhook", "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+	webhooks := []models.Webhook{}
+	for rows.Next() {
+		var webhook models.Webhook
+		if err := scanWebhook(rows, &webhook); err != nil {
+			slog.Error("failed to scan", "err", err)
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+func (r *webhookRepository) GetAllWebhooks() ([]models.Webhook, error) {
+	return r.queryWebhooks("select " + webhookColumns + " from webhook order by id")
+}
+
+func (r *webhookRepository) GetActiveWebhooks() ([]models.Webhook, error) {
+	return r.queryWebhooks("select " + webhookColumns + " from webhook where active order by id")
+}
+
+func (r *webhookRepository) GetWebhookById(id string) (models.Webhook, error) {
+	var webhook models.Webhook
+	err := scanWebhook(r.db.QueryRow("select "+webhookColumns+" from webhook where id = $1", id), &webhook)
+	return webhook, err
+}
+
+func (r *webhookRepository) CreateWebhook(webhook models.Webhook) (models.Webhook, error) {
+	err := r.db.QueryRow("INSERT INTO public.webhook (url, secret, events, active) VALUES($1, $2, $3, coalesce($4, true)) returning id, active, created_at;",
+		webhook.Url, webhook.Secret, webhook.Events, webhook.Active).Scan(&webhook.Id, &webhook.Active, &webhook.CreatedAt)
+	return webhook, err
+}
+
+// UpdateWebhook: Changes the url, events and active flag, the active flag
+// and the secret are only replaced when given
+func (r *webhookRepository) UpdateWebhook(id string, webhook models.Webhook) (models.Webhook, error) {
+	err := scanWebhook(r.db.QueryRow("UPDATE public.webhook SET url=$1, events=$2, active=coalesce($3, active), secret=coalesce(nullif($4, ''), secret) WHERE id=$5 returning "+webhookColumns,
+		webhook.Url, webhook.Events, webhook.Active, webhook.Secret, id), &webhook)
+	return webhook, err
+}
+
+func (r *webhookRepository) DeleteWebhook(id string) error {
+	result, err := r.db.Exec("DELETE FROM public.webhook WHERE id=$1;", id)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *webhookRepository) CreateDelivery(delivery models.WebhookDelivery) (models.WebhookDelivery, error) {
+	err := r.db.QueryRow(`INSERT INTO public.webhook_delivery (webhook_id, event_id, event_type, payload, attempt, status_code, error, success)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8) returning id, created_at;`,
+		delivery.WebhookId, delivery.EventId, delivery.EventType, []byte(delivery.Payload), delivery.Attempt, delivery.StatusCode, delivery.Error, delivery.Success).Scan(&delivery.Id, &delivery.CreatedAt)
+	return delivery, err
+}
+
+func (r *webhookRepository) GetDeliveries(webhookId string) ([]models.WebhookDelivery, error) {
+	rows, err := r.db.Query("select "+deliveryColumns+" from webhook_delivery where webhook_id = $1 order by created_at desc, id desc", webhookId)
+	if err != nil {
+		slog.Error("failed to query webhook_delivery", "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		var delivery models.WebhookDelivery
+		if err := scanDelivery(rows, &delivery); err != nil {
+			slog.Error("failed to scan", "err", err)
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+func (r *webhookRepository) GetDeliveryById(id string) (models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	err := scanDelivery(r.db.QueryRow("select "+deliveryColumns+" from webhook_delivery where id = $1", id), &delivery)
+	return delivery, err
+}
+
+// QueueDelivery: Queues the event for the webhook, doing nothing when it is
+// already queued
+func (r *webhookRepository) QueueDelivery(pending models.PendingDelivery) error {
+	_, err := r.db.Exec(`INSERT INTO public.webhook_pending (webhook_id, event_id, event_type, payload)
+		VALUES($1, $2, $3, $4) on conflict (webhook_id, event_id) do nothing;`,
+		pending.WebhookId, pending.EventId, pending.EventType, []byte(pending.Payload))
+	return err
+}
+
+// ClaimDueDeliveries: Returns up to limit deliveries whose next attempt is
+// due and pushes that attempt lease into the future, so other dispatchers
+// skip them meanwhile. A dispatcher dying mid delivery leaves the delivery to
+// be retried once the lease expires
+func (r *webhookRepository) ClaimDueDeliveries(limit int, lease time.Duration) ([]models.PendingDelivery, error) {
+	rows, err := r.db.Query(`UPDATE public.webhook_pending SET next_attempt_at = now() + make_interval(secs => $2)
+		WHERE id IN (select id from webhook_pending where next_attempt_at <= now() order by id limit $1 for update skip locked)
+		returning `+pendingColumns, limit, lease.Seconds())
+	if err != nil {
+		slog.Error("failed to claim webhook_pending", "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+	pending := []models.PendingDelivery{}
+	for rows.Next() {
+		var delivery models.PendingDelivery
+		var payload []byte
+		if err := rows.Scan(&delivery.Id, &delivery.WebhookId, &delivery.EventId, &delivery.EventType, &payload, &delivery.Attempt, &delivery.NextAttemptAt); err != nil {
+			slog.Error("failed to scan", "err", err)
+			return nil, err
+		}
+		delivery.Payload = payload
+		pending = append(pending, delivery)
+	}
+	return pending, rows.Err()
+}
+
+func (r *webhookRepository) RescheduleDelivery(id int64, attempt int, at time.Time) error {
+	_, err := r.db.Exec("UPDATE public.webhook_pending SET attempt=$1, next_attempt_at=$2 WHERE id=$3;", attempt, at, id)
+	return err
+}
+
+func (r *webhookRepository) DeletePendingDelivery(id int64) error {
+	_, err := r.db.Exec("DELETE FROM public.webhook_pending WHERE id=$1;", id)
+	return err
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"crproductos/internal/auth"
+	"crproductos/internal/models"
+	"crproductos/internal/repository"
+	"database/sql"
+	"errors"
+	"strings"
+)
+
+type apiKeyService struct {
+	repo repository.APIKeyRepository
+}
+type APIKeyService interface {
+	GetAllKeys() ([]models.APIKey, error)
+	IssueKey(key models.APIKey) (models.APIKey, error)
+	RevokeKey(id string) (models.APIKey, error)
+	Authenticate(key string) (models.APIKey, error)
+}
+
+func NewAPIKeyService(repo repository.APIKeyRepository) APIKeyService {
+	return &apiKeyService{repo: repo}
+}
+func (s *apiKeyService) GetAllKeys() ([]models.APIKey, error) {
+	return s.repo.GetAllKeys()
+}
+
+// IssueKey: Generates a key for the given name and role. Only its hash is
+// stored, the returned APIKey is the one place the plain key is shown
+func (s *apiKeyService) IssueKey(key models.APIKey) (models.APIKey, error) {
+	if err := key.Validate(); err != nil {
+		return key, err
+	}
+	plain, prefix, err := auth.GenerateKey()
+	if err != nil {
+		return key, err
+	}
+	key.Name = strings.TrimSpace(key.Name)
+	key.Prefix = prefix
+	issued, err := s.repo.CreateKey(key, auth.HashKey(plain))
+	if err != nil {
+		return issued, err
+	}
+	issued.Key = plain
+	return issued, nil
+}
+func (s *apiKeyService) RevokeKey(id string) (models.APIKey, error) {
+	return s.repo.RevokeKey(id)
+}
+
+// Authenticate returns the issued key matching the plain key, unknown and
+// revoked keys are rejected with ErrInvalidAPIKey
+func (s *apiKeyService) Authenticate(key string) (models.APIKey, error) {
+	apiKey, err := s.repo.GetKeyByHash(auth.HashKey(key))
+	if errors.Is(err, sql.ErrNoRows) {
+		return apiKey, models.ErrInvalidAPIKey
+	}
+	if err != nil {
+		return apiKey, err
+	}
+	if apiKey.RevokedAt != nil {
+		return apiKey, errors.Join(models.ErrInvalidAPIKey, errors.New("key was revoked"))
+	}
+	return apiKey, nil
+}
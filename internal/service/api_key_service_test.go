@@ -0,0 +1,77 @@
+package service
+
+import (
+	"crproductos/internal/models"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeAPIKeyRepository struct {
+	keys   []models.APIKey
+	hashes []string
+}
+
+func (f *fakeAPIKeyRepository) GetAllKeys() ([]models.APIKey, error) {
+	return f.keys, nil
+}
+func (f *fakeAPIKeyRepository) GetKeyByHash(hash string) (models.APIKey, error) {
+	for i, h := range f.hashes {
+		if h == hash {
+			return f.keys[i], nil
+		}
+	}
+	return models.APIKey{}, sql.ErrNoRows
+}
+func (f *fakeAPIKeyRepository) CreateKey(key models.APIKey, hash string) (models.APIKey, error) {
+	key.Id = len(f.keys) + 1
+	f.keys = append(f.keys, key)
+	f.hashes = append(f.hashes, hash)
+	return key, nil
+}
+func (f *fakeAPIKeyRepository) RevokeKey(id string) (models.APIKey, error) {
+	for i := range f.keys {
+		if strconv.Itoa(f.keys[i].Id) == id {
+			now := time.Now()
+			f.keys[i].RevokedAt = &now
+			return f.keys[i], nil
+		}
+	}
+	return models.APIKey{}, sql.ErrNoRows
+}
+
+func TestIssueAuthenticateRevoke(t *testing.T) {
+	repo := &fakeAPIKeyRepository{}
+	svc := NewAPIKeyService(repo)
+
+	if _, err := svc.IssueKey(models.APIKey{Name: "scraper", Role: "owner"}); !errors.Is(err, models.ErrInvalidAPIKey) {
+		t.Errorf("unknown role accepted: %v", err)
+	}
+	issued, err := svc.IssueKey(models.APIKey{Name: " scraper ", Role: models.RolePriceCollector})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(issued.Key, issued.Prefix+"_") || issued.Name != "scraper" {
+		t.Errorf("unexpected issued key %+v", issued)
+	}
+	if repo.hashes[0] == issued.Key || strings.Contains(repo.hashes[0], issued.Key) {
+		t.Error("plain key was stored")
+	}
+
+	key, err := svc.Authenticate(issued.Key)
+	if err != nil || key.Role != models.RolePriceCollector {
+		t.Fatalf("authenticate = %+v, %v", key, err)
+	}
+	if _, err := svc.Authenticate(issued.Key + "x"); !errors.Is(err, models.ErrInvalidAPIKey) {
+		t.Errorf("wrong key accepted: %v", err)
+	}
+	if _, err := svc.RevokeKey(strconv.Itoa(issued.Id)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Authenticate(issued.Key); !errors.Is(err, models.ErrInvalidAPIKey) {
+		t.Errorf("revoked key accepted: %v", err)
+	}
+}
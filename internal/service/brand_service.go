@@ -0,0 +1,45 @@
+package service
+
+import (
+	"crproductos/internal/models"
+	"crproductos/internal/repository"
+	"strings"
+)
+
+type brandService struct {
+	repo repository.BrandRepository
+}
+type BrandService interface {
+	GetAllBrands() ([]models.Brand, error)
+	GetBrandById(id string) (models.Brand, error)
+	CreateBrand(brand models.Brand) (models.Brand, error)
+	UpdateBrand(id string, brand models.Brand) (models.Brand, error)
+	DeleteBrand(id string) error
+}
+
+func NewBrandService(repo repository.BrandRepository) BrandService {
+	return &brandService{repo: repo}
+}
+func (s *brandService) GetAllBrands() ([]models.Brand, error) {
+	return s.repo.GetAllBrands()
+}
+func (s *brandService) GetBrandById(id string) (models.Brand, error) {
+	return s.repo.GetBrandById(id)
+}
+func (s *brandService) CreateBrand(brand models.Brand) (models.Brand, error) {
+	if err := brand.Validate(); err != nil {
+		return brand, err
+	}
+	brand.Name = strings.TrimSpace(brand.Name)
+	return s.repo.CreateBrand(brand)
+}
+func (s *brandService) UpdateBrand(id string, brand models.Brand) (models.Brand, error) {
+	if err := brand.Validate(); err != nil {
+		return brand, err
+	}
+	brand.Name = strings.TrimSpace(brand.Name)
+	return s.repo.UpdateBrand(id, brand)
+}
+func (s *brandService) DeleteBrand(id string) error {
+	return s.repo.DeleteBrand(id)
+}
@@ -0,0 +1,266 @@
+package service
+
+import (
+	"context"
+	"crproductos/internal/cache"
+	"crproductos/internal/events"
+	"crproductos/internal/models"
+	"encoding/json"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+// maxCachedScan is the longest list ScanProducts keeps in the cache, longer
+// ones are streamed through without being held in memory
+const maxCachedScan = 1000
+
+// Cached entries are keyed under generations, replacing a generation leaves
+// every entry cached under it behind at once. listGenerationKey covers the
+// lists, productsGenerationKey every product and productGenerationKey a
+// single product
+const (
+	listGenerationKey     = "products:lists:generation"
+	productsGenerationKey = "products:generation"
+)
+
+func productGenerationKey(id string) string {
+	return "products:id:" + id + ":generation"
+}
+
+// ProductInvalidator drops cached product reads, Invalidate the given
+// products and every list, InvalidateAll everything
+type ProductInvalidator interface {
+	Invalidate(ctx context.Context, productIds ...string)
+	InvalidateAll(ctx context.Context)
+}
+
+// ProductCache is a ProductService caching its reads. Its Publish drops the
+// product of every event, so writes made by other processes reach it when it
+// listens to the outbox
+type ProductCache interface {
+	ProductService
+	ProductInvalidator
+	events.Publisher
+}
+
+type cachedProductService struct {
+	svc     ProductService
+	store   cache.Store
+	ttl     time.Duration
+	observe func(method string, hit bool)
+}
+
+// NewCachedProductService: Serves GetAllProducts and GetProductById from
+// store for up to ttl. Every write drops the product it touched and all the
+// cached lists, before the write and again after it, so a read racing with
+// the write can't cache what it read before the commit. observe, when not
+// nil, is told about every lookup. Writes made elsewhere are dropped by the
+// category and tag services given WithProductCache and by the outbox events
+// handed to Publish
+func NewCachedProductService(svc ProductService, store cache.Store, ttl time.Duration, observe func(method string, hit bool)) ProductCache {
+	return &cachedProductService{svc: svc, store: store, ttl: ttl, observe: observe}
+}
+
+// cached: Returns the value stored under key, calling load and storing its
+// result on a miss. Store failures are logged and the call goes through to
+// load, errors are never cached
+func cached[T any](ctx context.Context, s *cachedProductService, method string, key string, load func() (T, error)) (T, error) {
+	value, ok, err := s.store.Get(key)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to read cache", "key", key, "err", err)
+	}
+	var out T
+	if ok {
+		if err := json.Unmarshal(value, &out); err == nil {
+			s.record(ctx, method, true)
+			return out, nil
+		}
+	}
+	s.record(ctx, method, false)
+	out, err = load()
+	if err != nil {
+		return out, err
+	}
+	if value, err := json.Marshal(out); err == nil {
+		if err := s.store.Set(key, value, s.ttl); err != nil {
+			slog.ErrorContext(ctx, "failed to write cache", "key", key, "err", err)
+		}
+	}
+	return out, nil
+}
+
+func (s *cachedProductService) record(ctx context.Context, method string, hit bool) {
+	cache.Record(ctx, hit)
+	if s.observe != nil {
+		s.observe(method, hit)
+	}
+}
+
+// productKey is the key of the product under the current generations
+func (s *cachedProductService) productKey(ctx context.Context, id string) string {
+	return "products:id:" + id + ":" + s.generation(ctx, productsGenerationKey) + ":" + s.generation(ctx, productGenerationKey(id))
+}
+
+// generation returns the generation stored under key, starting a new one
+// when the store lost it
+func (s *cachedProductService) generation(ctx context.Context, key string) string {
+	generation, ok, err := s.store.Get(key)
+	if err == nil && ok {
+		return string(generation)
+	}
+	return s.rotate(ctx, key)
+}
+
+func (s *cachedProductService) rotate(ctx context.Context, key string) string {
+	generation := events.NewId()
+	if err := s.store.Set(key, []byte(generation), 0); err != nil {
+		slog.ErrorContext(ctx, "failed to write cache", "key", key, "err", err)
+	}
+	return generation
+}
+
+func (s *cachedProductService) Invalidate(ctx context.Context, productIds ...string) {
+	for _, id := range productIds {
+		s.rotate(ctx, productGenerationKey(id))
+	}
+	s.rotate(ctx, listGenerationKey)
+}
+
+func (s *cachedProductService) InvalidateAll(ctx context.Context) {
+	s.rotate(ctx, productsGenerationKey)
+	s.rotate(ctx, listGenerationKey)
+}
+
+// Publish drops the product of the event, events without one drop everything
+func (s *cachedProductService) Publish(event events.Event) error {
+	if event.ProductId == 0 {
+		s.InvalidateAll(context.Background())
+	} else {
+		s.Invalidate(context.Background(), strconv.Itoa(event.ProductId))
+	}
+	return nil
+}
+
+func (s *cachedProductService) listKey(ctx context.Context, filter models.ProductFilter) string {
+	filterKey, _ := json.Marshal(filter)
+	return "products:list:" + s.generation(ctx, listGenerationKey) + ":" + string(filterKey)
+}
+
+func (s *cachedProductService) GetAllProducts(ctx context.Context, filter models.ProductFilter) ([]models.ProductResponse, error) {
+	key := s.listKey(ctx, filter)
+	return cached(ctx, s, "GetAllProducts", key, func() ([]models.ProductResponse, error) {
+		return s.svc.GetAllProducts(ctx, filter)
+	})
+}
+
+// ScanProducts: Replays the list cached by GetAllProducts or an earlier scan.
+// On a miss the products are streamed from svc and the list is cached when
+// it has at most maxCachedScan products
+func (s *cachedProductService) ScanProducts(ctx context.Context, filter models.ProductFilter, fn func(models.ProductResponse) error) error {
+	key := s.listKey(ctx, filter)
+	value, ok, err := s.store.Get(key)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to read cache", "key", key, "err", err)
+	}
+	var products []models.ProductResponse
+	if ok && json.Unmarshal(value, &products) == nil {
+		s.record(ctx, "ScanProducts", true)
+		for _, product := range products {
+			if err := fn(product); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	s.record(ctx, "ScanProducts", false)
+	products = []models.ProductResponse{}
+	err = s.svc.ScanProducts(ctx, filter, func(product models.ProductResponse) error {
+		if products != nil {
+			if products = append(products, product); len(products) > maxCachedScan {
+				products = nil
+			}
+		}
+		return fn(product)
+	})
+	if err != nil || products == nil {
+		return err
+	}
+	if value, err := json.Marshal(products); err == nil {
+		if err := s.store.Set(key, value, s.ttl); err != nil {
+			slog.ErrorContext(ctx, "failed to write cache", "key", key, "err", err)
+		}
+	}
+	return nil
+}
+
+func (s *cachedProductService) GetProductById(ctx context.Context, id string) (models.Product, error) {
+	return cached(ctx, s, "GetProductById", s.productKey(ctx, id), func() (models.Product, error) {
+		return s.svc.GetProductById(ctx, id)
+	})
+}
+
+func (s *cachedProductService) GetProductByBarcode(ctx context.Context, barcode string) (models.Product, error) {
+	return s.svc.GetProductByBarcode(ctx, barcode)
+}
+
+func (s *cachedProductService) CreateProduct(ctx context.Context, product models.ProductResponse) (models.ProductResponse, error) {
+	s.Invalidate(ctx)
+	defer s.Invalidate(ctx)
+	return s.svc.CreateProduct(ctx, product)
+}
+
+func (s *cachedProductService) DeleteProduct(ctx context.Context, id string) error {
+	s.Invalidate(ctx, id)
+	defer s.Invalidate(ctx, id)
+	return s.svc.DeleteProduct(ctx, id)
+}
+
+func (s *cachedProductService) UpdateProduct(ctx context.Context, id string, product models.ProductResponse) (models.ProductResponse, error) {
+	s.Invalidate(ctx, id)
+	defer s.Invalidate(ctx, id)
+	return s.svc.UpdateProduct(ctx, id, product)
+}
+
+func (s *cachedProductService) PatchProduct(ctx context.Context, id string, product models.ProductResponse) (models.Product, error) {
+	s.Invalidate(ctx, id)
+	defer s.Invalidate(ctx, id)
+	return s.svc.PatchProduct(ctx, id, product)
+}
+
+func (s *cachedProductService) PatchStore(ctx context.Context, id string, jsonStore []byte) (models.Product, error) {
+	s.Invalidate(ctx, id)
+	defer s.Invalidate(ctx, id)
+	return s.svc.PatchStore(ctx, id, jsonStore)
+}
+
+func (s *cachedProductService) CompareProducts(ctx context.Context, filter models.ProductFilter) ([]models.VariantComparison, error) {
+	return s.svc.CompareProducts(ctx, filter)
+}
+
+// CatalogOption plugs the product cache into the category and tag services,
+// whose writes change how products read
+type CatalogOption func(*catalogWrites)
+
+// WithProductCache has the writes drop what they change from cache
+func WithProductCache(cache ProductInvalidator) CatalogOption {
+	return func(c *catalogWrites) {
+		c.cache = cache
+	}
+}
+
+type catalogWrites struct {
+	cache ProductInvalidator
+}
+
+// invalidate drops the cached product, or every product when productId is
+// empty. Writes call it before and after writing, like the product cache
+func (c catalogWrites) invalidate(productId string) {
+	switch {
+	case c.cache == nil:
+	case productId == "":
+		c.cache.InvalidateAll(context.Background())
+	default:
+		c.cache.Invalidate(context.Background(), productId)
+	}
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"crproductos/internal/cache"
+	"crproductos/internal/events"
+	"crproductos/internal/models"
+	"crproductos/internal/repository"
+	"database/sql"
+	"testing"
+	"time"
+)
+
+// countingProductService serves a single product and counts the reads
+type countingProductService struct {
+	ProductService
+	reads   int
+	price   float64
+	missing bool
+}
+
+func (s *countingProductService) GetAllProducts(ctx context.Context, filter models.ProductFilter) ([]models.ProductResponse, error) {
+	s.reads++
+	return []models.ProductResponse{{Id: 1, Stores: &models.Stores{"pali": s.price}}}, nil
+}
+
+func (s *countingProductService) ScanProducts(ctx context.Context, filter models.ProductFilter, fn func(models.ProductResponse) error) error {
+	products, _ := s.GetAllProducts(ctx, filter)
+	return fn(products[0])
+}
+
+func (s *countingProductService) GetProductById(ctx context.Context, id string) (models.Product, error) {
+	s.reads++
+	if s.missing {
+		return models.Product{}, sql.ErrNoRows
+	}
+	return models.Product{Id: 1, Name: sql.NullString{String: "Leche", Valid: true}, Stores: &models.Stores{"pali": s.price}}, nil
+}
+
+func (s *countingProductService) PatchStore(ctx context.Context, id string, jsonStore []byte) (models.Product, error) {
+	s.price = 900
+	return models.Product{Id: 1}, nil
+}
+
+func TestCachedProductService(t *testing.T) {
+	inner := &countingProductService{price: 950}
+	lookups := map[bool]int{}
+	svc := NewCachedProductService(inner, cache.NewLRU(10), time.Minute, func(method string, hit bool) { lookups[hit]++ })
+	ctx, status := cache.WithStatus(context.Background())
+	category := 3
+
+	for i := 0; i < 3; i++ {
+		svc.GetProductById(ctx, "1")
+		svc.GetAllProducts(ctx, models.ProductFilter{})
+	}
+	products, _ := svc.GetAllProducts(ctx, models.ProductFilter{CategoryId: &category})
+	if inner.reads != 3 || lookups[true] != 4 || lookups[false] != 3 || status.String() != "MISS" {
+		t.Errorf("got %d reads, lookups %v, status %s", inner.reads, lookups, status)
+	}
+	product, _ := svc.GetProductById(context.Background(), "1")
+	if !product.Name.Valid || product.Name.String != "Leche" || (*products[0].Stores)["pali"] != 950 {
+		t.Errorf("cached values changed on the way: %+v %+v", product, products)
+	}
+
+	svc.PatchStore(ctx, "1", []byte(`{"pali": 900}`))
+	product, _ = svc.GetProductById(ctx, "1")
+	products, _ = svc.GetAllProducts(ctx, models.ProductFilter{CategoryId: &category})
+	if (*product.Stores)["pali"] != 900 || (*products[0].Stores)["pali"] != 900 {
+		t.Errorf("stale reads after a write: %+v %+v", product, products)
+	}
+
+	inner.missing = true
+	svc.GetProductById(ctx, "2")
+	svc.GetProductById(ctx, "2")
+	if lookups[false] != 7 {
+		t.Errorf("errors should not be cached, got %v", lookups)
+	}
+}
+
+func TestCachedScanProducts(t *testing.T) {
+	inner := &countingProductService{price: 950}
+	svc := NewCachedProductService(inner, cache.NewLRU(10), time.Minute, nil)
+	ctx := context.Background()
+	scan := func() []models.ProductResponse {
+		var products []models.ProductResponse
+		svc.ScanProducts(ctx, models.ProductFilter{}, func(product models.ProductResponse) error {
+			products = append(products, product)
+			return nil
+		})
+		return products
+	}
+
+	scan()
+	svc.GetAllProducts(ctx, models.ProductFilter{})
+	if products := scan(); inner.reads != 1 || len(products) != 1 || (*products[0].Stores)["pali"] != 950 {
+		t.Errorf("got %d reads, %+v", inner.reads, products)
+	}
+	svc.PatchStore(ctx, "1", []byte(`{"pali": 900}`))
+	if products := scan(); inner.reads != 2 || (*products[0].Stores)["pali"] != 900 {
+		t.Errorf("stale scan after a write: %d reads, %+v", inner.reads, products)
+	}
+}
+
+// racingProductService runs write once a read has loaded the product, so
+// the write commits before the read stores what it loaded
+type racingProductService struct {
+	*countingProductService
+	write func()
+}
+
+func (s *racingProductService) race() {
+	if write := s.write; write != nil {
+		s.write = nil
+		write()
+	}
+}
+
+func (s *racingProductService) GetProductById(ctx context.Context, id string) (models.Product, error) {
+	defer s.race()
+	return s.countingProductService.GetProductById(ctx, id)
+}
+
+func (s *racingProductService) GetAllProducts(ctx context.Context, filter models.ProductFilter) ([]models.ProductResponse, error) {
+	defer s.race()
+	return s.countingProductService.GetAllProducts(ctx, filter)
+}
+
+func TestCachedProductServiceWriteDuringRead(t *testing.T) {
+	inner := &racingProductService{countingProductService: &countingProductService{price: 950}}
+	svc := NewCachedProductService(inner, cache.NewLRU(10), time.Minute, nil)
+	ctx := context.Background()
+	write := func() { svc.PatchStore(ctx, "1", []byte(`{"pali": 900}`)) }
+
+	inner.write = write
+	svc.GetProductById(ctx, "1")
+	inner.write = write
+	svc.GetAllProducts(ctx, models.ProductFilter{})
+	product, _ := svc.GetProductById(ctx, "1")
+	products, _ := svc.GetAllProducts(ctx, models.ProductFilter{})
+	if (*product.Stores)["pali"] != 900 || (*products[0].Stores)["pali"] != 900 {
+		t.Errorf("a read racing with the write cached the old price: %+v %+v", product, products)
+	}
+}
+
+// memoryTags accepts every product tag assignment
+type memoryTags struct {
+	repository.TagRepository
+}
+
+func (memoryTags) SetProductTags(productId string, tags []string) error { return nil }
+func (memoryTags) DeleteTag(id string) error                            { return nil }
+
+func TestCachedProductServiceOutsideWrites(t *testing.T) {
+	inner := &countingProductService{price: 950}
+	svc := NewCachedProductService(inner, cache.NewLRU(10), time.Minute, nil)
+	tags := NewTagService(memoryTags{}, WithProductCache(svc))
+	ctx := context.Background()
+	read := func() {
+		svc.GetProductById(ctx, "1")
+		svc.GetAllProducts(ctx, models.ProductFilter{})
+	}
+
+	read()
+	read()
+	if inner.reads != 2 {
+		t.Fatalf("expected the second reads to be cached, got %d reads", inner.reads)
+	}
+	for _, write := range []func(){
+		func() { svc.Publish(events.Event{Id: "1", Type: events.PriceChanged, ProductId: 1}) },
+		func() { tags.SetProductTags("1", []string{"lacteos"}) },
+		func() { tags.DeleteTag("2") },
+	} {
+		before := inner.reads
+		write()
+		read()
+		if inner.reads != before+2 {
+			t.Errorf("expected the product and the list to be read again, got %d reads", inner.reads-before)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"crproductos/internal/models"
+	"crproductos/internal/repository"
+)
+
+type categoryService struct {
+	catalogWrites
+	repo repository.CategoryRepository
+}
+type CategoryService interface {
+	GetAllCategories() ([]models.Category, error)
+	GetCategoryById(id string) (models.Category, error)
+	CreateCategory(category models.Category) (models.Category, error)
+	UpdateCategory(id string, category models.Category) (models.Category, error)
+	DeleteCategory(id string) error
+	SetProductCategories(productId string, categoryIds []int) error
+}
+
+func NewCategoryService(repo repository.CategoryRepository, opts ...CatalogOption) CategoryService {
+	s := &categoryService{repo: repo}
+	for _, opt := range opts {
+		opt(&s.catalogWrites)
+	}
+	return s
+}
+func (s *categoryService) GetAllCategories() ([]models.Category, error) {
+	return s.repo.GetAllCategories()
+}
+func (s *categoryService) GetCategoryById(id string) (models.Category, error) {
+	return s.repo.GetCategoryById(id)
+}
+func (s *categoryService) CreateCategory(category models.Category) (models.Category, error) {
+	if err := category.Validate(); err != nil {
+		return category, err
+	}
+	return s.repo.CreateCategory(category)
+}
+func (s *categoryService) UpdateCategory(id string, category models.Category) (models.Category, error) {
+	if err := category.Validate(); err != nil {
+		return category, err
+	}
+	// renames and moves show in every product of the category and its subtree
+	s.invalidate("")
+	defer s.invalidate("")
+	return s.repo.UpdateCategory(id, category)
+}
+func (s *categoryService) DeleteCategory(id string) error {
+	s.invalidate("")
+	defer s.invalidate("")
+	return s.repo.DeleteCategory(id)
+}
+func (s *categoryService) SetProductCategories(productId string, categoryIds []int) error {
+	s.invalidate(productId)
+	defer s.invalidate(productId)
+	return s.repo.SetProductCategories(productId, categoryIds)
+}
@@ -0,0 +1,208 @@
+package service
+
+import (
+	"crproductos/internal/models"
+	"crproductos/internal/repository"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type exchangeRateService struct {
+	repo repository.ExchangeRateRepository
+}
+type ExchangeRateService interface {
+	GetAllExchangeRates() ([]models.ExchangeRate, error)
+	CreateExchangeRate(rate models.ExchangeRate) (models.ExchangeRate, error)
+	DeleteExchangeRate(id string) error
+	ConvertProducts(products []models.ProductResponse, currency string, at time.Time) ([]models.ProductResponse, error)
+	ConvertComparisons(comparisons []models.VariantComparison, currency string, at time.Time) ([]models.VariantComparison, error)
+	Convert(amount float64, from string, to string, at time.Time) (float64, error)
+}
+
+func NewExchangeRateService(repo repository.ExchangeRateRepository) ExchangeRateService {
+	return &exchangeRateService{repo: repo}
+}
+func (s *exchangeRateService) GetAllExchangeRates() ([]models.ExchangeRate, error) {
+	return s.repo.GetAllExchangeRates()
+}
+func (s *exchangeRateService) CreateExchangeRate(rate models.ExchangeRate) (models.ExchangeRate, error) {
+	if err := rate.Validate(); err != nil {
+		return rate, err
+	}
+	return s.repo.CreateExchangeRate(rate)
+}
+func (s *exchangeRateService) DeleteExchangeRate(id string) error {
+	return s.repo.DeleteExchangeRate(id)
+}
+
+// ConvertProducts: Returns copies of the products with every store price
+// expressed in currency, using the rates in effect at the given time. Each
+// product carries the rates that were applied to it in Conversion
+func (s *exchangeRateService) ConvertProducts(products []models.ProductResponse, currency string, at time.Time) ([]models.ProductResponse, error) {
+	c, err := s.newConverter(currency, at)
+	if err != nil {
+		return nil, err
+	}
+	converted := make([]models.ProductResponse, 0, len(products))
+	for _, product := range products {
+		conversion := &models.Conversion{Currency: c.currency, Rates: []models.ExchangeRate{}}
+		stores, err := c.convertStores(product.Stores, product.Currencies, conversion)
+		if err != nil {
+			return nil, err
+		}
+		effective, err := c.convertStores(product.EffectivePrices, product.Currencies, conversion)
+		if err != nil {
+			return nil, err
+		}
+		product.Stores = stores
+		product.EffectivePrices = effective
+		product.Currencies = nil
+		product.Conversion = conversion
+		converted = append(converted, product)
+	}
+	return converted, nil
+}
+
+// ConvertComparisons: Same as ConvertProducts for comparisons, unit prices
+// are converted along with the store prices
+func (s *exchangeRateService) ConvertComparisons(comparisons []models.VariantComparison, currency string, at time.Time) ([]models.VariantComparison, error) {
+	c, err := s.newConverter(currency, at)
+	if err != nil {
+		return nil, err
+	}
+	converted := make([]models.VariantComparison, 0, len(comparisons))
+	for _, comparison := range comparisons {
+		conversion := &models.Conversion{Currency: c.currency, Rates: []models.ExchangeRate{}}
+		stores, err := c.convertStores(comparison.Stores, comparison.Currencies, conversion)
+		if err != nil {
+			return nil, err
+		}
+		effective, err := c.convertStores(comparison.EffectivePrices, comparison.Currencies, conversion)
+		if err != nil {
+			return nil, err
+		}
+		unitPrices, err := c.convertStores(&comparison.UnitPrices, comparison.Currencies, conversion)
+		if err != nil {
+			return nil, err
+		}
+		comparison.Stores = stores
+		comparison.EffectivePrices = effective
+		comparison.UnitPrices = *unitPrices
+		if comparison.EffectiveUnitPrices != nil {
+			effectiveUnitPrices, err := c.convertStores(&comparison.EffectiveUnitPrices, comparison.Currencies, conversion)
+			if err != nil {
+				return nil, err
+			}
+			comparison.EffectiveUnitPrices = *effectiveUnitPrices
+		}
+		comparison.Currencies = nil
+		comparison.Conversion = conversion
+		converted = append(converted, comparison)
+	}
+	return converted, nil
+}
+
+// Convert: Expresses a single amount given in one currency in another, with
+// the rates in effect at the given time
+func (s *exchangeRateService) Convert(amount float64, from string, to string, at time.Time) (float64, error) {
+	c, err := s.newConverter(to, at)
+	if err != nil {
+		return 0, err
+	}
+	from, err = models.NormalizeCurrency(from)
+	if err != nil || from == c.currency {
+		return amount, err
+	}
+	legs, err := c.legsFrom(from)
+	if err != nil {
+		return 0, err
+	}
+	for _, leg := range legs {
+		amount *= leg.Rate
+	}
+	return amount, nil
+}
+
+// converter caches the exchange rates looked up while converting a response
+type converter struct {
+	repo     repository.ExchangeRateRepository
+	currency string
+	at       time.Time
+	legs     map[string][]models.ExchangeRate
+}
+
+func (s *exchangeRateService) newConverter(currency string, at time.Time) (*converter, error) {
+	currency, err := models.NormalizeCurrency(currency)
+	if err != nil {
+		return nil, err
+	}
+	return &converter{repo: s.repo, currency: currency, at: at, legs: map[string][]models.ExchangeRate{}}, nil
+}
+
+// find looks up from→to, falling back to the inverse of to→from
+func (c *converter) find(from string, to string) (models.ExchangeRate, error) {
+	rate, err := c.repo.FindExchangeRate(from, to, c.at)
+	if !errors.Is(err, models.ErrExchangeRateNotFound) {
+		return rate, err
+	}
+	inverse, err := c.repo.FindExchangeRate(to, from, c.at)
+	if err != nil {
+		return inverse, err
+	}
+	return models.ExchangeRate{Id: inverse.Id, Base: from, Quote: to, Rate: 1 / inverse.Rate, EffectiveDate: inverse.EffectiveDate}, nil
+}
+
+// legsFrom returns the rates needed to go from the given currency to the
+// target one, either directly or through colones when there is no direct rate
+func (c *converter) legsFrom(from string) ([]models.ExchangeRate, error) {
+	if legs, ok := c.legs[from]; ok {
+		return legs, nil
+	}
+	var legs []models.ExchangeRate
+	rate, err := c.find(from, c.currency)
+	switch {
+	case err == nil:
+		legs = []models.ExchangeRate{rate}
+	case errors.Is(err, models.ErrExchangeRateNotFound) && from != models.DefaultCurrency && c.currency != models.DefaultCurrency:
+		first, err := c.find(from, models.DefaultCurrency)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s to %s", err, from, c.currency)
+		}
+		second, err := c.find(models.DefaultCurrency, c.currency)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s to %s", err, from, c.currency)
+		}
+		legs = []models.ExchangeRate{first, second}
+	case errors.Is(err, models.ErrExchangeRateNotFound):
+		return nil, fmt.Errorf("%w: %s to %s", err, from, c.currency)
+	default:
+		return nil, err
+	}
+	c.legs[from] = legs
+	return legs, nil
+}
+
+func (c *converter) convertStores(stores *models.Stores, currencies *models.StoreCurrencies, conversion *models.Conversion) (*models.Stores, error) {
+	if stores == nil {
+		return nil, nil
+	}
+	converted := make(models.Stores, len(*stores))
+	for store, price := range *stores {
+		from := currencies.Of(store)
+		if from == c.currency {
+			converted[store] = price
+			continue
+		}
+		legs, err := c.legsFrom(from)
+		if err != nil {
+			return nil, err
+		}
+		for _, leg := range legs {
+			price *= leg.Rate
+			conversion.Add(leg)
+		}
+		converted[store] = price
+	}
+	return &converted, nil
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"crproductos/internal/models"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+type fakeExchangeRateRepository struct {
+	rates []models.ExchangeRate
+}
+
+func (r *fakeExchangeRateRepository) GetAllExchangeRates() ([]models.ExchangeRate, error) {
+	return r.rates, nil
+}
+func (r *fakeExchangeRateRepository) CreateExchangeRate(rate models.ExchangeRate) (models.ExchangeRate, error) {
+	rate.Id = len(r.rates) + 1
+	r.rates = append(r.rates, rate)
+	return rate, nil
+}
+func (r *fakeExchangeRateRepository) DeleteExchangeRate(id string) error {
+	return nil
+}
+func (r *fakeExchangeRateRepository) FindExchangeRate(base string, quote string, at time.Time) (models.ExchangeRate, error) {
+	var found *models.ExchangeRate
+	for i, rate := range r.rates {
+		if rate.Base == base && rate.Quote == quote && !rate.EffectiveDate.After(at) {
+			if found == nil || rate.EffectiveDate.After(found.EffectiveDate.Time) {
+				found = &r.rates[i]
+			}
+		}
+	}
+	if found == nil {
+		return models.ExchangeRate{}, models.ErrExchangeRateNotFound
+	}
+	return *found, nil
+}
+
+func date(value string) models.Date {
+	t, _ := time.Parse("2006-01-02", value)
+	return models.Date{Time: t}
+}
+
+func TestConvertProducts(t *testing.T) {
+	repo := &fakeExchangeRateRepository{rates: []models.ExchangeRate{
+		{Id: 1, Base: "USD", Quote: "CRC", Rate: 500, EffectiveDate: date("2026-01-01")},
+		{Id: 2, Base: "USD", Quote: "CRC", Rate: 520, EffectiveDate: date("2026-06-01")},
+		{Id: 3, Base: "EUR", Quote: "CRC", Rate: 600, EffectiveDate: date("2026-01-01")},
+	}}
+	rates := NewExchangeRateService(repo)
+	products := []models.ProductResponse{{
+		Id:         1,
+		Stores:     &models.Stores{"pali": 1040, "amazon": 2, "europa": 1},
+		Currencies: &models.StoreCurrencies{"amazon": "USD", "europa": "EUR"},
+	}}
+
+	converted, err := rates.ConvertProducts(products, "usd", time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Test failed, reason: %v", err)
+	}
+	stores := *converted[0].Stores
+	expected := map[string]float64{"pali": 2, "amazon": 2, "europa": 600.0 / 520}
+	for store, price := range expected {
+		if math.Abs(stores[store]-price) > 1e-9 {
+			t.Errorf("Expected %s to cost %v USD, got %v", store, price, stores[store])
+		}
+	}
+	if converted[0].Conversion.Currency != "USD" || len(converted[0].Conversion.Rates) != 2 {
+		t.Errorf("Expected CRC->USD and EUR->CRC rates to be reported, got %+v", converted[0].Conversion)
+	}
+	if (*products[0].Stores)["pali"] != 1040 {
+		t.Errorf("Expected the original products to be left untouched")
+	}
+
+	_, err = rates.ConvertProducts(products, "USD", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	if !errors.Is(err, models.ErrExchangeRateNotFound) {
+		t.Errorf("Expected missing rate before its effective date, got %v", err)
+	}
+}
@@ -0,0 +1,20 @@
+package service
+
+import (
+	"crproductos/internal/models"
+	"crproductos/internal/repository"
+)
+
+type priceHistoryService struct {
+	repo repository.PriceHistoryRepository
+}
+type PriceHistoryService interface {
+	GetPriceHistory(filter models.PriceHistoryFilter) ([]models.PricePoint, error)
+}
+
+func NewPriceHistoryService(repo repository.PriceHistoryRepository) PriceHistoryService {
+	return &priceHistoryService{repo: repo}
+}
+func (s *priceHistoryService) GetPriceHistory(filter models.PriceHistoryFilter) ([]models.PricePoint, error) {
+	return s.repo.GetPriceHistory(filter)
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"crproductos/internal/models"
+	"time"
+)
+
+// pricingService turns the list prices read from the catalog into the prices
+// shown to clients, the REST handlers and the GraphQL resolvers share it so
+// both rank and render stores the same way
+type pricingService struct {
+	promotions PromotionService
+	rates      ExchangeRateService
+}
+type PricingService interface {
+	PriceProducts(products []models.ProductResponse, currency string, at time.Time) ([]models.ProductResponse, error)
+	PriceComparisons(comparisons []models.VariantComparison, currency string, at time.Time) ([]models.VariantComparison, error)
+}
+
+// NewPricingService: Either service may be nil, without promotions the
+// effective prices are left empty and without rates asking for a currency
+// fails with models.ErrExchangeRateNotFound
+func NewPricingService(promotions PromotionService, rates ExchangeRateService) PricingService {
+	return &pricingService{promotions: promotions, rates: rates}
+}
+
+// PriceProducts applies the promotions active at the given time and then
+// converts to currency, promotions go first as fixed discounts are in the
+// currency of their store. An empty currency keeps every store's own one
+func (s *pricingService) PriceProducts(products []models.ProductResponse, currency string, at time.Time) ([]models.ProductResponse, error) {
+	var err error
+	if s.promotions != nil {
+		if products, err = s.promotions.ApplyPromotions(products, at); err != nil {
+			return nil, err
+		}
+	}
+	if currency == "" {
+		return products, nil
+	}
+	if s.rates == nil {
+		return nil, models.ErrExchangeRateNotFound
+	}
+	return s.rates.ConvertProducts(products, currency, at)
+}
+
+// PriceComparisons works like PriceProducts, except that comparisons mixing
+// currencies are converted to colones when no currency is given, unit prices
+// in different currencies can't be compared as they are
+func (s *pricingService) PriceComparisons(comparisons []models.VariantComparison, currency string, at time.Time) ([]models.VariantComparison, error) {
+	var err error
+	if s.promotions != nil {
+		if comparisons, err = s.promotions.ApplyPromotionsToComparisons(comparisons, at); err != nil {
+			return nil, err
+		}
+	}
+	if currency == "" && models.MixedCurrencies(comparisons) {
+		currency = models.DefaultCurrency
+	}
+	if currency == "" {
+		return comparisons, nil
+	}
+	if s.rates == nil {
+		return nil, models.ErrExchangeRateNotFound
+	}
+	return s.rates.ConvertComparisons(comparisons, currency, at)
+}
@@ -1,46 +1,198 @@
 package service
 
 import (
+	"context"
 	"crproductos/internal/models"
 	"crproductos/internal/repository"
+	"errors"
+	"log/slog"
+	"strconv"
 )
 
 type productService struct {
-	repo repository.ProductRepository
+	repo   repository.ProductRepository
+	alerts *AlertEvaluator
 }
 type ProductService interface {
-	GetAllProducts() ([]models.ProductResponse, error)
-	GetProductById(id string) (models.Product, error)
-	CreateProduct(product models.ProductResponse) (models.ProductResponse, error)
-	DeleteProduct(id string) error
-	UpdateProduct(id string, product models.ProductResponse) (models.ProductResponse, error)
-	PatchProduct(id string, product models.ProductResponse) (models.Product, error)
-	PatchStore(id string, jsonStore []byte) (models.Product, error)
+	GetAllProducts(ctx context.Context, filter models.ProductFilter) ([]models.ProductResponse, error)
+	ScanProducts(ctx context.Context, filter models.ProductFilter, fn func(models.ProductResponse) error) error
+	GetProductById(ctx context.Context, id string) (models.Product, error)
+	GetProductByBarcode(ctx context.Context, barcode string) (models.Product, error)
+	CreateProduct(ctx context.Context, product models.ProductResponse) (models.ProductResponse, error)
+	DeleteProduct(ctx context.Context, id string) error
+	UpdateProduct(ctx context.Context, id string, product models.ProductResponse) (models.ProductResponse, error)
+	PatchProduct(ctx context.Context, id string, product models.ProductResponse) (models.Product, error)
+	PatchStore(ctx context.Context, id string, jsonStore []byte) (models.Product, error)
+	CompareProducts(ctx context.Context, filter models.ProductFilter) ([]models.VariantComparison, error)
 }
 
-func NewProductService(repo repository.ProductRepository) ProductService {
-	return &productService{repo: repo}
+// ProductServiceOption plugs optional collaborators into the ProductService
+type ProductServiceOption func(*productService)
+
+// WithAlertEvaluator evaluates the watchlists on every price changing write
+func WithAlertEvaluator(alerts *AlertEvaluator) ProductServiceOption {
+	return func(s *productService) {
+		s.alerts = alerts
+	}
+}
+
+func NewProductService(repo repository.ProductRepository, opts ...ProductServiceOption) ProductService {
+	s := &productService{repo: repo}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
-func (s *productService) GetAllProducts() ([]models.ProductResponse, error) {
-	return s.repo.GetAllProducts()
+
+// storesBefore returns the store prices of the product ahead of a write, and
+// the currencies they are listed in, so the price changes can be evaluated
+// afterwards, nil when nobody listens
+func (s *productService) storesBefore(ctx context.Context, id string) (*models.Stores, *models.StoreCurrencies) {
+	if s.alerts == nil {
+		return nil, nil
+	}
+	product, err := s.repo.GetProductById(ctx, id)
+	if err != nil {
+		return nil, nil
+	}
+	return product.Stores, product.Currencies
 }
 
-func (s *productService) GetProductById(id string) (models.Product, error) {
-	return s.repo.GetProductById(id)
+// pricesChanged hands the store prices that changed with a write over to the
+// alert evaluator. The write already happened, so failures are only logged
+func (s *productService) pricesChanged(ctx context.Context, productId int, before *models.Stores, beforeIn *models.StoreCurrencies, after *models.Stores, afterIn *models.StoreCurrencies) {
+	if s.alerts == nil {
+		return
+	}
+	changes := models.DiffStores(productId, before, after)
+	for i := range changes {
+		changes[i].PreviousCurrency = beforeIn.Of(changes[i].Store)
+		changes[i].Currency = afterIn.Of(changes[i].Store)
+	}
+	if _, err := s.alerts.Evaluate(changes); err != nil {
+		slog.ErrorContext(ctx, "failed to evaluate price alerts", "product_id", productId, "err", err)
+	}
+}
+func (s *productService) GetAllProducts(ctx context.Context, filter models.ProductFilter) ([]models.ProductResponse, error) {
+	return s.repo.GetAllProducts(ctx, filter)
 }
 
-func (s *productService) CreateProduct(product models.ProductResponse) (models.ProductResponse, error) {
-	return s.repo.CreateProduct(product)
+// ScanProducts hands the products to fn a page at a time, so large lists are
+// never held in memory at once and fn may block without holding a cursor open
+func (s *productService) ScanProducts(ctx context.Context, filter models.ProductFilter, fn func(models.ProductResponse) error) error {
+	return s.repo.ScanProducts(ctx, filter, fn)
 }
-func (s *productService) DeleteProduct(id string) error {
-	return s.repo.DeleteProduct(id)
+
+func (s *productService) GetProductById(ctx context.Context, id string) (models.Product, error) {
+	return s.repo.GetProductById(ctx, id)
 }
-func (s *productService) UpdateProduct(id string, product models.ProductResponse) (models.ProductResponse, error) {
-	return s.repo.UpdateProduct(id, product)
+
+func (s *productService) GetProductByBarcode(ctx context.Context, barcode string) (models.Product, error) {
+	if err := models.ValidateBarcode(barcode); err != nil {
+		return models.Product{}, err
+	}
+	return s.repo.GetProductByBarcode(ctx, barcode)
 }
-func (s *productService) PatchProduct(id string, product models.ProductResponse) (models.Product, error) {
-	return s.repo.PatchProduct(id, product)
+
+// validateProduct: Checks the barcodes and currencies of the product and, for size variants,
+// that the parent exists and variants stay one level deep. id is empty for
+// products that are being created
+func (s *productService) validateProduct(ctx context.Context, id string, product models.ProductResponse) error {
+	if err := product.Barcodes.Validate(); err != nil {
+		return err
+	}
+	if product.Currencies != nil {
+		if err := product.Currencies.Normalize(); err != nil {
+			return err
+		}
+	}
+	if product.ParentId == nil {
+		return nil
+	}
+	parentId := strconv.Itoa(*product.ParentId)
+	if parentId == id {
+		return errors.Join(models.ErrInvalidVariant, errors.New("a product cannot be a variant of itself"))
+	}
+	parent, err := s.repo.GetProductById(ctx, parentId)
+	if err != nil {
+		return errors.Join(models.ErrInvalidVariant, errors.New("parent product not found"))
+	}
+	if parent.ParentId.Valid {
+		return errors.Join(models.ErrInvalidVariant, errors.New("parent product is a variant itself"))
+	}
+	if id != "" {
+		if idNumber, err := strconv.Atoi(id); err == nil {
+			variants, err := s.repo.GetAllProducts(ctx, models.ProductFilter{ParentId: &idNumber})
+			if err != nil {
+				return err
+			}
+			if len(variants) > 0 {
+				return errors.Join(models.ErrInvalidVariant, errors.New("product has variants of its own"))
+			}
+		}
+	}
+	return nil
 }
-func (s *productService) PatchStore(id string, jsonStore []byte) (models.Product, error) {
-	return s.repo.PatchStore(id, jsonStore)
+
+func (s *productService) CreateProduct(ctx context.Context, product models.ProductResponse) (models.ProductResponse, error) {
+	if err := s.validateProduct(ctx, "", product); err != nil {
+		return product, err
+	}
+	created, err := s.repo.CreateProduct(ctx, product)
+	if err != nil {
+		return created, err
+	}
+	s.pricesChanged(ctx, created.Id, nil, nil, created.Stores, created.Currencies)
+	return created, nil
+}
+func (s *productService) DeleteProduct(ctx context.Context, id string) error {
+	return s.repo.DeleteProduct(ctx, id)
+}
+func (s *productService) UpdateProduct(ctx context.Context, id string, product models.ProductResponse) (models.ProductResponse, error) {
+	if err := s.validateProduct(ctx, id, product); err != nil {
+		return product, err
+	}
+	before, beforeIn := s.storesBefore(ctx, id)
+	updated, err := s.repo.UpdateProduct(ctx, id, product)
+	if err != nil {
+		return updated, err
+	}
+	if productId, err := strconv.Atoi(id); err == nil {
+		s.pricesChanged(ctx, productId, before, beforeIn, updated.Stores, updated.Currencies)
+	}
+	return updated, nil
+}
+func (s *productService) PatchProduct(ctx context.Context, id string, product models.ProductResponse) (models.Product, error) {
+	if err := s.validateProduct(ctx, id, product); err != nil {
+		return models.Product{}, err
+	}
+	before, beforeIn := s.storesBefore(ctx, id)
+	patched, err := s.repo.PatchProduct(ctx, id, product)
+	if err != nil {
+		return patched, err
+	}
+	if product.Stores != nil {
+		s.pricesChanged(ctx, patched.Id, before, beforeIn, patched.Stores, patched.Currencies)
+	}
+	return patched, nil
+}
+func (s *productService) PatchStore(ctx context.Context, id string, jsonStore []byte) (models.Product, error) {
+	before, beforeIn := s.storesBefore(ctx, id)
+	patched, err := s.repo.PatchStore(ctx, id, jsonStore)
+	if err != nil {
+		return patched, err
+	}
+	s.pricesChanged(ctx, patched.Id, before, beforeIn, patched.Stores, patched.Currencies)
+	return patched, nil
+}
+
+// CompareProducts: Lists the products matching filter with the price each
+// store charges per base unit, used to put every size of a brand or of a
+// parent product side by side
+func (s *productService) CompareProducts(ctx context.Context, filter models.ProductFilter) ([]models.VariantComparison, error) {
+	products, err := s.repo.GetAllProducts(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return models.CompareVariants(products), nil
 }
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"crproductos/internal/models"
+	"crproductos/internal/repository"
+	"time"
+)
+
+type promotionService struct {
+	repo repository.PromotionRepository
+}
+type PromotionService interface {
+	GetPromotions(filter models.PromotionFilter) ([]models.Promotion, error)
+	GetPromotionById(id string) (models.Promotion, error)
+	CreatePromotion(promotion models.Promotion) (models.Promotion, error)
+	UpdatePromotion(id string, promotion models.Promotion) (models.Promotion, error)
+	DeletePromotion(id string) error
+	ApplyPromotions(products []models.ProductResponse, at time.Time) ([]models.ProductResponse, error)
+	ApplyPromotionsToComparisons(comparisons []models.VariantComparison, at time.Time) ([]models.VariantComparison, error)
+}
+
+func NewPromotionService(repo repository.PromotionRepository) PromotionService {
+	return &promotionService{repo: repo}
+}
+func (s *promotionService) GetPromotions(filter models.PromotionFilter) ([]models.Promotion, error) {
+	return s.repo.GetPromotions(filter)
+}
+func (s *promotionService) GetPromotionById(id string) (models.Promotion, error) {
+	return s.repo.GetPromotionById(id)
+}
+func (s *promotionService) CreatePromotion(promotion models.Promotion) (models.Promotion, error) {
+	if err := promotion.Validate(); err != nil {
+		return promotion, err
+	}
+	return s.repo.CreatePromotion(promotion)
+}
+func (s *promotionService) UpdatePromotion(id string, promotion models.Promotion) (models.Promotion, error) {
+	if err := promotion.Validate(); err != nil {
+		return promotion, err
+	}
+	return s.repo.UpdatePromotion(id, promotion)
+}
+func (s *promotionService) DeletePromotion(id string) error {
+	return s.repo.DeletePromotion(id)
+}
+
+// activePromotions loads, in a single query, the promotions running at the
+// given time for every product, grouped by product id
+func (s *promotionService) activePromotions(productIds []int, at time.Time) (map[int][]models.Promotion, error) {
+	grouped := map[int][]models.Promotion{}
+	if len(productIds) == 0 {
+		return grouped, nil
+	}
+	promotions, err := s.repo.GetPromotions(models.PromotionFilter{ProductIds: productIds, ActiveAt: &at})
+	if err != nil {
+		return nil, err
+	}
+	for _, promotion := range promotions {
+		grouped[promotion.ProductId] = append(grouped[promotion.ProductId], promotion)
+	}
+	return grouped, nil
+}
+
+func applyPromotions(product models.ProductResponse, promotions []models.Promotion, at time.Time) models.ProductResponse {
+	if product.Stores == nil {
+		return product
+	}
+	effective := models.EffectivePrices(*product.Stores, promotions, at)
+	product.EffectivePrices = &effective
+	product.Promotions = promotions
+	return product
+}
+
+// ApplyPromotions: Returns copies of the products with the effective price of
+// every store at the given time, along with the promotions behind them
+func (s *promotionService) ApplyPromotions(products []models.ProductResponse, at time.Time) ([]models.ProductResponse, error) {
+	productIds := make([]int, 0, len(products))
+	for _, product := range products {
+		productIds = append(productIds, product.Id)
+	}
+	promotions, err := s.activePromotions(productIds, at)
+	if err != nil {
+		return nil, err
+	}
+	applied := make([]models.ProductResponse, 0, len(products))
+	for _, product := range products {
+		applied = append(applied, applyPromotions(product, promotions[product.Id], at))
+	}
+	return applied, nil
+}
+
+// ApplyPromotionsToComparisons: Same as ApplyPromotions, the effective unit
+// prices are scaled by the same discount as the store prices
+func (s *promotionService) ApplyPromotionsToComparisons(comparisons []models.VariantComparison, at time.Time) ([]models.VariantComparison, error) {
+	productIds := make([]int, 0, len(comparisons))
+	for _, comparison := range comparisons {
+		productIds = append(productIds, comparison.Id)
+	}
+	promotions, err := s.activePromotions(productIds, at)
+	if err != nil {
+		return nil, err
+	}
+	applied := make([]models.VariantComparison, 0, len(comparisons))
+	for _, comparison := range comparisons {
+		comparison.ProductResponse = applyPromotions(comparison.ProductResponse, promotions[comparison.Id], at)
+		if comparison.EffectivePrices != nil {
+			comparison.EffectiveUnitPrices = models.Stores{}
+			for store, unitPrice := range comparison.UnitPrices {
+				if price := (*comparison.Stores)[store]; price > 0 {
+					comparison.EffectiveUnitPrices[store] = unitPrice * (*comparison.EffectivePrices)[store] / price
+				}
+			}
+		}
+		applied = append(applied, comparison)
+	}
+	return applied, nil
+}
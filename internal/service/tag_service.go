@@ -0,0 +1,65 @@
+package service
+
+import (
+	"crproductos/internal/models"
+	"crproductos/internal/repository"
+)
+
+type tagService struct {
+	catalogWrites
+	repo repository.TagRepository
+}
+type TagService interface {
+	GetAllTags() ([]models.Tag, error)
+	CreateTag(tag models.Tag) (models.Tag, error)
+	UpdateTag(id string, tag models.Tag) (models.Tag, error)
+	DeleteTag(id string) error
+	SetProductTags(productId string, tags []string) error
+}
+
+func NewTagService(repo repository.TagRepository, opts ...CatalogOption) TagService {
+	s := &tagService{repo: repo}
+	for _, opt := range opts {
+		opt(&s.catalogWrites)
+	}
+	return s
+}
+func (s *tagService) GetAllTags() ([]models.Tag, error) {
+	return s.repo.GetAllTags()
+}
+func (s *tagService) CreateTag(tag models.Tag) (models.Tag, error) {
+	name, err := models.NormalizeTag(tag.Name)
+	if err != nil {
+		return tag, err
+	}
+	tag.Name = name
+	return s.repo.CreateTag(tag)
+}
+func (s *tagService) UpdateTag(id string, tag models.Tag) (models.Tag, error) {
+	name, err := models.NormalizeTag(tag.Name)
+	if err != nil {
+		return tag, err
+	}
+	tag.Name = name
+	s.invalidate("")
+	defer s.invalidate("")
+	return s.repo.UpdateTag(id, tag)
+}
+func (s *tagService) DeleteTag(id string) error {
+	s.invalidate("")
+	defer s.invalidate("")
+	return s.repo.DeleteTag(id)
+}
+func (s *tagService) SetProductTags(productId string, tags []string) error {
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		name, err := models.NormalizeTag(tag)
+		if err != nil {
+			return err
+		}
+		normalized = append(normalized, name)
+	}
+	s.invalidate(productId)
+	defer s.invalidate(productId)
+	return s.repo.SetProductTags(productId, normalized)
+}
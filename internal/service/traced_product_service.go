@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"crproductos/internal/models"
+	"crproductos/internal/trace"
+)
+
+type tracedProductService struct {
+	svc ProductService
+}
+
+// NewTracedProductService wraps svc in a span per call, named after the
+// method, so the spans of the statements it runs nest below it
+func NewTracedProductService(svc ProductService) ProductService {
+	return &tracedProductService{svc: svc}
+}
+
+func startService(ctx context.Context, method string) (context.Context, *trace.Span) {
+	return trace.Start(ctx, "ProductService."+method, trace.KindInternal)
+}
+
+func (s *tracedProductService) GetAllProducts(ctx context.Context, filter models.ProductFilter) ([]models.ProductResponse, error) {
+	ctx, span := startService(ctx, "GetAllProducts")
+	defer span.End()
+	products, err := s.svc.GetAllProducts(ctx, filter)
+	span.RecordError(err)
+	span.SetAttribute("products.count", len(products))
+	return products, err
+}
+func (s *tracedProductService) ScanProducts(ctx context.Context, filter models.ProductFilter, fn func(models.ProductResponse) error) error {
+	ctx, span := startService(ctx, "ScanProducts")
+	defer span.End()
+	count := 0
+	err := s.svc.ScanProducts(ctx, filter, func(product models.ProductResponse) error {
+		count++
+		return fn(product)
+	})
+	span.RecordError(err)
+	span.SetAttribute("products.count", count)
+	return err
+}
+func (s *tracedProductService) GetProductById(ctx context.Context, id string) (models.Product, error) {
+	ctx, span := startService(ctx, "GetProductById")
+	defer span.End()
+	span.SetAttribute("product.id", id)
+	product, err := s.svc.GetProductById(ctx, id)
+	span.RecordError(err)
+	return product, err
+}
+func (s *tracedProductService) GetProductByBarcode(ctx context.Context, barcode string) (models.Product, error) {
+	ctx, span := startService(ctx, "GetProductByBarcode")
+	defer span.End()
+	span.SetAttribute("product.barcode", barcode)
+	product, err := s.svc.GetProductByBarcode(ctx, barcode)
+	span.RecordError(err)
+	return product, err
+}
+func (s *tracedProductService) CreateProduct(ctx context.Context, product models.ProductResponse) (models.ProductResponse, error) {
+	ctx, span := startService(ctx, "CreateProduct")
+	defer span.End()
+	created, err := s.svc.CreateProduct(ctx, product)
+	span.RecordError(err)
+	span.SetAttribute("product.id", created.Id)
+	return created, err
+}
+func (s *tracedProductService) DeleteProduct(ctx context.Context, id string) error {
+	ctx, span := startService(ctx, "DeleteProduct")
+	defer span.End()
+	span.SetAttribute("product.id", id)
+	err := s.svc.DeleteProduct(ctx, id)
+	span.RecordError(err)
+	return err
+}
+func (s *tracedProductService) UpdateProduct(ctx context.Context, id string, product models.ProductResponse) (models.ProductResponse, error) {
+	ctx, span := startService(ctx, "UpdateProduct")
+	defer span.End()
+	span.SetAttribute("product.id", id)
+	updated, err := s.svc.UpdateProduct(ctx, id, product)
+	span.RecordError(err)
+	return updated, err
+}
+func (s *tracedProductService) PatchProduct(ctx context.Context, id string, product models.ProductResponse) (models.Product, error) {
+	ctx, span := startService(ctx, "PatchProduct")
+	defer span.End()
+	span.SetAttribute("product.id", id)
+	patched, err := s.svc.PatchProduct(ctx, id, product)
+	span.RecordError(err)
+	return patched, err
+}
+func (s *tracedProductService) PatchStore(ctx context.Context, id string, jsonStore []byte) (models.Product, error) {
+	ctx, span := startService(ctx, "PatchStore")
+	defer span.End()
+	span.SetAttribute("product.id", id)
+	patched, err := s.svc.PatchStore(ctx, id, jsonStore)
+	span.RecordError(err)
+	return patched, err
+}
+func (s *tracedProductService) CompareProducts(ctx context.Context, filter models.ProductFilter) ([]models.VariantComparison, error) {
+	ctx, span := startService(ctx, "CompareProducts")
+	defer span.End()
+	comparison, err := s.svc.CompareProducts(ctx, filter)
+	span.RecordError(err)
+	return comparison, err
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"crproductos/internal/models"
+	"crproductos/internal/notify"
+	"crproductos/internal/repository"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+type watchlistService struct {
+	repo repository.WatchlistRepository
+}
+
+// WatchlistService: The methods taking a subscriber only act on the watches
+// and alerts of that subscriber, an empty one acts on all of them
+type WatchlistService interface {
+	GetWatches(filter models.WatchFilter) ([]models.Watch, error)
+	GetWatchById(id string, subscriber string) (models.Watch, error)
+	CreateWatch(watch models.Watch) (models.Watch, error)
+	UpdateWatch(id string, subscriber string, watch models.Watch) (models.Watch, error)
+	DeleteWatch(id string, subscriber string) error
+	GetAlerts(filter models.AlertFilter) ([]models.Alert, error)
+	MarkAlertRead(id string, subscriber string) (models.Alert, error)
+}
+
+func NewWatchlistService(repo repository.WatchlistRepository) WatchlistService {
+	return &watchlistService{repo: repo}
+}
+func (s *watchlistService) GetWatches(filter models.WatchFilter) ([]models.Watch, error) {
+	return s.repo.GetWatches(filter)
+}
+func (s *watchlistService) GetWatchById(id string, subscriber string) (models.Watch, error) {
+	return s.repo.GetWatchById(id, subscriber)
+}
+func (s *watchlistService) CreateWatch(watch models.Watch) (models.Watch, error) {
+	if err := watch.Validate(); err != nil {
+		return watch, err
+	}
+	return s.repo.CreateWatch(watch)
+}
+func (s *watchlistService) UpdateWatch(id string, subscriber string, watch models.Watch) (models.Watch, error) {
+	if err := watch.Validate(); err != nil {
+		return watch, err
+	}
+	return s.repo.UpdateWatch(id, subscriber, watch)
+}
+func (s *watchlistService) DeleteWatch(id string, subscriber string) error {
+	return s.repo.DeleteWatch(id, subscriber)
+}
+func (s *watchlistService) GetAlerts(filter models.AlertFilter) ([]models.Alert, error) {
+	return s.repo.GetAlerts(filter)
+}
+func (s *watchlistService) MarkAlertRead(id string, subscriber string) (models.Alert, error) {
+	return s.repo.MarkAlertRead(id, subscriber)
+}
+
+// AlertEvaluator raises alerts for the watches whose target price was crossed
+// by a price change. It is plugged into the ProductService write methods
+type AlertEvaluator struct {
+	repo     repository.WatchlistRepository
+	notifier notify.Notifier
+	rates    ExchangeRateService
+}
+
+// NewAlertEvaluator: Creates an evaluator delivering alerts through notifier,
+// notify.LogNotifier is used when notifier is nil. Prices are converted to
+// the currency of each watch with rates, without rates only the watches in
+// the currency of the store are evaluated
+func NewAlertEvaluator(repo repository.WatchlistRepository, notifier notify.Notifier, rates ExchangeRateService) *AlertEvaluator {
+	if notifier == nil {
+		notifier = notify.LogNotifier{}
+	}
+	return &AlertEvaluator{repo: repo, notifier: notifier, rates: rates}
+}
+
+// convert expresses a price of a change in the given currency, with the
+// rates in effect now as the change just happened. Empty currencies are
+// colones
+func (e *AlertEvaluator) convert(price *float64, from string, to string) (*float64, error) {
+	if price == nil {
+		return nil, nil
+	}
+	if from == "" {
+		from = models.DefaultCurrency
+	}
+	if to == "" {
+		to = models.DefaultCurrency
+	}
+	if from == to {
+		return price, nil
+	}
+	if e.rates == nil {
+		return nil, fmt.Errorf("%w: %s to %s", models.ErrExchangeRateNotFound, from, to)
+	}
+	converted, err := e.rates.Convert(*price, from, to, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return &converted, nil
+}
+
+// Evaluate: Raises an alert for every watch of the product whose target was
+// crossed, that is the price is now at or below the target and it either
+// was above it or the store did not list the product before. Prices are
+// compared in the currency of the watch, watches whose currency can't be
+// reached are skipped
+func (e *AlertEvaluator) Evaluate(changes []models.PriceChange) ([]models.Alert, error) {
+	var alerts []models.Alert
+	watchesByProduct := map[int][]models.Watch{}
+	for _, change := range changes {
+		if change.Price == nil {
+			continue
+		}
+		watches, ok := watchesByProduct[change.ProductId]
+		if !ok {
+			productId := change.ProductId
+			var err error
+			watches, err = e.repo.GetWatches(models.WatchFilter{ProductId: &productId})
+			if err != nil {
+				return alerts, err
+			}
+			watchesByProduct[change.ProductId] = watches
+		}
+		for _, watch := range watches {
+			if !watch.Matches(change.Store) {
+				continue
+			}
+			price, err := e.convert(change.Price, change.Currency, watch.Currency)
+			if err != nil {
+				slog.Error("failed to convert price for watch", "watchId", watch.Id, "err", err)
+				continue
+			}
+			previous, err := e.convert(change.PreviousPrice, change.PreviousCurrency, watch.Currency)
+			if err != nil {
+				slog.Error("failed to convert price for watch", "watchId", watch.Id, "err", err)
+				continue
+			}
+			if *price > watch.TargetPrice || (previous != nil && *previous <= watch.TargetPrice) {
+				continue
+			}
+			alert, err := e.repo.CreateAlert(models.Alert{
+				WatchId:       watch.Id,
+				Subscriber:    watch.Subscriber,
+				ProductId:     change.ProductId,
+				Store:         change.Store,
+				Price:         *price,
+				PreviousPrice: previous,
+				TargetPrice:   watch.TargetPrice,
+				Currency:      watch.Currency,
+			})
+			if err != nil {
+				return alerts, err
+			}
+			if err := e.notifier.Notify(alert); err != nil {
+				slog.Error("failed to notify alert", "err", err)
+			}
+			alerts = append(alerts, alert)
+		}
+	}
+	return alerts, nil
+}
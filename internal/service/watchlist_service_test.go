@@ -0,0 +1,130 @@
+package service
+
+import (
+	"crproductos/internal/models"
+	"math"
+	"testing"
+)
+
+type fakeWatchlistRepository struct {
+	watches []models.Watch
+	alerts  []models.Alert
+}
+
+func (r *fakeWatchlistRepository) GetWatches(filter models.WatchFilter) ([]models.Watch, error) {
+	var watches []models.Watch
+	for _, watch := range r.watches {
+		if filter.ProductId == nil || watch.ProductId == *filter.ProductId {
+			watches = append(watches, watch)
+		}
+	}
+	return watches, nil
+}
+func (r *fakeWatchlistRepository) GetWatchById(id string, subscriber string) (models.Watch, error) {
+	return models.Watch{}, nil
+}
+func (r *fakeWatchlistRepository) CreateWatch(watch models.Watch) (models.Watch, error) {
+	return watch, nil
+}
+func (r *fakeWatchlistRepository) UpdateWatch(id string, subscriber string, watch models.Watch) (models.Watch, error) {
+	return watch, nil
+}
+func (r *fakeWatchlistRepository) DeleteWatch(id string, subscriber string) error {
+	return nil
+}
+func (r *fakeWatchlistRepository) GetAlerts(filter models.AlertFilter) ([]models.Alert, error) {
+	return r.alerts, nil
+}
+func (r *fakeWatchlistRepository) CreateAlert(alert models.Alert) (models.Alert, error) {
+	alert.Id = len(r.alerts) + 1
+	r.alerts = append(r.alerts, alert)
+	return alert, nil
+}
+func (r *fakeWatchlistRepository) MarkAlertRead(id string, subscriber string) (models.Alert, error) {
+	return models.Alert{}, nil
+}
+
+type recordingNotifier struct {
+	alerts []models.Alert
+}
+
+func (n *recordingNotifier) Notify(alert models.Alert) error {
+	n.alerts = append(n.alerts, alert)
+	return nil
+}
+
+func TestAlertEvaluatorRaisesOnCrossing(t *testing.T) {
+	pali := "pali"
+	repo := &fakeWatchlistRepository{watches: []models.Watch{
+		{Id: 1, Subscriber: "ana", ProductId: 1, Store: &pali, TargetPrice: 1000},
+		{Id: 2, Subscriber: "luis", ProductId: 1, TargetPrice: 1100},
+		{Id: 3, Subscriber: "sofia", ProductId: 2, TargetPrice: 5000},
+	}}
+	notifier := &recordingNotifier{}
+	evaluator := NewAlertEvaluator(repo, notifier, nil)
+
+	before := &models.Stores{"pali": 1200, "walmart": 1400}
+	after := &models.Stores{"pali": 950, "walmart": 1400, "maxipali": 1600}
+	alerts, err := evaluator.Evaluate(models.DiffStores(1, before, after))
+	if err != nil {
+		t.Fatalf("Test failed, reason: %v", err)
+	}
+	// pali crossed both targets, walmart did not change and maxipali stays above them
+	if len(alerts) != 2 || len(notifier.alerts) != 2 {
+		t.Fatalf("Expected 2 alerts, got %+v", alerts)
+	}
+	for _, alert := range alerts {
+		if alert.Store != "pali" || alert.Price != 950 || *alert.PreviousPrice != 1200 {
+			t.Errorf("Unexpected alert %+v", alert)
+		}
+	}
+
+	// Dropping again while already below the target does not raise it twice
+	alerts, _ = evaluator.Evaluate(models.DiffStores(1, after, &models.Stores{"pali": 900}))
+	if len(alerts) != 0 {
+		t.Errorf("Expected no new alerts, got %+v", alerts)
+	}
+}
+
+func TestAlertEvaluatorConvertsToTheWatchCurrency(t *testing.T) {
+	amazon := "amazon"
+	repo := &fakeWatchlistRepository{watches: []models.Watch{
+		{Id: 1, Subscriber: "ana", ProductId: 1, Store: &amazon, TargetPrice: 1000, Currency: "CRC"},
+		{Id: 2, Subscriber: "luis", ProductId: 1, Store: &amazon, TargetPrice: 1.5, Currency: "USD"},
+		{Id: 3, Subscriber: "sofia", ProductId: 1, Store: &amazon, TargetPrice: 1, Currency: "EUR"},
+	}}
+	rates := NewExchangeRateService(&fakeExchangeRateRepository{rates: []models.ExchangeRate{
+		{Id: 1, Base: "USD", Quote: "CRC", Rate: 500, EffectiveDate: date("2026-01-01")},
+	}})
+	evaluator := NewAlertEvaluator(repo, &recordingNotifier{}, rates)
+
+	// amazon dropped from $2.50 to $1.90, that is ₡1250 to ₡950
+	usd := &models.StoreCurrencies{"amazon": "USD"}
+	changes := models.DiffStores(1, &models.Stores{"amazon": 2.5}, &models.Stores{"amazon": 1.9})
+	for i := range changes {
+		changes[i].PreviousCurrency = usd.Of(changes[i].Store)
+		changes[i].Currency = usd.Of(changes[i].Store)
+	}
+	alerts, err := evaluator.Evaluate(changes)
+	if err != nil {
+		t.Fatalf("Test failed, reason: %v", err)
+	}
+	// the colones watch crossed, the dollar one stays above $1.50 and there
+	// is no rate to euros
+	if len(alerts) != 1 {
+		t.Fatalf("Expected 1 alert, got %+v", alerts)
+	}
+	alert := alerts[0]
+	if alert.WatchId != 1 || alert.Currency != "CRC" || alert.Price != 950 || *alert.PreviousPrice != 1250 {
+		t.Errorf("Unexpected alert %+v", alert)
+	}
+
+	// listed in colones now, ₡1000 to ₡700 is $2 to $1.40 for the dollar
+	// watch, the colones one was already at its target
+	changes = []models.PriceChange{{ProductId: 1, Store: "amazon", PreviousPrice: &[]float64{1000}[0], Price: &[]float64{700}[0],
+		PreviousCurrency: "CRC", Currency: "CRC"}}
+	alerts, _ = evaluator.Evaluate(changes)
+	if len(alerts) != 1 || alerts[0].WatchId != 2 || alerts[0].Currency != "USD" || math.Abs(alerts[0].Price-1.4) > 1e-9 {
+		t.Errorf("Expected the dollar watch to be raised, got %+v", alerts)
+	}
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"crproductos/internal/events"
+	"crproductos/internal/models"
+	"crproductos/internal/repository"
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+)
+
+type webhookService struct {
+	repo   repository.WebhookRepository
+	replay Replayer
+}
+
+// Replayer re-sends a logged webhook delivery, implemented by webhook.Dispatcher
+type Replayer interface {
+	Replay(delivery models.WebhookDelivery) error
+}
+
+type WebhookService interface {
+	GetAllWebhooks() ([]models.Webhook, error)
+	GetWebhookById(id string) (models.Webhook, error)
+	CreateWebhook(webhook models.Webhook) (models.Webhook, error)
+	UpdateWebhook(id string, webhook models.Webhook) (models.Webhook, error)
+	DeleteWebhook(id string) error
+	GetDeliveries(webhookId string) ([]models.WebhookDelivery, error)
+	ReplayDelivery(id string) (models.WebhookDelivery, error)
+}
+
+func NewWebhookService(repo repository.WebhookRepository, replay Replayer) WebhookService {
+	return &webhookService{repo: repo, replay: replay}
+}
+
+// GetAllWebhooks lists the webhooks without their secrets
+func (s *webhookService) GetAllWebhooks() ([]models.Webhook, error) {
+	webhooks, err := s.repo.GetAllWebhooks()
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+	return webhooks, err
+}
+func (s *webhookService) GetWebhookById(id string) (models.Webhook, error) {
+	webhook, err := s.repo.GetWebhookById(id)
+	webhook.Secret = ""
+	return webhook, err
+}
+
+// CreateWebhook: Registers the webhook, active unless told otherwise and
+// generating a signing secret when none is given. The response is the only
+// place the secret is shown
+func (s *webhookService) CreateWebhook(webhook models.Webhook) (models.Webhook, error) {
+	if err := webhook.Validate(events.Types); err != nil {
+		return webhook, err
+	}
+	if webhook.Active == nil {
+		active := true
+		webhook.Active = &active
+	}
+	if webhook.Secret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return webhook, err
+		}
+		webhook.Secret = hex.EncodeToString(secret)
+	}
+	return s.repo.CreateWebhook(webhook)
+}
+
+// UpdateWebhook keeps the current secret unless a new one is given
+func (s *webhookService) UpdateWebhook(id string, webhook models.Webhook) (models.Webhook, error) {
+	if err := webhook.Validate(events.Types); err != nil {
+		return webhook, err
+	}
+	updated, err := s.repo.UpdateWebhook(id, webhook)
+	if webhook.Secret == "" {
+		updated.Secret = ""
+	}
+	return updated, err
+}
+func (s *webhookService) DeleteWebhook(id string) error {
+	return s.repo.DeleteWebhook(id)
+}
+func (s *webhookService) GetDeliveries(webhookId string) ([]models.WebhookDelivery, error) {
+	if _, err := s.repo.GetWebhookById(webhookId); err != nil {
+		return nil, err
+	}
+	return s.repo.GetDeliveries(webhookId)
+}
+
+// ReplayDelivery queues the payload of a logged delivery again, the new
+// attempts show up in the delivery log of the webhook
+func (s *webhookService) ReplayDelivery(id string) (models.WebhookDelivery, error) {
+	delivery, err := s.repo.GetDeliveryById(id)
+	if err != nil {
+		return delivery, err
+	}
+	if _, err := s.repo.GetWebhookById(strconv.Itoa(delivery.WebhookId)); err != nil {
+		return delivery, err
+	}
+	return delivery, s.replay.Replay(delivery)
+}
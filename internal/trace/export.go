@@ -0,0 +1,205 @@
+package trace
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Span kinds, as in OpenTelemetry
+const (
+	KindInternal = "internal"
+	KindServer   = "server"
+	KindClient   = "client"
+)
+
+// WriterExporter writes every span as a line of JSON, e.g. to stdout
+type WriterExporter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriterExporter(w io.Writer) *WriterExporter {
+	return &WriterExporter{w: w}
+}
+
+func (e *WriterExporter) Export(span SpanData) {
+	line, err := json.Marshal(map[string]any{
+		"name":       span.Name,
+		"kind":       span.Kind,
+		"traceId":    span.Context.TraceID.String(),
+		"spanId":     span.Context.SpanID.String(),
+		"parentId":   parentId(span.Parent),
+		"start":      span.Start,
+		"durationMs": float64(span.End.Sub(span.Start).Microseconds()) / 1000,
+		"attributes": span.Attributes,
+		"error":      span.Error,
+	})
+	if err != nil {
+		slog.Error("failed to marshal span", "err", err)
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.w.Write(append(line, '\n'))
+}
+
+func parentId(id SpanID) string {
+	if id == (SpanID{}) {
+		return ""
+	}
+	return id.String()
+}
+
+// OTLPExporter batches spans and posts them as OTLP/HTTP JSON to a collector,
+// e.g. http://localhost:4318/v1/traces. Spans are dropped, not queued
+// forever, when the collector is down
+type OTLPExporter struct {
+	url      string
+	service  string
+	client   *http.Client
+	mu       sync.Mutex
+	pending  []SpanData
+	maxBatch int
+	flush    chan struct{}
+	done     chan struct{}
+	stopped  chan struct{}
+}
+
+// NewOTLPExporter starts an exporter flushing every interval or whenever
+// maxBatch spans are pending. Call Shutdown to flush what is left
+func NewOTLPExporter(url string, service string, interval time.Duration, maxBatch int) *OTLPExporter {
+	e := &OTLPExporter{
+		url:      url,
+		service:  service,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		maxBatch: maxBatch,
+		flush:    make(chan struct{}, 1),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	go e.run(interval)
+	return e
+}
+
+func (e *OTLPExporter) Export(span SpanData) {
+	e.mu.Lock()
+	e.pending = append(e.pending, span)
+	full := len(e.pending) >= e.maxBatch
+	if len(e.pending) > 8*e.maxBatch {
+		e.pending = e.pending[len(e.pending)-8*e.maxBatch:]
+	}
+	e.mu.Unlock()
+	if full {
+		select {
+		case e.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Shutdown flushes the pending spans and stops the exporter
+func (e *OTLPExporter) Shutdown() {
+	close(e.done)
+	<-e.stopped
+}
+
+func (e *OTLPExporter) run(interval time.Duration) {
+	defer close(e.stopped)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-e.flush:
+		case <-e.done:
+			e.send()
+			return
+		}
+		e.send()
+	}
+}
+
+func (e *OTLPExporter) send() {
+	e.mu.Lock()
+	batch := e.pending
+	e.pending = nil
+	e.mu.Unlock()
+	if len(batch) == 0 {
+		return
+	}
+	body, err := json.Marshal(otlpRequest(e.service, batch))
+	if err != nil {
+		slog.Error("failed to marshal spans", "err", err)
+		return
+	}
+	resp, err := e.client.Post(e.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		slog.Error("failed to export spans", "err", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		slog.Error("failed to export spans", "status", resp.Status)
+	}
+}
+
+var otlpKinds = map[string]int{KindInternal: 1, KindServer: 2, KindClient: 3}
+
+// otlpRequest builds the ExportTraceServiceRequest JSON of the spans
+func otlpRequest(service string, spans []SpanData) map[string]any {
+	var otlpSpans []map[string]any
+	for _, span := range spans {
+		status := map[string]any{"code": 1}
+		if span.Error != "" {
+			status = map[string]any{"code": 2, "message": span.Error}
+		}
+		otlpSpan := map[string]any{
+			"traceId":           span.Context.TraceID.String(),
+			"spanId":            span.Context.SpanID.String(),
+			"name":              span.Name,
+			"kind":              otlpKinds[span.Kind],
+			"startTimeUnixNano": strconv.FormatInt(span.Start.UnixNano(), 10),
+			"endTimeUnixNano":   strconv.FormatInt(span.End.UnixNano(), 10),
+			"attributes":        otlpAttributes(span.Attributes),
+			"status":            status,
+		}
+		if parent := parentId(span.Parent); parent != "" {
+			otlpSpan["parentSpanId"] = parent
+		}
+		otlpSpans = append(otlpSpans, otlpSpan)
+	}
+	return map[string]any{"resourceSpans": []any{map[string]any{
+		"resource":   map[string]any{"attributes": otlpAttributes(map[string]any{"service.name": service})},
+		"scopeSpans": []any{map[string]any{"scope": map[string]any{"name": "crproductos/internal/trace"}, "spans": otlpSpans}},
+	}}}
+}
+
+func otlpAttributes(attributes map[string]any) []map[string]any {
+	list := []map[string]any{}
+	for key, value := range attributes {
+		var v map[string]any
+		switch value := value.(type) {
+		case string:
+			v = map[string]any{"stringValue": value}
+		case bool:
+			v = map[string]any{"boolValue": value}
+		case int:
+			v = map[string]any{"intValue": strconv.Itoa(value)}
+		case int64:
+			v = map[string]any{"intValue": strconv.FormatInt(value, 10)}
+		case float64:
+			v = map[string]any{"doubleValue": value}
+		default:
+			v = map[string]any{"stringValue": fmt.Sprint(value)}
+		}
+		list = append(list, map[string]any{"key": key, "value": v})
+	}
+	return list
+}
@@ -0,0 +1,216 @@
+// Package trace records spans in the spirit of OpenTelemetry: every span
+// belongs to a trace, knows its parent and is handed to an Exporter when it
+// ends. Trace context crosses process boundaries in W3C traceparent headers
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type (
+	TraceID [16]byte
+	SpanID  [8]byte
+)
+
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+func (s SpanID) String() string  { return hex.EncodeToString(s[:]) }
+
+// SpanContext identifies a span within its trace
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+// Valid reports whether the ids are set, all zero ids are invalid per W3C
+func (sc SpanContext) Valid() bool {
+	return sc.TraceID != TraceID{} && sc.SpanID != SpanID{}
+}
+
+// Traceparent formats sc as a W3C traceparent header value
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+// ParseTraceparent: Reads a W3C traceparent header value, only version 00 is
+// understood
+func ParseTraceparent(value string) (SpanContext, error) {
+	var sc SpanContext
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) != 4 || parts[0] != "00" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, fmt.Errorf("invalid traceparent %q", value)
+	}
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return sc, err
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return sc, err
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return sc, err
+	}
+	sc.Sampled = flags[0]&1 == 1
+	if !sc.Valid() {
+		return sc, fmt.Errorf("invalid traceparent %q", value)
+	}
+	return sc, nil
+}
+
+// Span is an operation being timed, call End once it is done. A nil span is
+// valid and does nothing, so callers never have to check whether tracing is on
+type Span struct {
+	tracer *Tracer
+	mu     sync.Mutex
+	data   SpanData
+	ended  bool
+}
+
+// SpanData is what exporters receive for an ended span
+type SpanData struct {
+	Name       string
+	Context    SpanContext
+	Parent     SpanID
+	Kind       string
+	Start      time.Time
+	End        time.Time
+	Attributes map[string]any
+	Error      string
+}
+
+// Context returns the span context, zero for a nil span
+func (s *Span) Context() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.data.Context
+}
+
+// SetAttribute attaches a key value pair to the span
+func (s *Span) SetAttribute(key string, value any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Attributes[key] = value
+}
+
+// SetName renames the span, e.g. once the route of a request is known
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Name = name
+}
+
+// RecordError marks the span as failed, a nil err is ignored
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Error = err.Error()
+}
+
+// End stamps the end time and exports the span if it is sampled
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mu.Unlock()
+	if data.Context.Sampled {
+		s.tracer.exporter.Export(data)
+	}
+}
+
+// Exporter receives every ended, sampled span
+type Exporter interface {
+	Export(span SpanData)
+}
+
+// Tracer starts spans and hands them to its exporter
+type Tracer struct {
+	exporter Exporter
+}
+
+func NewTracer(exporter Exporter) *Tracer {
+	return &Tracer{exporter: exporter}
+}
+
+var global atomic.Pointer[Tracer]
+
+// SetTracer installs the tracer used by Start, nil turns tracing off
+func SetTracer(tracer *Tracer) {
+	global.Store(tracer)
+}
+
+type spanKey struct{}
+type remoteKey struct{}
+
+// ContextWithRemote marks sc, received from another process, as the parent
+// of the next span started from the returned context
+func ContextWithRemote(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+// FromContext returns the current span, nil when there is none
+func FromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// Start: Starts a span as a child of the span in ctx, or of the remote parent
+// from ContextWithRemote, with the global tracer. Without a tracer the
+// returned span is nil and ctx is returned untouched
+func Start(ctx context.Context, name string, kind string) (context.Context, *Span) {
+	tracer := global.Load()
+	if tracer == nil {
+		return ctx, nil
+	}
+	return tracer.Start(ctx, name, kind)
+}
+
+// Start starts a span with this tracer, see the package level Start
+func (t *Tracer) Start(ctx context.Context, name string, kind string) (context.Context, *Span) {
+	data := SpanData{Name: name, Kind: kind, Start: time.Now(), Attributes: map[string]any{}}
+	var parent SpanContext
+	if span := FromContext(ctx); span != nil {
+		parent = span.Context()
+	} else if remote, ok := ctx.Value(remoteKey{}).(SpanContext); ok {
+		parent = remote
+	}
+	if parent.Valid() {
+		data.Context.TraceID = parent.TraceID
+		data.Context.Sampled = parent.Sampled
+		data.Parent = parent.SpanID
+	} else {
+		rand.Read(data.Context.TraceID[:])
+		data.Context.Sampled = true
+	}
+	rand.Read(data.Context.SpanID[:])
+	span := &Span{tracer: t, data: data}
+	return context.WithValue(ctx, spanKey{}, span), span
+}
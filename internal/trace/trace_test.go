@@ -0,0 +1,125 @@
+package trace
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recorder struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+func (r *recorder) Export(span SpanData) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, span)
+}
+
+func TestTraceparent(t *testing.T) {
+	value := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, err := ParseTraceparent(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sc.Sampled || sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || sc.Traceparent() != value {
+		t.Errorf("round trip gave %+v", sc)
+	}
+	for _, invalid := range []string{
+		"",
+		"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-zzf067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+	} {
+		if _, err := ParseTraceparent(invalid); err == nil {
+			t.Errorf("%q accepted", invalid)
+		}
+	}
+}
+
+func TestStartNestsSpans(t *testing.T) {
+	rec := &recorder{}
+	tracer := NewTracer(rec)
+	remote, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	ctx, root := tracer.Start(ContextWithRemote(context.Background(), remote), "root", KindServer)
+	_, child := tracer.Start(ctx, "child", KindInternal)
+	child.RecordError(errors.New("boom"))
+	child.End()
+	root.End()
+	root.End()
+
+	if len(rec.spans) != 2 {
+		t.Fatalf("exported %d spans, want 2", len(rec.spans))
+	}
+	childData, rootData := rec.spans[0], rec.spans[1]
+	if rootData.Context.TraceID != remote.TraceID || rootData.Parent != remote.SpanID {
+		t.Errorf("root does not continue the remote trace: %+v", rootData)
+	}
+	if childData.Context.TraceID != remote.TraceID || childData.Parent != rootData.Context.SpanID || childData.Error != "boom" {
+		t.Errorf("unexpected child %+v", childData)
+	}
+}
+
+func TestUnsampledAndNilSpans(t *testing.T) {
+	rec := &recorder{}
+	tracer := NewTracer(rec)
+	remote, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	_, span := tracer.Start(ContextWithRemote(context.Background(), remote), "root", KindServer)
+	span.End()
+	if len(rec.spans) != 0 {
+		t.Error("unsampled span exported")
+	}
+
+	SetTracer(nil)
+	ctx, none := Start(context.Background(), "off", KindInternal)
+	none.SetAttribute("k", "v")
+	none.RecordError(errors.New("ignored"))
+	none.End()
+	if none != nil || FromContext(ctx) != nil {
+		t.Error("span started without a tracer")
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	bodies := make(chan []byte, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies <- body
+	}))
+	defer collector.Close()
+
+	exporter := NewOTLPExporter(collector.URL, "crproductos", time.Hour, 10)
+	tracer := NewTracer(exporter)
+	_, span := tracer.Start(context.Background(), "GET /products/", KindServer)
+	span.SetAttribute("http.status_code", 200)
+	span.End()
+	exporter.Shutdown()
+
+	var request struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []struct {
+					TraceId string `json:"traceId"`
+					Name    string `json:"name"`
+					Kind    int    `json:"kind"`
+				} `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	if err := json.Unmarshal(<-bodies, &request); err != nil {
+		t.Fatal(err)
+	}
+	got := request.ResourceSpans[0].ScopeSpans[0].Spans[0]
+	if got.Name != "GET /products/" || got.Kind != 2 || got.TraceId != span.Context().TraceID.String() {
+		t.Errorf("unexpected span %+v", got)
+	}
+}
@@ -5,10 +5,21 @@ import (
 	"strings"
 )
 
+// FieldToColumnName returns the column a struct field is stored in, the db tag
+// wins over the json one for columns whose name differs from the JSON key
 func FieldToColumnName(field reflect.StructField) string {
+	if dbTag := field.Tag.Get("db"); dbTag != "" && dbTag != "-" {
+		return dbTag
+	}
 	jsonTag := field.Tag.Get("json")
 	if jsonTag == "" {
 		return field.Name
 	}
 	return strings.Split(jsonTag, ",")[0]
 }
+
+// IsColumn reports whether the field maps to a column of its own table.
+// Fields stored elsewhere (e.g. a join table) are tagged with `db:"-"`
+func IsColumn(field reflect.StructField) bool {
+	return field.Tag.Get("db") != "-"
+}
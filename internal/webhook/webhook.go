@@ -0,0 +1,266 @@
+// Package webhook delivers catalog events to the registered webhook endpoints.
+// Every request is signed with the webhook secret, failed deliveries are
+// retried with exponential backoff from a queue in the database and each
+// attempt is kept in the delivery log
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crproductos/internal/events"
+	"crproductos/internal/models"
+	"crproductos/internal/repository"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	TimestampHeader = "X-Webhook-Timestamp"
+	EventHeader     = "X-Webhook-Event"
+	EventIdHeader   = "X-Webhook-Event-Id"
+)
+
+// Sign: Computes the HMAC-SHA256 signature sent in SignatureHeader. The
+// timestamp is part of the signed content so receivers can reject replays
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature produced by Sign in constant time
+func Verify(secret string, timestamp string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// Dispatcher is an events.Publisher that queues every event for the active
+// webhooks subscribed to its type. Run delivers the queue in the background,
+// the queue is kept in the database so pending deliveries survive restarts
+type Dispatcher struct {
+	repo        repository.WebhookRepository
+	client      *http.Client
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	interval    time.Duration
+	batchSize   int
+}
+
+// claimLease is how long a claimed delivery is hidden from other dispatchers,
+// it outlasts an attempt with the default client
+const claimLease = time.Minute
+
+type Option func(*Dispatcher)
+
+// WithClient replaces the default HTTP client, which times out after 10s
+func WithClient(client *http.Client) Option {
+	return func(d *Dispatcher) {
+		d.client = client
+	}
+}
+
+// WithRetries sets how many attempts are made and the backoff between them,
+// the delay doubles after every failure up to maxDelay
+func WithRetries(maxAttempts int, baseDelay time.Duration, maxDelay time.Duration) Option {
+	return func(d *Dispatcher) {
+		d.maxAttempts = maxAttempts
+		d.baseDelay = baseDelay
+		d.maxDelay = maxDelay
+	}
+}
+
+// WithInterval sets how often Run looks for due deliveries
+func WithInterval(interval time.Duration) Option {
+	return func(d *Dispatcher) {
+		d.interval = interval
+	}
+}
+
+func NewDispatcher(repo repository.WebhookRepository, opts ...Option) *Dispatcher {
+	d := &Dispatcher{
+		repo:        repo,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		maxAttempts: 5,
+		baseDelay:   time.Second,
+		maxDelay:    5 * time.Minute,
+		interval:    time.Second,
+		batchSize:   100,
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Publish: Queues a delivery of the event to every subscribed webhook. The
+// event is only handed over once every delivery is queued, an error leaves
+// it to the outbox relay to publish again
+func (d *Dispatcher) Publish(event events.Event) error {
+	webhooks, err := d.repo.GetActiveWebhooks()
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	for _, webhook := range webhooks {
+		if !webhook.Subscribes(event.Type) {
+			continue
+		}
+		pending := models.PendingDelivery{WebhookId: webhook.Id, EventId: event.Id, EventType: event.Type, Payload: payload}
+		if err := d.repo.QueueDelivery(pending); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Replay: Queues the payload of a logged delivery again, with the same event
+// id so the receiver can detect the duplicate if it already processed it
+func (d *Dispatcher) Replay(delivery models.WebhookDelivery) error {
+	webhook, err := d.repo.GetWebhookById(strconv.Itoa(delivery.WebhookId))
+	if err != nil {
+		return err
+	}
+	return d.repo.QueueDelivery(models.PendingDelivery{WebhookId: webhook.Id, EventId: delivery.EventId, EventType: delivery.EventType, Payload: delivery.Payload})
+}
+
+// Flush: Attempts every delivery that is due until none is left, returns how
+// many attempts were made. Deliveries to webhooks deactivated meanwhile are
+// dropped
+func (d *Dispatcher) Flush() (int, error) {
+	total := 0
+	for {
+		pending, err := d.repo.ClaimDueDeliveries(d.batchSize, claimLease)
+		if err != nil || len(pending) == 0 {
+			return total, err
+		}
+		webhooks, err := d.repo.GetActiveWebhooks()
+		if err != nil {
+			return total, err
+		}
+		byId := make(map[int]models.Webhook, len(webhooks))
+		for _, webhook := range webhooks {
+			byId[webhook.Id] = webhook
+		}
+		var wg sync.WaitGroup
+		for _, delivery := range pending {
+			webhook, ok := byId[delivery.WebhookId]
+			if !ok {
+				d.dequeue(delivery)
+				continue
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				d.deliver(webhook, delivery)
+			}()
+		}
+		wg.Wait()
+		total += len(pending)
+		if len(pending) < d.batchSize {
+			return total, nil
+		}
+	}
+}
+
+// Run flushes the due deliveries every interval until ctx is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		if _, err := d.Flush(); err != nil {
+			slog.Error("failed to deliver webhooks", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliver makes the next attempt at sending the payload and logs it. A failed
+// attempt is rescheduled with backoff until the attempts run out
+func (d *Dispatcher) deliver(webhook models.Webhook, pending models.PendingDelivery) {
+	attempt := pending.Attempt + 1
+	delivery := models.WebhookDelivery{
+		WebhookId: webhook.Id,
+		EventId:   pending.EventId,
+		EventType: pending.EventType,
+		Payload:   pending.Payload,
+		Attempt:   attempt,
+	}
+	statusCode, err := d.send(webhook, pending.EventId, pending.EventType, pending.Payload)
+	if statusCode != 0 {
+		delivery.StatusCode = &statusCode
+	}
+	if err != nil {
+		delivery.Error = err.Error()
+	}
+	delivery.Success = err == nil
+	if _, logErr := d.repo.CreateDelivery(delivery); logErr != nil {
+		slog.Error("failed to log webhook delivery", "err", logErr)
+	}
+	switch {
+	case delivery.Success:
+		d.dequeue(pending)
+	case attempt >= d.maxAttempts:
+		slog.Warn("giving up on webhook delivery", "event_id", pending.EventId, "webhook_id", webhook.Id, "attempts", attempt)
+		d.dequeue(pending)
+	default:
+		if err := d.repo.RescheduleDelivery(pending.Id, attempt, time.Now().Add(d.backoff(attempt))); err != nil {
+			slog.Error("failed to reschedule webhook delivery", "err", err)
+		}
+	}
+}
+
+// backoff returns the delay after the given failed attempt, doubling from
+// baseDelay up to maxDelay
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	delay := d.baseDelay
+	for i := 1; i < attempt; i++ {
+		delay = min(delay*2, d.maxDelay)
+	}
+	return delay
+}
+
+func (d *Dispatcher) dequeue(pending models.PendingDelivery) {
+	if err := d.repo.DeletePendingDelivery(pending.Id); err != nil {
+		slog.Error("failed to dequeue webhook delivery", "err", err)
+	}
+}
+
+func (d *Dispatcher) send(webhook models.Webhook, eventId string, eventType string, payload []byte) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, webhook.Url, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, eventType)
+	req.Header.Set(EventIdHeader, eventId)
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, timestamp, payload))
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver answered %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,259 @@
+package webhook
+
+import (
+	"crproductos/internal/events"
+	"crproductos/internal/models"
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var active = true
+
+// fakeWebhookRepository keeps the pending deliveries like the webhook_pending
+// table, a claimed delivery is hidden until its lease expires
+type fakeWebhookRepository struct {
+	mu         sync.Mutex
+	webhooks   []models.Webhook
+	deliveries []models.WebhookDelivery
+	pending    []models.PendingDelivery
+	lastId     int64
+}
+
+func (f *fakeWebhookRepository) GetAllWebhooks() ([]models.Webhook, error) {
+	return f.webhooks, nil
+}
+func (f *fakeWebhookRepository) GetWebhookById(id string) (models.Webhook, error) {
+	for _, webhook := range f.webhooks {
+		if strconv.Itoa(webhook.Id) == id {
+			return webhook, nil
+		}
+	}
+	return models.Webhook{}, sql.ErrNoRows
+}
+func (f *fakeWebhookRepository) CreateWebhook(webhook models.Webhook) (models.Webhook, error) {
+	return webhook, nil
+}
+func (f *fakeWebhookRepository) UpdateWebhook(id string, webhook models.Webhook) (models.Webhook, error) {
+	return webhook, nil
+}
+func (f *fakeWebhookRepository) DeleteWebhook(id string) error {
+	return nil
+}
+func (f *fakeWebhookRepository) GetActiveWebhooks() ([]models.Webhook, error) {
+	return f.webhooks, nil
+}
+func (f *fakeWebhookRepository) CreateDelivery(delivery models.WebhookDelivery) (models.WebhookDelivery, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delivery.Id = len(f.deliveries) + 1
+	f.deliveries = append(f.deliveries, delivery)
+	return delivery, nil
+}
+func (f *fakeWebhookRepository) GetDeliveries(webhookId string) ([]models.WebhookDelivery, error) {
+	return f.deliveries, nil
+}
+func (f *fakeWebhookRepository) GetDeliveryById(id string) (models.WebhookDelivery, error) {
+	return models.WebhookDelivery{}, sql.ErrNoRows
+}
+func (f *fakeWebhookRepository) QueueDelivery(pending models.PendingDelivery) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, queued := range f.pending {
+		if queued.WebhookId == pending.WebhookId && queued.EventId == pending.EventId {
+			return nil
+		}
+	}
+	f.lastId++
+	pending.Id = f.lastId
+	pending.NextAttemptAt = time.Now()
+	f.pending = append(f.pending, pending)
+	return nil
+}
+func (f *fakeWebhookRepository) ClaimDueDeliveries(limit int, lease time.Duration) ([]models.PendingDelivery, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var claimed []models.PendingDelivery
+	for i := range f.pending {
+		if len(claimed) < limit && !f.pending[i].NextAttemptAt.After(time.Now()) {
+			claimed = append(claimed, f.pending[i])
+			f.pending[i].NextAttemptAt = time.Now().Add(lease)
+		}
+	}
+	return claimed, nil
+}
+func (f *fakeWebhookRepository) RescheduleDelivery(id int64, attempt int, at time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := range f.pending {
+		if f.pending[i].Id == id {
+			f.pending[i].Attempt, f.pending[i].NextAttemptAt = attempt, at
+		}
+	}
+	return nil
+}
+func (f *fakeWebhookRepository) DeletePendingDelivery(id int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := range f.pending {
+		if f.pending[i].Id == id {
+			f.pending = append(f.pending[:i], f.pending[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+func (f *fakeWebhookRepository) queued() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.pending)
+}
+
+// drain flushes the dispatcher until the queue is empty, retries included
+func drain(t *testing.T, dispatcher *Dispatcher, repo *fakeWebhookRepository) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for repo.queued() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d deliveries still queued", repo.queued())
+		}
+		if _, err := dispatcher.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	body := []byte(`{"id":"1"}`)
+	signature := Sign("secret", "1700000000", body)
+	if !Verify("secret", "1700000000", body, signature) {
+		t.Fatal("expected the signature to verify")
+	}
+	if Verify("other", "1700000000", body, signature) {
+		t.Error("signature verified with the wrong secret")
+	}
+	if Verify("secret", "1700000001", body, signature) {
+		t.Error("signature verified with a different timestamp")
+	}
+}
+
+func TestDispatcherRetriesUntilDelivered(t *testing.T) {
+	var calls atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !Verify("secret", r.Header.Get(TimestampHeader), body, r.Header.Get(SignatureHeader)) {
+			t.Error("receiver got a bad signature")
+		}
+		if r.Header.Get(EventHeader) != events.PriceChanged {
+			t.Errorf("event header = %q", r.Header.Get(EventHeader))
+		}
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	repo := &fakeWebhookRepository{webhooks: []models.Webhook{
+		{Id: 1, Url: receiver.URL, Secret: "secret", Active: &active, Events: models.EventList{events.PriceChanged}},
+		{Id: 2, Url: receiver.URL, Secret: "secret", Active: &active, Events: models.EventList{events.ProductDeleted}},
+	}}
+	dispatcher := NewDispatcher(repo, WithRetries(5, time.Millisecond, 4*time.Millisecond))
+	event, err := events.New(events.PriceChanged, 1, "walmart", events.PriceChange{Store: "walmart"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dispatcher.Publish(event); err != nil {
+		t.Fatal(err)
+	}
+	drain(t, dispatcher, repo)
+
+	if calls.Load() != 3 {
+		t.Fatalf("receiver called %d times, want 3", calls.Load())
+	}
+	if len(repo.deliveries) != 3 {
+		t.Fatalf("logged %d deliveries, want 3", len(repo.deliveries))
+	}
+	last := repo.deliveries[2]
+	if !last.Success || last.Attempt != 3 || last.WebhookId != 1 || last.EventId != event.Id {
+		t.Errorf("unexpected last delivery %+v", last)
+	}
+	if repo.deliveries[0].Success || *repo.deliveries[0].StatusCode != http.StatusInternalServerError {
+		t.Errorf("unexpected first delivery %+v", repo.deliveries[0])
+	}
+}
+
+func TestDispatcherGivesUp(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer receiver.Close()
+
+	repo := &fakeWebhookRepository{webhooks: []models.Webhook{{Id: 1, Url: receiver.URL, Secret: "secret", Active: &active}}}
+	dispatcher := NewDispatcher(repo, WithRetries(2, time.Millisecond, time.Millisecond))
+	event, _ := events.New(events.ProductDeleted, 1, "", nil)
+	dispatcher.Publish(event)
+	drain(t, dispatcher, repo)
+
+	if len(repo.deliveries) != 2 {
+		t.Fatalf("logged %d deliveries, want 2", len(repo.deliveries))
+	}
+	for _, delivery := range repo.deliveries {
+		if delivery.Success {
+			t.Errorf("delivery %d marked successful", delivery.Id)
+		}
+	}
+}
+
+func TestReplay(t *testing.T) {
+	var eventId string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		eventId = r.Header.Get(EventIdHeader)
+	}))
+	defer receiver.Close()
+
+	repo := &fakeWebhookRepository{webhooks: []models.Webhook{{Id: 1, Url: receiver.URL, Secret: "secret", Active: &active}}}
+	dispatcher := NewDispatcher(repo)
+	err := dispatcher.Replay(models.WebhookDelivery{WebhookId: 1, EventId: "abc", EventType: events.ProductCreated, Payload: []byte(`{}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	drain(t, dispatcher, repo)
+	if eventId != "abc" || len(repo.deliveries) != 1 || !repo.deliveries[0].Success {
+		t.Errorf("replay delivered %q, deliveries %+v", eventId, repo.deliveries)
+	}
+}
+
+func TestQueuedDeliveriesSurviveARestart(t *testing.T) {
+	var received atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Add(1)
+	}))
+	defer receiver.Close()
+
+	repo := &fakeWebhookRepository{webhooks: []models.Webhook{{Id: 1, Url: receiver.URL, Secret: "secret", Active: &active}}}
+	event, _ := events.New(events.ProductCreated, 1, "", nil)
+	// the relay publishes again when it crashed before marking the event
+	for range 2 {
+		if err := NewDispatcher(repo).Publish(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if received.Load() != 0 || repo.queued() != 1 {
+		t.Fatalf("expected one queued delivery and none sent, got %d queued and %d sent", repo.queued(), received.Load())
+	}
+
+	restarted := NewDispatcher(repo)
+	drain(t, restarted, repo)
+	if received.Load() != 1 || len(repo.deliveries) != 1 || !repo.deliveries[0].Success {
+		t.Errorf("receiver got %d requests, deliveries %+v", received.Load(), repo.deliveries)
+	}
+}